@@ -12,6 +12,16 @@ type Config struct {
 		Password string
 		Database string
 	}
+	LowStock struct {
+		Notifier string // log, webhook or file
+		Target   string // webhook URL or file path
+	}
+	Payments struct {
+		Provider string // fake
+	}
+	Shop struct {
+		Timezone string // IANA name, e.g. Europe/Berlin; the process's local time when empty
+	}
 }
 
 func getConfig() (*Config, error) {
@@ -30,5 +40,12 @@ func getConfig() (*Config, error) {
 	config.Postgres.Password = os.Getenv("DB_PASSWORD")
 	config.Postgres.Database = os.Getenv("DB_NAME")
 
+	config.LowStock.Notifier = os.Getenv("LOW_STOCK_NOTIFIER")
+	config.LowStock.Target = os.Getenv("LOW_STOCK_TARGET")
+
+	config.Payments.Provider = os.Getenv("PAYMENT_PROVIDER")
+
+	config.Shop.Timezone = os.Getenv("SHOP_TIMEZONE")
+
 	return &config, nil
 }
@@ -6,6 +6,8 @@ import (
 	"log"
 	"time"
 
+	"frappuccino/internal/notifier"
+	"frappuccino/internal/payment"
 	"frappuccino/internal/server"
 	"frappuccino/internal/utils"
 
@@ -41,7 +43,25 @@ func main() {
 	}
 	defer db.Close()
 
-	server := server.NewServer(":8080", db, utils.GetLogger())
+	lowStockNotifier, err := notifier.New(config.LowStock.Notifier, config.LowStock.Target, utils.GetLogger())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paymentProvider, err := payment.New(config.Payments.Provider)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	location := time.Local
+	if config.Shop.Timezone != "" {
+		location, err = time.LoadLocation(config.Shop.Timezone)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	server := server.NewServer(":8080", db, utils.GetLogger(), lowStockNotifier, paymentProvider, location)
 	server.RunServer()
 }
 
@@ -2,4 +2,4 @@ module frappuccino
 
 go 1.23.5
 
-require github.com/lib/pq v1.10.9 // indirect
+require github.com/lib/pq v1.10.9
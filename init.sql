@@ -1,13 +1,52 @@
-CREATE TYPE status AS ENUM ('open', 'in progress', 'closed');
+CREATE TYPE status AS ENUM ('open', 'in progress', 'closed', 'cancelled');
+
+CREATE TABLE customers (
+    id serial primary key,
+    name varchar(255) not null,
+    email varchar(255) unique,
+    phone varchar(50) unique,
+    default_preferences jsonb not null default '{}'::jsonb, -- pre-filled on the customer's new orders
+    created_at timestamp not null default now()
+);
+CREATE INDEX idx_customers_name ON customers (name);
 
 CREATE TABLE orders (
     id serial primary key,
-    customer_name varchar(255) not null,
+    customer_name varchar(255) not null, -- the customer's name when ordered
+    customer_id int references customers (id) on delete set null,
     order_status status not null,
-    created_at timestamp not null default now(),
-    customer_preferences jsonb not null default '{}'::jsonb
+    created_at timestamptz not null default now(), -- an instant, read in the shop's time zone for promotions
+    customer_preferences jsonb not null default '{}'::jsonb,
+    cancel_reason varchar(255),
+    promo_code varchar(50), -- as given with the order
+    discount decimal(10, 2) not null default 0 constraint positive_discount CHECK (discount >= 0), -- order-wide promotions
+    takeaway boolean not null default false, -- lines use the takeaway tax class of their menu item
+    -- sums of the lines after discounts, kept with the order
+    net_total decimal(12, 2) not null default 0,
+    tax_total decimal(12, 2) not null default 0,
+    gross_total decimal(12, 2) not null default 0,
+    override_by varchar(255) -- manager who closed the order before it was fully paid
 );
 CREATE INDEX idx_orders_customer_name ON orders (customer_name);
+CREATE INDEX idx_orders_customer_id ON orders (customer_id);
+
+CREATE TYPE payment_tender AS ENUM ('cash', 'card', 'voucher');
+
+-- Payments toward an order's gross_total; several make a split bill. Voided
+-- payments are kept but no longer count.
+CREATE TABLE payments (
+    id serial primary key,
+    order_id int not null references orders (id) on delete restrict,
+    tender payment_tender not null,
+    amount decimal(10, 2) not null constraint positive_amount CHECK (amount > 0),
+    tip decimal(10, 2) not null default 0 constraint positive_tip CHECK (tip >= 0),
+    tendered decimal(10, 2),                 -- cash handed over; NULL for other tenders
+    change_due decimal(10, 2) not null default 0 constraint positive_change_due CHECK (change_due >= 0),
+    reference varchar(255),                  -- voucher code or card authorization
+    created_at timestamp not null default now(),
+    voided_at timestamp
+);
+CREATE INDEX idx_payments_order_id ON payments (order_id);
 
 CREATE TABLE order_status_history (
     id serial primary key,
@@ -17,12 +56,30 @@ CREATE TABLE order_status_history (
     new_status status not null
 );
 
+CREATE TABLE menu_categories (
+    id serial primary key,
+    name varchar(100) not null unique,
+    display_order int not null default 0 constraint positive_display_order CHECK (display_order >= 0)
+);
+
+-- Tax classes. Menu prices include the tax unless added_to_price is set, as
+-- for sales taxes charged on top of the price.
+CREATE TABLE tax_classes (
+    id serial primary key,
+    name varchar(100) not null unique,
+    rate decimal(5, 2) not null constraint valid_rate CHECK (rate >= 0 AND rate <= 100), -- percent
+    added_to_price boolean not null default false
+);
+
 CREATE TABLE menu_items (
     id serial primary key,
     name varchar(255) not null unique,
     description varchar(1000) not null,
     tsv tsvector,
-    price decimal(10, 2) not null constraint positive_price CHECK (price >= 0)
+    price decimal(10, 2) not null constraint positive_price CHECK (price >= 0),
+    category_id int references menu_categories (id) on delete set null,
+    tax_class_id int references tax_classes (id),          -- NULL: not taxed
+    takeaway_tax_class_id int references tax_classes (id)  -- NULL: tax_class_id also applies to takeaway
 );
 CREATE INDEX idx_menu_items_tsv ON menu_items USING GIN(tsv);
 
@@ -34,12 +91,126 @@ CREATE TABLE price_history (
     updated_at timestamp not null
 );
 
+-- Price changes planned ahead; applied by the server once effective_at has passed
+CREATE TABLE scheduled_price_changes (
+    id serial primary key,
+    menu_item_id int not null references menu_items (id) on delete cascade,
+    new_price decimal(10,2) not null constraint positive_new_price CHECK (new_price >= 0),
+    effective_at timestamptz not null,
+    created_at timestamptz not null default now(),
+    applied_at timestamptz
+);
+
+CREATE INDEX idx_scheduled_price_changes_pending ON scheduled_price_changes (effective_at) WHERE applied_at IS NULL;
+
+CREATE TYPE loyalty_unit AS ENUM ('points', 'stamps');
+
+-- Loyalty earn rules. A rule earns `earn` units either per `per_amount` spent
+-- on the order (gross after discounts) or per item ordered from category_id.
+CREATE TABLE loyalty_rules (
+    id serial primary key,
+    name varchar(255) not null,
+    unit loyalty_unit not null,
+    earn int not null constraint positive_earn CHECK (earn > 0),
+    per_amount decimal(10, 2) constraint positive_per_amount CHECK (per_amount > 0),
+    category_id int references menu_categories (id) on delete cascade,
+    constraint one_basis CHECK ((per_amount IS NULL) <> (category_id IS NULL))
+);
+
+-- Rewards discount one unit of an order line in exchange for `cost` units
+CREATE TABLE loyalty_rewards (
+    id serial primary key,
+    name varchar(255) not null,
+    unit loyalty_unit not null,
+    cost int not null constraint positive_cost CHECK (cost > 0),
+    category_id int references menu_categories (id) on delete cascade, -- NULL: any menu item
+    discount_percent decimal(5, 2) not null default 100
+        constraint valid_discount_percent CHECK (discount_percent > 0 AND discount_percent <= 100)
+);
+
 CREATE TABLE order_item (
+    id serial primary key,
     order_id int references orders (id) on delete cascade,
     menu_item_id int references menu_items (id) on delete cascade,
-    quantity int not null constraint positive_quantity CHECK (quantity >= 0)
+    quantity int not null constraint positive_quantity CHECK (quantity >= 0),
+    unit_price decimal(10, 2) constraint positive_unit_price CHECK (unit_price >= 0), -- menu price when ordered
+    unit_cost decimal(12, 4) not null default 0,                                      -- ingredient cost when ordered
+    item_name varchar(255),                                                           -- menu name when ordered
+    -- line promotions and loyalty rewards
+    discount decimal(10, 2) not null default 0 constraint positive_discount CHECK (discount >= 0),
+    -- the line's share of orders.discount
+    order_discount decimal(10, 2) not null default 0 constraint positive_order_discount CHECK (order_discount >= 0),
+    reward_id int references loyalty_rewards (id) on delete set null,                 -- the reward the discount redeemed
+    -- what the line earns, after all discounts
+    line_total decimal(12, 2) GENERATED ALWAYS AS (quantity * unit_price - discount - order_discount) STORED,
+    -- tax class of the menu item when ordered
+    tax_class varchar(100),
+    tax_rate decimal(5, 2) not null default 0,
+    tax_added boolean not null default false,
+    -- the tax in line_total, or on top of it when tax_added
+    tax decimal(12, 2) GENERATED ALWAYS AS (
+        ROUND((quantity * unit_price - discount - order_discount) * tax_rate
+            / CASE WHEN tax_added THEN 100 ELSE 100 + tax_rate END, 2)
+    ) STORED,
+    constraint discount_within_line CHECK (discount + order_discount <= quantity * unit_price)
 );
 
+CREATE TYPE promotion_kind AS ENUM ('percent', 'fixed', 'buy_x_get_y');
+
+-- Promotions. One limited to a menu item or category discounts the matching
+-- lines; otherwise it discounts the whole order, except buy_x_get_y which
+-- always works on lines. With a code it only applies to orders giving the
+-- code, otherwise to every order placed while it is active.
+CREATE TABLE promotions (
+    id serial primary key,
+    name varchar(255) not null,
+    kind promotion_kind not null,
+    value decimal(10, 2) not null constraint positive_value CHECK (value > 0), -- percent off, or amount off for fixed
+    menu_item_id int references menu_items (id) on delete cascade,
+    category_id int references menu_categories (id) on delete cascade,
+    code varchar(50) unique,
+    usage_limit int constraint positive_usage_limit CHECK (usage_limit > 0),
+    starts_at timestamptz,
+    expires_at timestamptz,
+    daily_from time,                                                            -- daily window, local time
+    daily_to time,
+    buy_quantity int not null default 0 constraint positive_buy_quantity CHECK (buy_quantity >= 0),
+    get_quantity int not null default 0 constraint positive_get_quantity CHECK (get_quantity >= 0)
+);
+
+-- Promotions applied to each order; uses by orders that are not cancelled
+-- count against the usage limit
+CREATE TABLE order_promotions (
+    id serial primary key,
+    order_id int not null references orders (id) on delete cascade,
+    promotion_id int references promotions (id) on delete set null,
+    name varchar(255) not null,
+    code varchar(50),
+    amount decimal(10, 2) not null
+);
+CREATE INDEX idx_order_promotions_order_id ON order_promotions (order_id);
+CREATE INDEX idx_order_promotions_promotion_id ON order_promotions (promotion_id);
+
+CREATE TYPE loyalty_event AS ENUM ('earn', 'redeem');
+
+-- Every loyalty balance change. Earned units are positive and redeemed ones
+-- negative; when an order is cancelled, changed or closed again its entries
+-- are reversed by new entries of the opposite sign, never edited.
+CREATE TABLE loyalty_ledger (
+    id serial primary key,
+    customer_id int not null references customers (id) on delete cascade,
+    order_id int references orders (id) on delete set null,
+    event loyalty_event not null,
+    unit loyalty_unit not null,
+    amount int not null,
+    rule_id int references loyalty_rules (id) on delete set null,
+    reward_id int references loyalty_rewards (id) on delete set null,
+    note text,
+    created_at timestamp not null default now()
+);
+CREATE INDEX idx_loyalty_ledger_customer ON loyalty_ledger (customer_id, unit);
+CREATE INDEX idx_loyalty_ledger_order ON loyalty_ledger (order_id);
+
 CREATE TYPE unit AS ENUM ('shots', 'ml', 'g', 'units');
 
 CREATE TABLE inventory (
@@ -47,7 +218,11 @@ CREATE TABLE inventory (
     name varchar(255) not null unique,
     quantity int not null default 0 constraint positive_quantity CHECK (quantity >= 0),
     unit unit not null,
-    categories varchar(50)[]
+    categories varchar(50)[],
+    min_quantity int not null default 0 constraint positive_min_quantity CHECK (min_quantity >= 0),        -- alert when stock drops below
+    reorder_quantity int not null default 0 constraint positive_reorder_quantity CHECK (reorder_quantity >= 0), -- suggested amount to order
+    pack_size int not null default 0 constraint positive_pack_size CHECK (pack_size >= 0), -- units in a pack, 0 if not sold in packs
+    unit_cost decimal(12, 4) not null default 0 constraint positive_unit_cost CHECK (unit_cost >= 0) -- moving average cost of one unit
 );
 
 CREATE TABLE inventory_transactions (
@@ -55,22 +230,182 @@ CREATE TABLE inventory_transactions (
     inventory_id int references inventory (id) on delete cascade,
     old_quantity int not null,
     new_quantity int not null,
-    transaction_date timestamp not null
+    transaction_date timestamp not null,
+    reason varchar(30) not null default 'count_correction'
+        constraint valid_reason CHECK (reason IN ('order', 'order_update', 'order_cancel', 'delivery', 'spoilage', 'spill', 'count_correction')),
+    reference varchar(255), -- what caused the movement, e.g. 'order:12'
+    note text
 );
 
+CREATE INDEX idx_inventory_transactions_inventory ON inventory_transactions (inventory_id, transaction_date);
+
 CREATE TABLE menu_item_inventory (
     menu_id int references menu_items (id) on delete cascade,
     inventory_id int references inventory (id) on delete cascade,
     quantity int not null constraint positive_quantity CHECK (quantity >= 0)
 );
 
--- Function for inventory quantity tracking
+-- Options such as sizes, milk choices or extra shots offered on menu items
+CREATE TABLE modifier_groups (
+    id serial primary key,
+    name varchar(100) not null unique,
+    required boolean not null default false,           -- at least one modifier must be chosen
+    max_select int not null default 1 constraint positive_max_select CHECK (max_select >= 1)
+);
+
+CREATE TABLE modifiers (
+    id serial primary key,
+    group_id int not null references modifier_groups (id) on delete cascade,
+    name varchar(100) not null,
+    price_delta decimal(10, 2) not null default 0,
+    constraint unique_modifier_name unique (group_id, name) deferrable
+);
+
+-- Change to the recipe per unit when the modifier is chosen; negative quantities take ingredients away
+CREATE TABLE modifier_inventory (
+    modifier_id int references modifiers (id) on delete cascade,
+    inventory_id int references inventory (id) on delete cascade,
+    quantity int not null,
+    primary key (modifier_id, inventory_id)
+);
+
+CREATE TABLE menu_item_modifier_groups (
+    menu_id int references menu_items (id) on delete cascade,
+    group_id int references modifier_groups (id) on delete cascade,
+    primary key (menu_id, group_id)
+);
+
+-- Modifiers chosen on an order line, with name and price snapshotted like the line itself
+CREATE TABLE order_item_modifier (
+    order_item_id int references order_item (id) on delete cascade,
+    modifier_id int references modifiers (id) on delete set null,
+    name varchar(100) not null,
+    price_delta decimal(10, 2) not null
+);
+
+-- Where ingredients come from
+CREATE TABLE suppliers (
+    id serial primary key,
+    name varchar(255) not null unique,
+    contact_name varchar(255),
+    email varchar(255),
+    phone varchar(50),
+    lead_time_days int not null default 0 constraint positive_lead_time CHECK (lead_time_days >= 0)
+);
+
+-- Inventory items a supplier sells, in packs of pack_size inventory units
+CREATE TABLE supplier_items (
+    supplier_id int references suppliers (id) on delete cascade,
+    inventory_id int references inventory (id) on delete cascade,
+    pack_size int not null constraint positive_pack_size CHECK (pack_size > 0),
+    pack_price decimal(10,2) not null constraint positive_pack_price CHECK (pack_price >= 0),
+    primary key (supplier_id, inventory_id)
+);
+
+CREATE TYPE purchase_order_status AS ENUM ('draft', 'sent', 'partially_received', 'received');
+
+CREATE TABLE purchase_orders (
+    id serial primary key,
+    supplier_id int not null references suppliers (id), -- suppliers with purchase orders cannot be deleted
+    status purchase_order_status not null default 'draft',
+    note text,
+    created_at timestamptz not null default now(),
+    sent_at timestamptz,
+    received_at timestamptz
+);
+
+-- Pack size and price are copied from supplier_items when the line is written
+CREATE TABLE purchase_order_items (
+    purchase_order_id int references purchase_orders (id) on delete cascade,
+    inventory_id int references inventory (id),
+    packs_ordered int not null constraint positive_packs_ordered CHECK (packs_ordered > 0),
+    packs_received int not null default 0 constraint valid_packs_received CHECK (packs_received BETWEEN 0 AND packs_ordered),
+    pack_size int not null,
+    pack_price decimal(10,2) not null,
+    primary key (purchase_order_id, inventory_id)
+);
+
+-- Ingredients taken from inventory for each order, so cancellations can restock exactly
+CREATE TABLE order_inventory_usage (
+    order_id int references orders (id) on delete cascade,
+    inventory_id int references inventory (id) on delete cascade,
+    quantity int not null constraint positive_quantity CHECK (quantity >= 0),
+    primary key (order_id, inventory_id)
+);
+
+-- Business shifts; at most one is open at a time
+CREATE TABLE shifts (
+    id serial primary key,
+    opened_at timestamp not null default now(),
+    opened_by varchar(255) not null,
+    closed_at timestamp,
+    closed_by varchar(255),
+    forced boolean not null default false -- closed while orders were still open or in progress
+);
+
+CREATE UNIQUE INDEX idx_shifts_one_open ON shifts ((closed_at IS NULL)) WHERE closed_at IS NULL;
+
+-- Z-report: what happened during a shift, snapshotted when it closes
+CREATE TABLE z_reports (
+    shift_id int primary key references shifts (id),
+    orders_taken int not null,
+    orders_closed int not null,
+    orders_cancelled int not null,
+    orders_open int not null, -- still open or in progress at close
+    revenue decimal(12, 2) not null,                 -- gross, with tax
+    net_revenue decimal(12, 2) not null default 0,
+    tax decimal(12, 2) not null default 0,
+    cogs decimal(12, 2) not null,
+    cancelled_value decimal(12, 2) not null,
+    items_sold jsonb not null,
+    inventory_consumed jsonb not null
+);
+
+-- Closed shifts and their Z-reports are the till record and never change
+CREATE OR REPLACE FUNCTION prevent_closed_shift_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'closed shifts and Z-reports cannot be changed' USING ERRCODE = 'P0001';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER closed_shift_immutable
+BEFORE UPDATE OR DELETE ON shifts
+FOR EACH ROW
+WHEN (OLD.closed_at IS NOT NULL)
+EXECUTE FUNCTION prevent_closed_shift_change();
+
+CREATE TRIGGER z_report_immutable
+BEFORE UPDATE OR DELETE ON z_reports
+FOR EACH ROW
+EXECUTE FUNCTION prevent_closed_shift_change();
+
+-- Loyalty entries are only ever added. Unlinking a deleted order or rule is
+-- the one change allowed.
+CREATE OR REPLACE FUNCTION prevent_loyalty_ledger_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'loyalty ledger entries cannot be changed' USING ERRCODE = 'P0001';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER loyalty_ledger_append_only
+BEFORE UPDATE OF customer_id, event, unit, amount, created_at ON loyalty_ledger
+FOR EACH ROW
+EXECUTE FUNCTION prevent_loyalty_ledger_change();
+
+-- Function for inventory quantity tracking. The reason and reference are set
+-- by the application for the current transaction with
+-- set_config('app.inventory_reason', ..., true), 'app.inventory_reference' and 'app.inventory_note'
 CREATE OR REPLACE FUNCTION log_inventory_transaction()
 RETURNS TRIGGER AS $$
 BEGIN
     IF OLD.quantity <> NEW.quantity THEN
-        INSERT INTO inventory_transactions (inventory_id, old_quantity, new_quantity, transaction_date)
-        VALUES (NEW.id, OLD.quantity, NEW.quantity, NOW());
+        INSERT INTO inventory_transactions (inventory_id, old_quantity, new_quantity, transaction_date, reason, reference, note)
+        VALUES (NEW.id, OLD.quantity, NEW.quantity, NOW(),
+                COALESCE(NULLIF(current_setting('app.inventory_reason', true), ''), 'count_correction'),
+                NULLIF(current_setting('app.inventory_reference', true), ''),
+                NULLIF(current_setting('app.inventory_note', true), ''));
     END IF;
     RETURN NEW;
 END;
@@ -120,6 +455,75 @@ AFTER UPDATE ON orders
 FOR EACH ROW
 EXECUTE FUNCTION log_order_status_change();
 
+-- Fills the unit price and item name of order lines that were stored before
+-- they were snapshotted. The price in effect when the order was created is the
+-- old price of the first price change after it, or the current price if the
+-- item has not been repriced since.
+-- Existing databases can be migrated with:
+--   ALTER TABLE order_item ADD COLUMN unit_price decimal(10, 2), ADD COLUMN item_name varchar(255);
+--   SELECT backfill_order_item_prices();
+CREATE OR REPLACE FUNCTION backfill_order_item_prices()
+RETURNS int AS $$
+DECLARE
+    updated int;
+BEGIN
+    UPDATE order_item oi
+    SET unit_price = COALESCE((
+            SELECT ph.old_price
+            FROM price_history ph
+            WHERE ph.menu_item_id = oi.menu_item_id AND ph.updated_at > o.created_at
+            ORDER BY ph.updated_at
+            LIMIT 1
+        ), mi.price),
+        item_name = COALESCE(oi.item_name, mi.name)
+    FROM orders o, menu_items mi
+    WHERE o.id = oi.order_id AND mi.id = oi.menu_item_id AND oi.unit_price IS NULL;
+
+    GET DIAGNOSTICS updated = ROW_COUNT;
+    RETURN updated;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Links orders without a customer to the customer of the same name, creating
+-- customers for names that have none. Names shared by several customers are
+-- ambiguous and left unlinked.
+-- Existing databases can be migrated with:
+--   BEGIN;
+--   CREATE TABLE customers (
+--       id serial primary key,
+--       name varchar(255) not null,
+--       email varchar(255) unique,
+--       phone varchar(50) unique,
+--       default_preferences jsonb not null default '{}'::jsonb,
+--       created_at timestamp not null default now()
+--   );
+--   CREATE INDEX idx_customers_name ON customers (name);
+--   ALTER TABLE orders ADD COLUMN customer_id int references customers (id) on delete set null;
+--   CREATE INDEX idx_orders_customer_id ON orders (customer_id);
+--   -- then create link_orders_to_customers() below
+--   SELECT link_orders_to_customers();
+--   COMMIT;
+CREATE OR REPLACE FUNCTION link_orders_to_customers()
+RETURNS int AS $$
+DECLARE
+    updated int;
+BEGIN
+    INSERT INTO customers (name)
+    SELECT DISTINCT o.customer_name
+    FROM orders o
+    WHERE o.customer_id IS NULL
+      AND NOT EXISTS (SELECT 1 FROM customers c WHERE c.name = o.customer_name);
+
+    UPDATE orders o
+    SET customer_id = c.id
+    FROM (SELECT name, MIN(id) AS id FROM customers GROUP BY name HAVING COUNT(*) = 1) c
+    WHERE o.customer_id IS NULL AND o.customer_name = c.name;
+
+    GET DIAGNOSTICS updated = ROW_COUNT;
+    RETURN updated;
+END;
+$$ LANGUAGE plpgsql;
+
 CREATE OR REPLACE FUNCTION set_menu_items_tsv() 
 RETURNS trigger AS $$
 BEGIN
@@ -156,23 +560,53 @@ INSERT INTO inventory (name, quantity, unit, categories) VALUES
 ('Nutmeg', 1000, 'g', ARRAY['Spice']),
 ('Matcha Powder', 800, 'g', ARRAY['Tea', 'Flavoring']),
 ('Ice Cubes', 3000, 'units', ARRAY['Cooling']),
-('Hazelnut Syrup', 1000, 'ml', ARRAY['Flavoring']);
-
-
-INSERT INTO menu_items (name, description, price) VALUES
-('Blueberry Muffin', 'Freshly baked muffin with blueberries', 2.00),
-('Raspberry Muffin', 'Muffin with fresh raspberries', 2.00),
-('Strawberry Muffin', 'Freshly baked muffin with strawberries', 2.00),
-('Caffe Latte', 'Espresso with steamed milk', 3.50),
-('Espresso', 'A strong shot of coffee', 2.00),
-('Vanilla Cappuccino', 'Espresso with vanilla syrup and foam', 3.80),
-('Caramel Macchiato', 'Espresso with caramel syrup and steamed milk', 4.20),
-('Chocolate Frappe', 'Blended chocolate drink with whipped cream', 4.50),
-('Matcha Latte', 'Green tea with steamed milk', 3.60),
-('Chai Tea Latte', 'Spiced tea with milk', 3.70),
-('Barista Special', 'Rich espresso with hazelnut syrup and cream', 4.60),
-('Ice Latte', 'Chilled espresso with milk and ice cubes', 4.10),
-('Double Espresso', 'Two strong espresso shots', 3.20);
+('Hazelnut Syrup', 1000, 'ml', ARRAY['Flavoring']),
+('Oat Milk', 3000, 'ml', ARRAY['Dairy Alternative']);
+
+UPDATE inventory SET min_quantity = 100, reorder_quantity = 500 WHERE name = 'Espresso Shot';
+UPDATE inventory SET min_quantity = 1000, reorder_quantity = 5000 WHERE name = 'Milk';
+UPDATE inventory SET min_quantity = 500, reorder_quantity = 3000 WHERE name = 'Oat Milk';
+UPDATE inventory SET min_quantity = 1000, reorder_quantity = 5000 WHERE name = 'Coffee Beans';
+UPDATE inventory SET pack_size = 12 WHERE name = 'Eggs';
+
+-- Unit costs per shot, ml, g or unit
+UPDATE inventory AS i SET unit_cost = c.unit_cost
+FROM (VALUES
+    ('Espresso Shot', 0.25), ('Milk', 0.0012), ('Flour', 0.0015), ('Blueberries', 0.012),
+    ('Raspberry', 0.015), ('Sugar', 0.001), ('Coffee Beans', 0.02), ('Ground Coffee', 0.022),
+    ('Vanilla Syrup', 0.008), ('Caramel Syrup', 0.008), ('Chocolate Syrup', 0.009),
+    ('Whipped Cream', 0.006), ('Tea Leaves', 0.04), ('Honey', 0.012), ('Pastry Dough', 0.004),
+    ('Butter', 0.009), ('Eggs', 0.25), ('Cinnamon', 0.03), ('Nutmeg', 0.05),
+    ('Matcha Powder', 0.12), ('Ice Cubes', 0.01), ('Hazelnut Syrup', 0.009), ('Oat Milk', 0.002)
+) AS c (name, unit_cost)
+WHERE i.name = c.name;
+
+
+INSERT INTO menu_categories (name, display_order) VALUES
+('Coffee', 1),
+('Tea', 2),
+('Cold Drinks', 3),
+('Bakery', 4);
+
+INSERT INTO tax_classes (name, rate) VALUES
+('Food, dine-in', 19),
+('Food, takeaway', 7),
+('Beverages', 19);
+
+INSERT INTO menu_items (name, description, price, category_id, tax_class_id, takeaway_tax_class_id) VALUES
+('Blueberry Muffin', 'Freshly baked muffin with blueberries', 2.00, 4, 1, 2),
+('Raspberry Muffin', 'Muffin with fresh raspberries', 2.00, 4, 1, 2),
+('Strawberry Muffin', 'Freshly baked muffin with strawberries', 2.00, 4, 1, 2),
+('Caffe Latte', 'Espresso with steamed milk', 3.50, 1, 3, NULL),
+('Espresso', 'A strong shot of coffee', 2.00, 1, 3, NULL),
+('Vanilla Cappuccino', 'Espresso with vanilla syrup and foam', 3.80, 1, 3, NULL),
+('Caramel Macchiato', 'Espresso with caramel syrup and steamed milk', 4.20, 1, 3, NULL),
+('Chocolate Frappe', 'Blended chocolate drink with whipped cream', 4.50, 3, 3, NULL),
+('Matcha Latte', 'Green tea with steamed milk', 3.60, 2, 3, NULL),
+('Chai Tea Latte', 'Spiced tea with milk', 3.70, 2, 3, NULL),
+('Barista Special', 'Rich espresso with hazelnut syrup and cream', 4.60, 1, 3, NULL),
+('Ice Latte', 'Chilled espresso with milk and ice cubes', 4.10, 3, 3, NULL),
+('Double Espresso', 'Two strong espresso shots', 3.20, 1, 3, NULL);
 
 
 -- Blueberry Muffin
@@ -255,6 +689,54 @@ INSERT INTO menu_item_inventory (menu_id, inventory_id, quantity) VALUES
 (13, 1, 2);
 
 
+INSERT INTO suppliers (name, contact_name, email, phone, lead_time_days) VALUES
+('Mountain Roasters', 'Aigerim Sadykova', 'orders@mountainroasters.kz', '+7 701 555 0101', 3),
+('Dairy Farm Co', 'Ruslan Bekov', 'sales@dairyfarm.kz', '+7 702 555 0202', 1);
+
+INSERT INTO supplier_items (supplier_id, inventory_id, pack_size, pack_price) VALUES
+(1, 7, 1000, 18.00),  -- Coffee Beans, 1 kg bag
+(1, 8, 500, 10.00),   -- Ground Coffee, 500 g bag
+(2, 2, 1000, 1.20),   -- Milk, 1 l carton
+(2, 23, 1000, 2.10),  -- Oat Milk, 1 l carton
+(2, 12, 500, 3.50),   -- Whipped Cream
+(2, 16, 250, 2.40);   -- Butter
+
+INSERT INTO modifier_groups (name, required, max_select) VALUES
+('Size', false, 1),
+('Milk', false, 1),
+('Extras', false, 3);
+
+INSERT INTO modifiers (group_id, name, price_delta) VALUES
+(1, 'Small', -0.50),
+(1, 'Large', 0.70),
+(2, 'Oat Milk', 0.50),
+(3, 'Extra Shot', 0.60),
+(3, 'Vanilla Syrup', 0.40),
+(3, 'Whipped Cream', 0.30);
+
+INSERT INTO modifier_inventory (modifier_id, inventory_id, quantity) VALUES
+(1, 2, -50),   -- Small: less milk
+(2, 2, 100),   -- Large: more milk
+(3, 2, -150),  -- Oat Milk: replaces milk
+(3, 23, 150),
+(4, 1, 1),     -- Extra Shot
+(5, 9, 30),    -- Vanilla Syrup
+(6, 12, 30);   -- Whipped Cream
+
+-- Lattes and cappuccinos get every option, espresso drinks only extras
+INSERT INTO menu_item_modifier_groups (menu_id, group_id) VALUES
+(4, 1), (4, 2), (4, 3),
+(6, 1), (6, 2), (6, 3),
+(7, 1), (7, 2), (7, 3),
+(9, 1), (9, 2), (9, 3),
+(10, 1), (10, 2), (10, 3),
+(12, 1), (12, 2), (12, 3),
+(5, 3),
+(13, 3),
+(11, 3),
+(8, 3);
+
+
 INSERT INTO orders (customer_name, order_status, created_at) VALUES
 ('Alice Johnson', 'open', '2025-02-05 09:52:00'),
 ('George Martin', 'open', '2025-02-26 08:16:00'),
@@ -431,3 +913,59 @@ INSERT INTO order_item (order_id, menu_item_id, quantity) VALUES
 (60, 7, 3),
 (60, 12, 2);
 
+SELECT backfill_order_item_prices();
+
+SELECT link_orders_to_customers();
+
+UPDATE customers SET email = 'fiona.harris@example.com', default_preferences = '{"milk": "oat", "sugar": "none"}'
+WHERE name = 'Fiona Harris';
+
+INSERT INTO loyalty_rules (name, unit, earn, per_amount, category_id) VALUES
+('1 point per 1.00 spent', 'points', 1, 1.00, NULL),
+('1 stamp per coffee', 'stamps', 1, NULL, 1);
+
+INSERT INTO loyalty_rewards (name, unit, cost, category_id, discount_percent) VALUES
+('10th coffee free', 'stamps', 9, 1, 100),
+('Half-price pastry', 'points', 50, 4, 50);
+
+INSERT INTO promotions (name, kind, value, category_id, code, usage_limit, expires_at, daily_from, daily_to, buy_quantity, get_quantity) VALUES
+('Afternoon pastries -30%', 'percent', 30, 4, NULL, NULL, NULL, '14:00', '16:00', 0, 0),
+('Second muffin free', 'buy_x_get_y', 100, 4, NULL, NULL, NULL, NULL, NULL, 1, 1),
+('Welcome 5 off', 'fixed', 5, NULL, 'WELCOME5', 100, '2026-12-31 23:59:59+00', NULL, NULL, 0, 0);
+
+-- Mock orders are treated as already deducted from the inventory above
+INSERT INTO order_inventory_usage (order_id, inventory_id, quantity)
+SELECT oi.order_id, mii.inventory_id, SUM(oi.quantity * mii.quantity)
+FROM order_item oi
+JOIN menu_item_inventory mii ON mii.menu_id = oi.menu_item_id
+GROUP BY oi.order_id, mii.inventory_id;
+
+-- Mock orders are costed at the seeded ingredient costs
+UPDATE order_item oi SET unit_cost = (
+    SELECT COALESCE(SUM(mii.quantity * i.unit_cost), 0)
+    FROM menu_item_inventory mii
+    JOIN inventory i ON i.id = mii.inventory_id
+    WHERE mii.menu_id = oi.menu_item_id
+);
+
+-- Mock orders are dine-in and taxed at the seeded tax classes
+UPDATE order_item oi SET tax_class = tc.name, tax_rate = tc.rate, tax_added = tc.added_to_price
+FROM menu_items mi
+JOIN tax_classes tc ON tc.id = mi.tax_class_id
+WHERE mi.id = oi.menu_item_id;
+
+UPDATE orders o SET net_total = t.net, tax_total = t.tax, gross_total = t.net + t.tax
+FROM (
+    SELECT order_id,
+        SUM(line_total - CASE WHEN tax_added THEN 0 ELSE tax END) AS net,
+        SUM(tax) AS tax
+    FROM order_item
+    GROUP BY order_id
+) t
+WHERE o.id = t.order_id;
+
+-- Closed mock orders were paid in cash
+INSERT INTO payments (order_id, tender, amount, tendered, created_at)
+SELECT id, 'cash', gross_total, gross_total, created_at
+FROM orders
+WHERE order_status = 'closed' AND gross_total > 0;
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/utils"
+)
+
+func (app *application) customerCreate(w http.ResponseWriter, r *http.Request) {
+	var customer models.Customer
+	err := json.NewDecoder(r.Body).Decode(&customer)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	customer, m, err := app.CustomerSvc.Insert(customer)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, customer)
+}
+
+func (app *application) customerRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	customers, err := app.CustomerSvc.RetrieveAll(r.URL.Query().Get("q"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, customers)
+}
+
+func (app *application) customerRetrieveByID(w http.ResponseWriter, r *http.Request) {
+	customer, err := app.CustomerSvc.RetrieveByID(r.PathValue("id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, customer)
+}
+
+func (app *application) customerUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var customer models.Customer
+	err := json.NewDecoder(r.Body).Decode(&customer)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.CustomerSvc.Update(id, customer)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated customer %s", id)})
+}
+
+func (app *application) customerDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.CustomerSvc.Delete(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+// customerOrders lists the customer's orders, paged and filtered like GET /orders
+func (app *application) customerOrders(w http.ResponseWriter, r *http.Request) {
+	customer, err := app.CustomerSvc.RetrieveByID(r.PathValue("id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	queryArgs := r.URL.Query()
+	page, _ := strconv.Atoi(queryArgs.Get("page"))
+	pageSize, _ := strconv.Atoi(queryArgs.Get("pageSize"))
+
+	orders, err := app.OrderSvc.RetrieveAll(models.OrderFilter{
+		Status:     queryArgs.Get("status"),
+		CustomerID: customer.ID,
+		From:       queryArgs.Get("from"),
+		To:         queryArgs.Get("to"),
+		Sort:       queryArgs.Get("sort"),
+		Page:       page,
+		PageSize:   pageSize,
+	})
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, orders)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"frappuccino/internal/tabular"
+	"frappuccino/internal/utils"
+)
+
+// sendReport responds with data as JSON, or with table as a CSV or XLSX file
+// named name when the request asks for one (see tabular.Negotiate). table may
+// be nil if the caller already wrote any file asked for with writeTable.
+func (app *application) sendReport(w http.ResponseWriter, r *http.Request, name string, data any, table tabular.Table) {
+	format, err := tabular.Negotiate(r)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+	if format == tabular.FormatJSON {
+		utils.SendJSONResponse(w, http.StatusOK, data)
+		return
+	}
+
+	app.writeTable(w, format, name, table)
+}
+
+// exportFormat returns the CSV or XLSX format the request asks for, or "" when
+// it asks for JSON or for a format sendReport rejects. Reports read from the
+// database while they are written use it to skip loading the data in full.
+func exportFormat(r *http.Request) string {
+	format, err := tabular.Negotiate(r)
+	if err != nil || format == tabular.FormatJSON {
+		return ""
+	}
+	return format
+}
+
+func (app *application) writeTable(w http.ResponseWriter, format, name string, table tabular.Table) {
+	if err := tabular.Write(w, format, name, table); err != nil {
+		// the status is already sent; the client gets a truncated file
+		app.logger.Error("Failed to write report", "report", name, "format", format, "error", err)
+	}
+}
+
+// optionalInt is a table cell for a value that may be missing
+func optionalInt(value *int) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
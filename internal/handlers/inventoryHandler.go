@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"frappuccino/internal/models"
+	"frappuccino/internal/tabular"
 	"frappuccino/internal/utils"
 )
 
@@ -71,6 +72,45 @@ func (app *application) inventoryUpdateByID(w http.ResponseWriter, r *http.Reque
 	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated inventory %s", id)})
 }
 
+func (app *application) inventoryAdjustByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var adjustment models.InventoryAdjustment
+	err := json.NewDecoder(r.Body).Decode(&adjustment)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	adjusted, m, err := app.InventorySvc.Adjust(id, adjustment)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, adjusted)
+}
+
+func (app *application) inventoryBatchAdjust(w http.ResponseWriter, r *http.Request) {
+	var batch models.BatchInventoryAdjustment
+	err := json.NewDecoder(r.Body).Decode(&batch)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	adjusted, m, err := app.InventorySvc.BatchAdjust(batch)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, adjusted)
+}
+
 func (app *application) inventoryDeleteByID(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	err := app.InventorySvc.Delete(id)
@@ -83,6 +123,41 @@ func (app *application) inventoryDeleteByID(w http.ResponseWriter, r *http.Reque
 	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
 }
 
+func (app *application) inventoryTransactionsByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	transactions, err := app.InventorySvc.Transactions(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, transactions)
+}
+
+func (app *application) inventoryTransactions(w http.ResponseWriter, r *http.Request) {
+	queryArgs := r.URL.Query()
+	transactions, err := app.InventorySvc.TransactionsByPeriod(queryArgs.Get("from"), queryArgs.Get("to"), queryArgs.Get("reason"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, transactions)
+}
+
+func (app *application) inventoryLowStock(w http.ResponseWriter, r *http.Request) {
+	items, err := app.InventorySvc.LowStock()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, items)
+}
+
 func (app *application) inventoryGetLeftOvers(w http.ResponseWriter, r *http.Request) {
 	sortBy := r.URL.Query().Get("sortBy")
 	pageStr := r.URL.Query().Get("page")
@@ -98,5 +173,10 @@ func (app *application) inventoryGetLeftOvers(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	utils.SendJSONResponse(w, http.StatusOK, result)
+	app.sendReport(w, r, "leftovers", result, tabular.FromSlice(
+		[]string{"name", "quantity"},
+		result.Data,
+		func(item models.InventoryLeftOverItem) []any {
+			return []any{item.Name, item.Quantity}
+		}))
 }
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/utils"
+)
+
+func (app *application) loyaltyRuleCreate(w http.ResponseWriter, r *http.Request) {
+	var rule models.LoyaltyRule
+	err := json.NewDecoder(r.Body).Decode(&rule)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	rule, m, err := app.LoyaltySvc.InsertRule(rule)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, rule)
+}
+
+func (app *application) loyaltyRuleRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	rules, err := app.LoyaltySvc.RetrieveRules()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, rules)
+}
+
+func (app *application) loyaltyRuleUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var rule models.LoyaltyRule
+	err := json.NewDecoder(r.Body).Decode(&rule)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.LoyaltySvc.UpdateRule(id, rule)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated loyalty rule %s", id)})
+}
+
+func (app *application) loyaltyRuleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.LoyaltySvc.DeleteRule(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+func (app *application) loyaltyRewardCreate(w http.ResponseWriter, r *http.Request) {
+	var reward models.LoyaltyReward
+	err := json.NewDecoder(r.Body).Decode(&reward)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	reward, m, err := app.LoyaltySvc.InsertReward(reward)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, reward)
+}
+
+func (app *application) loyaltyRewardRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	rewards, err := app.LoyaltySvc.RetrieveRewards()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, rewards)
+}
+
+func (app *application) loyaltyRewardUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var reward models.LoyaltyReward
+	err := json.NewDecoder(r.Body).Decode(&reward)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.LoyaltySvc.UpdateReward(id, reward)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated loyalty reward %s", id)})
+}
+
+func (app *application) loyaltyRewardDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.LoyaltySvc.DeleteReward(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+func (app *application) customerLoyalty(w http.ResponseWriter, r *http.Request) {
+	loyalty, err := app.LoyaltySvc.CustomerLoyalty(r.PathValue("id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, loyalty)
+}
@@ -29,7 +29,7 @@ func (app *application) menuCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) menuRetrieveAll(w http.ResponseWriter, r *http.Request) {
-	menuItems, err := app.MenuSvc.RetrieveAll()
+	menuItems, err := app.MenuSvc.RetrieveAll(r.URL.Query().Get("category"))
 	if err != nil {
 		utils.SendJSONResponse(w, http.StatusInternalServerError, utils.Response{"error": "Internal Server Error"})
 		return
@@ -81,3 +81,206 @@ func (app *application) menuDelete(w http.ResponseWriter, r *http.Request) {
 
 	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
 }
+
+func (app *application) menuRetrieveGrouped(w http.ResponseWriter, r *http.Request) {
+	groups, err := app.MenuSvc.RetrieveGrouped()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, groups)
+}
+
+func (app *application) menuRetrieveAvailability(w http.ResponseWriter, r *http.Request) {
+	items, err := app.MenuSvc.RetrieveAvailability(r.URL.Query().Get("threshold"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, items)
+}
+
+func (app *application) categoryCreate(w http.ResponseWriter, r *http.Request) {
+	var category models.MenuCategory
+	err := json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.MenuSvc.InsertCategory(category)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, utils.Response{"message": "created"})
+}
+
+func (app *application) categoryRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	categories, err := app.MenuSvc.RetrieveCategories()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, categories)
+}
+
+func (app *application) categoryUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var category models.MenuCategory
+	err := json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.MenuSvc.UpdateCategory(id, category)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated category %s", id)})
+}
+
+func (app *application) categoryDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.MenuSvc.DeleteCategory(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+func (app *application) taxClassCreate(w http.ResponseWriter, r *http.Request) {
+	var taxClass models.TaxClass
+	err := json.NewDecoder(r.Body).Decode(&taxClass)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.MenuSvc.InsertTaxClass(taxClass)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, utils.Response{"message": "created"})
+}
+
+func (app *application) taxClassRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	taxClasses, err := app.MenuSvc.RetrieveTaxClasses()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, taxClasses)
+}
+
+func (app *application) taxClassUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var taxClass models.TaxClass
+	err := json.NewDecoder(r.Body).Decode(&taxClass)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.MenuSvc.UpdateTaxClass(id, taxClass)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated tax class %s", id)})
+}
+
+func (app *application) taxClassDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.MenuSvc.DeleteTaxClass(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+func (app *application) menuPriceHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	history, err := app.MenuSvc.RetrievePriceHistory(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, history)
+}
+
+func (app *application) menuSchedulePrice(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var change models.ScheduledPriceChange
+	err := json.NewDecoder(r.Body).Decode(&change)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	change, m, err := app.MenuSvc.SchedulePriceChange(id, change)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, change)
+}
+
+func (app *application) menuScheduledPrices(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	changes, err := app.MenuSvc.RetrieveScheduledPriceChanges(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, changes)
+}
+
+func (app *application) menuDeleteScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	changeID := r.PathValue("changeID")
+	err := app.MenuSvc.DeleteScheduledPriceChange(id, changeID)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted scheduled price change %s", changeID)})
+}
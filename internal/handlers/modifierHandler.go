@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/utils"
+)
+
+func (app *application) modifierGroupCreate(w http.ResponseWriter, r *http.Request) {
+	var group models.ModifierGroup
+	err := json.NewDecoder(r.Body).Decode(&group)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.MenuSvc.InsertModifierGroup(group)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, utils.Response{"message": "created"})
+}
+
+func (app *application) modifierGroupRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	groups, err := app.MenuSvc.RetrieveModifierGroups()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, groups)
+}
+
+func (app *application) modifierGroupRetrieveByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	group, err := app.MenuSvc.RetrieveModifierGroupByID(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, group)
+}
+
+func (app *application) modifierGroupUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var group models.ModifierGroup
+	err := json.NewDecoder(r.Body).Decode(&group)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.MenuSvc.UpdateModifierGroup(id, group)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated modifier group %s", id)})
+}
+
+func (app *application) modifierGroupDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.MenuSvc.DeleteModifierGroup(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+func (app *application) menuRetrieveModifiers(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	groups, err := app.MenuSvc.RetrieveMenuItemModifiers(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, groups)
+}
+
+func (app *application) menuUpdateModifiers(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var groups models.MenuItemModifierGroups
+	err := json.NewDecoder(r.Body).Decode(&groups)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	err = app.MenuSvc.SetMenuItemModifiers(id, groups.GroupIDs)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated modifiers of menu item %s", id)})
+}
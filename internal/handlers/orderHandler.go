@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"frappuccino/internal/models"
+	"frappuccino/internal/tabular"
 	"frappuccino/internal/utils"
 )
 
@@ -29,7 +35,19 @@ func (app *application) orderCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) orderRetrieveAll(w http.ResponseWriter, r *http.Request) {
-	orders, err := app.OrderSvc.RetrieveAll()
+	queryArgs := r.URL.Query()
+	page, _ := strconv.Atoi(queryArgs.Get("page"))
+	pageSize, _ := strconv.Atoi(queryArgs.Get("pageSize"))
+
+	orders, err := app.OrderSvc.RetrieveAll(models.OrderFilter{
+		Status:   queryArgs.Get("status"),
+		Customer: queryArgs.Get("customer"),
+		From:     queryArgs.Get("from"),
+		To:       queryArgs.Get("to"),
+		Sort:     queryArgs.Get("sort"),
+		Page:     page,
+		PageSize: pageSize,
+	})
 	if err != nil {
 		status, body := utils.MapErrorToResponse(err, nil)
 		utils.SendJSONResponse(w, status, body)
@@ -83,18 +101,95 @@ func (app *application) orderDeleteByID(w http.ResponseWriter, r *http.Request)
 	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
 }
 
-func (app *application) orderCloseByID(w http.ResponseWriter, r *http.Request) {
+func (app *application) orderStartByID(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	err := app.OrderSvc.Close(id)
+	err := app.OrderSvc.Start(id)
 	if err != nil {
 		status, body := utils.MapErrorToResponse(err, nil)
 		utils.SendJSONResponse(w, status, body)
 		return
 	}
 
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Started %s", id)})
+}
+
+func (app *application) orderCloseByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	// an empty body closes a fully paid order
+	var close models.OrderClose
+	err := json.NewDecoder(r.Body).Decode(&close)
+	if err != nil && !errors.Is(err, io.EOF) {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.OrderSvc.Close(id, close)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
 	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Closed %s", id)})
 }
 
+func (app *application) orderCancelByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var cancelRequest models.OrderCancelRequest
+	err := json.NewDecoder(r.Body).Decode(&cancelRequest)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	cancellation, err := app.OrderSvc.Cancel(id, cancelRequest.Reason)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, cancellation)
+}
+
+func (app *application) orderReopenByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.OrderSvc.Reopen(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Reopened %s", id)})
+}
+
+func (app *application) orderHistoryByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	history, err := app.OrderSvc.History(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, history)
+}
+
+func (app *application) orderStatusHistory(w http.ResponseWriter, r *http.Request) {
+	queryArgs := r.URL.Query()
+	history, err := app.OrderSvc.StatusHistory(queryArgs.Get("from"), queryArgs.Get("to"), queryArgs.Get("status"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, history)
+}
+
 func (app *application) numberOfOrderedItems(w http.ResponseWriter, r *http.Request) {
 	queryArgs := r.URL.Query()
 	startDateArgs, endDateArgs := queryArgs["startDate"], queryArgs["endDate"]
@@ -113,7 +208,12 @@ func (app *application) numberOfOrderedItems(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	utils.SendJSONResponse(w, http.StatusOK, data)
+	app.sendReport(w, r, "ordered-items", data, tabular.FromSlice(
+		[]string{"menu_item", "quantity"},
+		slices.Sorted(maps.Keys(data)),
+		func(name string) []any {
+			return []any{name, data[name]}
+		}))
 }
 
 func (app *application) orderButchCreate(w http.ResponseWriter, r *http.Request) {
@@ -134,3 +234,22 @@ func (app *application) orderButchCreate(w http.ResponseWriter, r *http.Request)
 
 	utils.SendJSONResponse(w, http.StatusCreated, batchOrderResponse)
 }
+
+func (app *application) orderBatchCancel(w http.ResponseWriter, r *http.Request) {
+	var batchCancelRequest models.BatchCancelRequest
+	err := json.NewDecoder(r.Body).Decode(&batchCancelRequest)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	batchCancelResponse, err := app.OrderSvc.BatchCancel(batchCancelRequest)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, batchCancelResponse)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/utils"
+)
+
+func (app *application) paymentCreate(w http.ResponseWriter, r *http.Request) {
+	var payment models.Payment
+	err := json.NewDecoder(r.Body).Decode(&payment)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	payment, m, err := app.PaymentSvc.Insert(r.Context(), r.PathValue("id"), payment)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, payment)
+}
+
+func (app *application) paymentRetrieveByOrder(w http.ResponseWriter, r *http.Request) {
+	bill, err := app.PaymentSvc.RetrieveByOrder(r.PathValue("id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, bill)
+}
+
+func (app *application) paymentVoid(w http.ResponseWriter, r *http.Request) {
+	payment, err := app.PaymentSvc.Void(r.Context(), r.PathValue("id"), r.PathValue("paymentID"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, payment)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/utils"
+)
+
+func (app *application) promotionCreate(w http.ResponseWriter, r *http.Request) {
+	var promotion models.Promotion
+	err := json.NewDecoder(r.Body).Decode(&promotion)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	promotion, m, err := app.PromotionSvc.Insert(promotion)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, promotion)
+}
+
+func (app *application) promotionRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	promotions, err := app.PromotionSvc.RetrieveAll()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, promotions)
+}
+
+func (app *application) promotionRetrieveByID(w http.ResponseWriter, r *http.Request) {
+	promotion, err := app.PromotionSvc.RetrieveByID(r.PathValue("id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, promotion)
+}
+
+func (app *application) promotionUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var promotion models.Promotion
+	err := json.NewDecoder(r.Body).Decode(&promotion)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.PromotionSvc.Update(id, promotion)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated promotion %s", id)})
+}
+
+func (app *application) promotionDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.PromotionSvc.Delete(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
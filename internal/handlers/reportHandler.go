@@ -2,30 +2,125 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+	"frappuccino/internal/tabular"
 	"frappuccino/internal/utils"
 )
 
+// reportQuery reads the query parameters shared by the sales reports
+func reportQuery(r *http.Request) models.ReportQuery {
+	query := r.URL.Query()
+	return models.ReportQuery{
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		Limit:     query.Get("limit"),
+		GroupBy:   query.Get("groupBy"),
+		CompareTo: query.Get("compareTo"),
+		Category:  query.Get("category"),
+	}
+}
+
 func (app *application) getTotalSalesReport(w http.ResponseWriter, r *http.Request) {
-	report, err := app.ReportSvc.GetTotalSales()
+	query := reportQuery(r)
+	// grouped reports may span many hours or days, so their groups are
+	// written as they are read
+	if format := exportFormat(r); format != "" && query.GroupBy != "" {
+		groupBy, groups, err := app.ReportSvc.SalesGroups(query)
+		if err != nil {
+			status, body := utils.MapErrorToResponse(err, nil)
+			utils.SendJSONResponse(w, status, body)
+			return
+		}
+		app.writeTable(w, format, "total-sales", salesGroupsTable(groupBy, groups))
+		return
+	}
+
+	report, err := app.ReportSvc.GetTotalSales(query)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	app.sendReport(w, r, "total-sales", report, totalSalesTable(report))
+}
+
+func salesGroupsTable(groupBy string, groups service.RowSource[models.ReportSalesGroup]) tabular.Table {
+	return tabular.New(
+		[]string{groupBy, "orders_completed", "total_sales", "net_sales", "tax", "discounts", "cogs", "gross_profit"},
+		func(write func(row []any) error) error {
+			return groups(func(group models.ReportSalesGroup) error {
+				return write([]any{group.Group, group.OrdersCompleted, group.TotalSales, group.NetSales, group.Tax, group.Discounts, group.COGS, group.GrossProfit})
+			})
+		})
+}
+
+// totalSalesTable lists the period's totals followed by the previous period's
+// when compared; grouped reports are written by salesGroupsTable
+func totalSalesTable(report models.ReportTotalSales) tabular.Table {
+	periods := []models.ReportTotalSales{report}
+	if report.Previous != nil {
+		periods = append(periods, *report.Previous)
+	}
+	return tabular.FromSlice(
+		[]string{"from", "to", "orders_completed", "total_sales", "net_sales", "tax", "discounts", "cogs", "gross_profit"},
+		periods,
+		func(period models.ReportTotalSales) []any {
+			return []any{period.From, period.To, period.OrdersCompleted, period.TotalSales, period.NetSales, period.Tax, period.Discounts, period.COGS, period.GrossProfit}
+		})
+}
+
+func (app *application) getTaxReport(w http.ResponseWriter, r *http.Request) {
+	report, err := app.ReportSvc.GetTaxSummary(reportQuery(r))
 	if err != nil {
 		status, body := utils.MapErrorToResponse(err, nil)
 		utils.SendJSONResponse(w, status, body)
 		return
 	}
 
-	utils.SendJSONResponse(w, http.StatusOK, report)
+	app.sendReport(w, r, "tax", report, tabular.FromSlice(
+		[]string{"tax_class", "rate", "lines", "net", "tax", "gross"},
+		report.Classes,
+		func(class models.ReportTaxClass) []any {
+			return []any{class.TaxClass, class.Rate, class.Lines, class.Net, class.Tax, class.Gross}
+		}))
+}
+
+func (app *application) getMenuMargins(w http.ResponseWriter, r *http.Request) {
+	margins, err := app.ReportSvc.MenuMargins()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	app.sendReport(w, r, "menu-margins", margins, tabular.FromSlice(
+		[]string{"menu_id", "name", "category", "price", "cost", "margin", "margin_percent"},
+		margins,
+		func(margin models.ReportMenuMargin) []any {
+			return []any{margin.MenuID, margin.Name, margin.Category, margin.Price, margin.Cost, margin.Margin, margin.MarginPercent}
+		}))
 }
 
 func (app *application) getPopularMenuItems(w http.ResponseWriter, r *http.Request) {
-	popularItems, err := app.ReportSvc.GetPopularMenuItems()
+	popularItems, err := app.ReportSvc.GetPopularMenuItems(reportQuery(r))
 	if err != nil {
 		status, body := utils.MapErrorToResponse(err, nil)
 		utils.SendJSONResponse(w, status, body)
 		return
 	}
 
-	utils.SendJSONResponse(w, http.StatusOK, popularItems)
+	app.sendReport(w, r, "popular-items", popularItems, tabular.FromSlice(
+		[]string{"group", "rank", "name", "category", "price", "total_items_sold", "total_revenue",
+			"previous_rank", "previous_items_sold", "items_sold_change"},
+		popularItems,
+		func(item models.ReportPopularItem) []any {
+			return []any{item.Group, item.Rank, item.Name, item.Category, item.Price, item.TotalItemsSold, item.TotalRevenue,
+				optionalInt(item.PreviousRank), optionalInt(item.PreviousItemsSold), optionalInt(item.ItemsSoldChange)}
+		}))
 }
 
 func (app *application) textSearch(w http.ResponseWriter, r *http.Request) {
@@ -47,35 +142,83 @@ func (app *application) textSearch(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if format := exportFormat(r); format != "" {
+		menuMatches, orderMatches, err := app.ReportSvc.SearchMatches(args[0], args[1], args[2], args[3])
+		if err != nil {
+			status, body := utils.MapErrorToResponse(err, nil)
+			utils.SendJSONResponse(w, status, body)
+			return
+		}
+		app.writeTable(w, format, "search", searchTable(menuMatches, orderMatches))
+		return
+	}
+
 	data, err := app.ReportSvc.TextSearch(args[0], args[1], args[2], args[3])
 	if err != nil {
 		status, body := utils.MapErrorToResponse(err, nil)
 		utils.SendJSONResponse(w, status, body)
 		return
 	}
-	utils.SendJSONResponse(w, http.StatusOK, data)
+	app.sendReport(w, r, "search", data, nil)
+}
+
+// searchTable lists the menu matches, then the order matches
+func searchTable(menuMatches service.RowSource[models.ReportMenuSearchItem], orderMatches service.RowSource[models.ReportOrderSearchItem]) tabular.Table {
+	return tabular.New(
+		[]string{"type", "id", "name", "details", "price", "relevance"},
+		func(write func(row []any) error) error {
+			err := menuMatches(func(item models.ReportMenuSearchItem) error {
+				return write([]any{"menu", item.Id, item.Name, item.Description, item.Price, float64(item.Relevance)})
+			})
+			if err != nil {
+				return err
+			}
+			return orderMatches(func(order models.ReportOrderSearchItem) error {
+				return write([]any{"order", order.Id, order.CustomerName, strings.Join(order.Items, ", "), order.Total, float64(order.Relevance)})
+			})
+		})
 }
 
 func (app *application) orderedItemsByPeriod(w http.ResponseWriter, r *http.Request) {
 	queryArgs := r.URL.Query()
-	periodArr := queryArgs["period"]
-	if len(periodArr) == 0 {
+	if queryArgs.Get("period") == "" {
 		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "period must be specified"})
 		return
 	}
-	monthArr := queryArgs["month"]
-	yearArr := queryArgs["year"]
-	if len(monthArr) == 0 {
-		monthArr = append(monthArr, "")
+	query := models.OrderedItemsQuery{
+		Period: queryArgs.Get("period"),
+		From:   queryArgs.Get("from"),
+		To:     queryArgs.Get("to"),
+		Month:  queryArgs.Get("month"),
+		Year:   queryArgs.Get("year"),
+		Count:  queryArgs.Get("count"),
 	}
-	if len(yearArr) == 0 {
-		yearArr = append(yearArr, "")
+
+	if format := exportFormat(r); format != "" {
+		data, periods, err := app.ReportSvc.OrderedItemsPeriods(query)
+		if err != nil {
+			status, body := utils.MapErrorToResponse(err, nil)
+			utils.SendJSONResponse(w, status, body)
+			return
+		}
+		app.writeTable(w, format, "ordered-items", tabular.New(
+			[]string{data.Period, data.Count},
+			func(write func(row []any) error) error {
+				return periods(func(entry map[string]int) error {
+					for period, count := range entry {
+						return write([]any{period, count})
+					}
+					return nil
+				})
+			}))
+		return
 	}
-	data, err := app.ReportSvc.OrderedItemsByPeriod(periodArr[0], monthArr[0], yearArr[0])
+
+	data, err := app.ReportSvc.OrderedItemsByPeriod(query)
 	if err != nil {
 		status, body := utils.MapErrorToResponse(err, nil)
 		utils.SendJSONResponse(w, status, body)
 		return
 	}
-	utils.SendJSONResponse(w, http.StatusOK, data)
+	app.sendReport(w, r, "ordered-items", data, nil)
 }
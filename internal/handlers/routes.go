@@ -13,6 +13,12 @@ type application struct {
 	MenuSvc      service.MenuService
 	OrderSvc     service.OrderService
 	ReportSvc    service.ReportService
+	SupplierSvc  service.SupplierService
+	ShiftSvc     service.ShiftService
+	CustomerSvc  service.CustomerService
+	LoyaltySvc   service.LoyaltyService
+	PromotionSvc service.PromotionService
+	PaymentSvc   service.PaymentService
 	// add more services
 }
 
@@ -21,6 +27,12 @@ func NewApplication(logger *slog.Logger,
 	menuSvc service.MenuService,
 	orderSvc service.OrderService,
 	reportSvc service.ReportService,
+	supplierSvc service.SupplierService,
+	shiftSvc service.ShiftService,
+	customerSvc service.CustomerService,
+	loyaltySvc service.LoyaltyService,
+	promotionSvc service.PromotionService,
+	paymentSvc service.PaymentService,
 ) *application {
 	return &application{
 		logger:       logger,
@@ -28,6 +40,12 @@ func NewApplication(logger *slog.Logger,
 		MenuSvc:      menuSvc,
 		OrderSvc:     orderSvc,
 		ReportSvc:    reportSvc,
+		SupplierSvc:  supplierSvc,
+		ShiftSvc:     shiftSvc,
+		CustomerSvc:  customerSvc,
+		LoyaltySvc:   loyaltySvc,
+		PromotionSvc: promotionSvc,
+		PaymentSvc:   paymentSvc,
 		// add more services
 	}
 }
@@ -41,19 +59,67 @@ func (app *application) Routes() http.Handler {
 
 	endpoints := map[string]http.HandlerFunc{
 		// inventory endpoints
-		"POST /inventory":        app.inventoryCreate,
-		"GET /inventory":         app.inventoryRetreiveAll,
-		"GET /inventory/{id}":    app.inventoryRetrieveByID,
-		"PUT /inventory/{id}":    app.inventoryUpdateByID,
-		"DELETE /inventory/{id}": app.inventoryDeleteByID,
-		"GET /getLeftOvers":      app.inventoryGetLeftOvers,
+		"POST /inventory":                  app.inventoryCreate,
+		"GET /inventory":                   app.inventoryRetreiveAll,
+		"GET /inventory/{id}":              app.inventoryRetrieveByID,
+		"PUT /inventory/{id}":              app.inventoryUpdateByID,
+		"DELETE /inventory/{id}":           app.inventoryDeleteByID,
+		"GET /inventory/{id}/transactions": app.inventoryTransactionsByID,
+		"GET /inventory/transactions":      app.inventoryTransactions,
+		"POST /inventory/{id}/adjustments": app.inventoryAdjustByID,
+		"POST /inventory/adjustments":      app.inventoryBatchAdjust,
+		"GET /inventory/low-stock":         app.inventoryLowStock,
+		"GET /getLeftOvers":                app.inventoryGetLeftOvers,
+
+		// supplier endpoints
+		"POST /suppliers":        app.supplierCreate,
+		"GET /suppliers":         app.supplierRetrieveAll,
+		"GET /suppliers/{id}":    app.supplierRetrieveByID,
+		"PUT /suppliers/{id}":    app.supplierUpdate,
+		"DELETE /suppliers/{id}": app.supplierDelete,
+
+		// purchase order endpoints
+		"POST /purchase-orders":              app.purchaseOrderCreate,
+		"GET /purchase-orders":               app.purchaseOrderRetrieveAll,
+		"GET /purchase-orders/{id}":          app.purchaseOrderRetrieveByID,
+		"PUT /purchase-orders/{id}":          app.purchaseOrderUpdate,
+		"DELETE /purchase-orders/{id}":       app.purchaseOrderDelete,
+		"POST /purchase-orders/{id}/send":    app.purchaseOrderSend,
+		"POST /purchase-orders/{id}/receive": app.purchaseOrderReceive,
 
 		// menu endpoints
-		"POST /menu":        app.menuCreate,
-		"GET /menu":         app.menuRetrieveAll,
-		"GET /menu/{id}":    app.menuRetrieveAllByID,
-		"PUT /menu/{id}":    app.menuUpdate,
-		"DELETE /menu/{id}": app.menuDelete,
+		"POST /menu":                                    app.menuCreate,
+		"GET /menu":                                     app.menuRetrieveAll,
+		"GET /menu/{id}":                                app.menuRetrieveAllByID,
+		"PUT /menu/{id}":                                app.menuUpdate,
+		"DELETE /menu/{id}":                             app.menuDelete,
+		"GET /menu/grouped":                             app.menuRetrieveGrouped,
+		"GET /menu/availability":                        app.menuRetrieveAvailability,
+		"GET /menu/{id}/modifiers":                      app.menuRetrieveModifiers,
+		"PUT /menu/{id}/modifiers":                      app.menuUpdateModifiers,
+		"GET /menu/{id}/price-history":                  app.menuPriceHistory,
+		"POST /menu/{id}/scheduled-prices":              app.menuSchedulePrice,
+		"GET /menu/{id}/scheduled-prices":               app.menuScheduledPrices,
+		"DELETE /menu/{id}/scheduled-prices/{changeID}": app.menuDeleteScheduledPrice,
+
+		// modifier endpoints
+		"POST /modifier-groups":        app.modifierGroupCreate,
+		"GET /modifier-groups":         app.modifierGroupRetrieveAll,
+		"GET /modifier-groups/{id}":    app.modifierGroupRetrieveByID,
+		"PUT /modifier-groups/{id}":    app.modifierGroupUpdate,
+		"DELETE /modifier-groups/{id}": app.modifierGroupDelete,
+
+		// menu category endpoints
+		"POST /categories":        app.categoryCreate,
+		"GET /categories":         app.categoryRetrieveAll,
+		"PUT /categories/{id}":    app.categoryUpdate,
+		"DELETE /categories/{id}": app.categoryDelete,
+
+		// tax class endpoints
+		"POST /tax-classes":        app.taxClassCreate,
+		"GET /tax-classes":         app.taxClassRetrieveAll,
+		"PUT /tax-classes/{id}":    app.taxClassUpdate,
+		"DELETE /tax-classes/{id}": app.taxClassDelete,
 
 		// orders endpoints
 		"POST /orders":                     app.orderCreate,
@@ -61,15 +127,63 @@ func (app *application) Routes() http.Handler {
 		"GET /orders/{id}":                 app.orderRetrieveByID,
 		"PUT /orders/{id}":                 app.orderUpdateByID,
 		"DELETE /orders/{id}":              app.orderDeleteByID,
+		"POST /orders/{id}/start":          app.orderStartByID,
+		"POST /orders/{id}/complete":       app.orderCloseByID,
 		"POST /orders/{id}/close":          app.orderCloseByID,
+		"POST /orders/{id}/cancel":         app.orderCancelByID,
+		"POST /orders/{id}/reopen":         app.orderReopenByID,
+		"GET /orders/{id}/history":         app.orderHistoryByID,
+		"GET /orders/status-history":       app.orderStatusHistory,
 		"POST /orders/batch-process":       app.orderButchCreate,
+		"POST /orders/batch-cancel":        app.orderBatchCancel,
 		"GET /orders/numberOfOrderedItems": app.numberOfOrderedItems,
 
+		// payments endpoints
+		"POST /orders/{id}/payments":                  app.paymentCreate,
+		"GET /orders/{id}/payments":                   app.paymentRetrieveByOrder,
+		"POST /orders/{id}/payments/{paymentID}/void": app.paymentVoid,
+
+		// customer endpoints
+		"POST /customers":             app.customerCreate,
+		"GET /customers":              app.customerRetrieveAll,
+		"GET /customers/{id}":         app.customerRetrieveByID,
+		"PUT /customers/{id}":         app.customerUpdate,
+		"DELETE /customers/{id}":      app.customerDelete,
+		"GET /customers/{id}/orders":  app.customerOrders,
+		"GET /customers/{id}/loyalty": app.customerLoyalty,
+
+		// loyalty endpoints
+		"POST /loyalty/rules":          app.loyaltyRuleCreate,
+		"GET /loyalty/rules":           app.loyaltyRuleRetrieveAll,
+		"PUT /loyalty/rules/{id}":      app.loyaltyRuleUpdate,
+		"DELETE /loyalty/rules/{id}":   app.loyaltyRuleDelete,
+		"POST /loyalty/rewards":        app.loyaltyRewardCreate,
+		"GET /loyalty/rewards":         app.loyaltyRewardRetrieveAll,
+		"PUT /loyalty/rewards/{id}":    app.loyaltyRewardUpdate,
+		"DELETE /loyalty/rewards/{id}": app.loyaltyRewardDelete,
+
+		// promotion endpoints
+		"POST /promotions":        app.promotionCreate,
+		"GET /promotions":         app.promotionRetrieveAll,
+		"GET /promotions/{id}":    app.promotionRetrieveByID,
+		"PUT /promotions/{id}":    app.promotionUpdate,
+		"DELETE /promotions/{id}": app.promotionDelete,
+
 		// aggregations endpoints
 		"GET /reports/total-sales":          app.getTotalSalesReport,
+		"GET /reports/tax":                  app.getTaxReport,
 		"GET /reports/popular-items":        app.getPopularMenuItems,
+		"GET /reports/menu-margins":         app.getMenuMargins,
 		"GET /reports/search":               app.textSearch,
 		"GET /reports/orderedItemsByPeriod": app.orderedItemsByPeriod,
+		"GET /reports/shifts/{id}":          app.shiftZReport,
+
+		// shift endpoints
+		"POST /shifts":            app.shiftOpen,
+		"GET /shifts":             app.shiftRetrieveAll,
+		"GET /shifts/current":     app.shiftRetrieveCurrent,
+		"GET /shifts/{id}":        app.shiftRetrieveByID,
+		"POST /shifts/{id}/close": app.shiftClose,
 	}
 
 	for endpoint, f := range endpoints {
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/tabular"
+	"frappuccino/internal/utils"
+)
+
+func (app *application) shiftOpen(w http.ResponseWriter, r *http.Request) {
+	var shift models.Shift
+	err := json.NewDecoder(r.Body).Decode(&shift)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	opened, m, err := app.ShiftSvc.Open(shift)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, opened)
+}
+
+func (app *application) shiftRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	shifts, err := app.ShiftSvc.RetrieveAll()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, shifts)
+}
+
+func (app *application) shiftRetrieveByID(w http.ResponseWriter, r *http.Request) {
+	shift, err := app.ShiftSvc.RetrieveByID(r.PathValue("id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, shift)
+}
+
+func (app *application) shiftRetrieveCurrent(w http.ResponseWriter, r *http.Request) {
+	shift, err := app.ShiftSvc.RetrieveCurrent()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, shift)
+}
+
+func (app *application) shiftClose(w http.ResponseWriter, r *http.Request) {
+	var close models.ShiftClose
+	err := json.NewDecoder(r.Body).Decode(&close)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	report, m, err := app.ShiftSvc.Close(r.PathValue("id"), close)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, report)
+}
+
+func (app *application) shiftZReport(w http.ResponseWriter, r *http.Request) {
+	report, err := app.ShiftSvc.ZReport(r.PathValue("id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	app.sendReport(w, r, "z-report-"+r.PathValue("id"), report, zReportTable(report))
+}
+
+// zReportTable lists the Z-report totals, then the items sold, then the
+// inventory consumed, each row tagged with its section
+func zReportTable(report models.ZReport) tabular.Table {
+	return tabular.New(
+		[]string{"section", "name", "quantity", "amount", "wasted"},
+		func(write func(row []any) error) error {
+			totals := [][]any{
+				{"total", "orders_taken", report.OrdersTaken, nil, nil},
+				{"total", "orders_closed", report.OrdersClosed, report.Revenue, nil},
+				{"total", "net_revenue", nil, report.NetRevenue, nil},
+				{"total", "tax", nil, report.Tax, nil},
+				{"total", "orders_cancelled", report.OrdersCancelled, report.CancelledValue, nil},
+				{"total", "orders_open", report.OrdersOpen, nil, nil},
+				{"total", "cogs", nil, report.COGS, nil},
+				{"total", "gross_profit", nil, report.GrossProfit, nil},
+			}
+			for _, row := range totals {
+				if err := write(row); err != nil {
+					return err
+				}
+			}
+			for _, item := range report.ItemsSold {
+				if err := write([]any{"item_sold", item.Name, item.Quantity, item.Revenue, nil}); err != nil {
+					return err
+				}
+			}
+			for _, item := range report.InventoryConsumed {
+				if err := write([]any{"inventory", item.Name + " (" + item.Unit + ")", item.Consumed, nil, item.Wasted}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+}
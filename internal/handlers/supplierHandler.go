@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/utils"
+)
+
+func (app *application) supplierCreate(w http.ResponseWriter, r *http.Request) {
+	var supplier models.Supplier
+	err := json.NewDecoder(r.Body).Decode(&supplier)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.SupplierSvc.InsertSupplier(supplier)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, utils.Response{"message": "created"})
+}
+
+func (app *application) supplierRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := app.SupplierSvc.RetrieveSuppliers()
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, suppliers)
+}
+
+func (app *application) supplierRetrieveByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	supplier, err := app.SupplierSvc.RetrieveSupplierByID(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, supplier)
+}
+
+func (app *application) supplierUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var supplier models.Supplier
+	err := json.NewDecoder(r.Body).Decode(&supplier)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.SupplierSvc.UpdateSupplier(id, supplier)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated supplier %s", id)})
+}
+
+func (app *application) supplierDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.SupplierSvc.DeleteSupplier(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+func (app *application) purchaseOrderCreate(w http.ResponseWriter, r *http.Request) {
+	var order models.PurchaseOrder
+	err := json.NewDecoder(r.Body).Decode(&order)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	order, m, err := app.SupplierSvc.InsertPurchaseOrder(order)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, order)
+}
+
+func (app *application) purchaseOrderRetrieveAll(w http.ResponseWriter, r *http.Request) {
+	queryArgs := r.URL.Query()
+	orders, err := app.SupplierSvc.RetrievePurchaseOrders(queryArgs.Get("status"), queryArgs.Get("supplier_id"))
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, orders)
+}
+
+func (app *application) purchaseOrderRetrieveByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	order, err := app.SupplierSvc.RetrievePurchaseOrderByID(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, order)
+}
+
+func (app *application) purchaseOrderUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var order models.PurchaseOrder
+	err := json.NewDecoder(r.Body).Decode(&order)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	m, err := app.SupplierSvc.UpdatePurchaseOrder(id, order)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Updated purchase order %s", id)})
+}
+
+func (app *application) purchaseOrderDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := app.SupplierSvc.DeletePurchaseOrder(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, utils.Response{"message": fmt.Sprintf("Deleted %s", id)})
+}
+
+func (app *application) purchaseOrderSend(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	order, err := app.SupplierSvc.SendPurchaseOrder(id)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, nil)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, order)
+}
+
+func (app *application) purchaseOrderReceive(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	// an empty body receives everything that is still outstanding
+	var receipt models.PurchaseOrderReceipt
+	err := json.NewDecoder(r.Body).Decode(&receipt)
+	if err != nil && !errors.Is(err, io.EOF) {
+		utils.SendJSONResponse(w, http.StatusBadRequest, utils.Response{"error": "request body does not match json format"})
+		return
+	}
+	defer r.Body.Close()
+
+	order, m, err := app.SupplierSvc.ReceivePurchaseOrder(id, receipt)
+	if err != nil {
+		status, body := utils.MapErrorToResponse(err, m)
+		utils.SendJSONResponse(w, status, body)
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, order)
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type Customer struct {
+	ID                 int       `json:"id"`
+	Name               string    `json:"name"`
+	Email              string    `json:"email,omitempty"`
+	Phone              string    `json:"phone,omitempty"`
+	DefaultPreferences Jsonb     `json:"default_preferences"` // pre-filled on the customer's new orders
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type customerValidator struct {
+	errors   map[string]string
+	customer Customer
+}
+
+func NewCustomerValidator(customer Customer) *customerValidator {
+	return &customerValidator{
+		errors:   make(map[string]string),
+		customer: customer,
+	}
+}
+
+func (v *customerValidator) Validate() map[string]string {
+	if v.customer.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if v.customer.Email != "" && !strings.Contains(v.customer.Email, "@") {
+		v.errors["Email"] = "Email is not valid"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrNoRecord         = errors.New("models: no record")
@@ -12,18 +15,102 @@ var (
 	// Inventory errors
 	ErrDuplicateInventory       = errors.New("models: duplicate inventory")
 	ErrInvalidEnumTypeInventory = errors.New("models: invalid enum type. Supported types: shots, ml, g, units")
+	ErrUnknownUnit              = errors.New("unknown unit; should be shots, ml, l, g, kg, units or pack")
+	ErrIncompatibleUnit         = errors.New("unit is not compatible with the inventory item's unit")
+	ErrFractionalQuantity       = errors.New("quantity is not a whole number of the inventory item's unit")
+	ErrInvalidInventoryReason   = errors.New("invalid reason; should be 'order', 'order_update', 'order_cancel', 'delivery', 'spoilage', 'spill' or 'count_correction'")
+	ErrInventoryUnitChange      = errors.New("the unit of an inventory item cannot be changed; create a new item instead")
+	ErrInventoryItemNotFound    = errors.New("inventory item does not exist")
 
 	// Menu errors
 	ErrDuplicateMenuItem                 = errors.New("models: duplicate menu item")
 	ErrForeignKeyConstraintMenuInventory = errors.New("inventory does not exist")
+	ErrForeignKeyConstraintMenuCategory  = errors.New("menu category does not exist")
+	ErrDuplicateMenuCategory             = errors.New("models: duplicate menu category")
+	ErrDuplicateModifierGroup            = errors.New("models: duplicate modifier group")
+	ErrForeignKeyConstraintModifierGroup = errors.New("modifier group does not exist")
+	ErrModifierNotInGroup                = errors.New("modifier does not belong to this modifier group")
+	ErrInvalidThreshold                  = errors.New("invalid threshold; should be a non-negative integer")
+	ErrDuplicateTaxClass                 = errors.New("models: duplicate tax class")
+	ErrForeignKeyConstraintTaxClass      = errors.New("tax class does not exist")
+	ErrTaxClassInUse                     = errors.New("tax class is assigned to menu items and cannot be deleted")
+
+	// Supplier errors
+	ErrDuplicateSupplier            = errors.New("models: duplicate supplier")
+	ErrForeignKeyConstraintSupplier = errors.New("supplier does not exist")
+	ErrSupplierHasPurchaseOrders    = errors.New("supplier has purchase orders and cannot be deleted")
+	ErrItemNotSupplied              = errors.New("supplier does not sell this inventory item")
+	ErrItemNotOnPurchaseOrder       = errors.New("inventory item is not on the purchase order")
+	ErrOverReceipt                  = errors.New("more packs received than are outstanding")
+	ErrInvalidPurchaseOrderStatus   = errors.New("invalid purchase order status; should be 'draft', 'sent', 'partially_received' or 'received'")
+
+	// Shift errors
+	ErrShiftAlreadyOpen   = errors.New("a shift is already open")
+	ErrShiftClosed        = errors.New("shift is already closed")
+	ErrShiftNotClosed     = errors.New("shift is still open; its Z-report is made when it closes")
+	ErrShiftHasOpenOrders = errors.New("orders are still open or in progress; close them or force the shift close")
+
+	// Customer errors
+	ErrDuplicateCustomer = errors.New("a customer with this email or phone already exists")
+
+	// Loyalty errors
+	ErrForeignKeyConstraintReward = errors.New("loyalty reward does not exist")
+	ErrRewardNotApplicable        = errors.New("loyalty reward does not apply to this menu item")
+	ErrRewardNeedsCustomer        = errors.New("redeeming a loyalty reward requires a customer_id")
+	ErrInsufficientLoyalty        = errors.New("customer's loyalty balance does not cover the reward")
+
+	// Promotion errors
+	ErrDuplicatePromoCode     = errors.New("a promotion with this code already exists")
+	ErrInvalidPromoCode       = errors.New("promo code does not exist")
+	ErrPromoCodeNotActive     = errors.New("promo code is not valid at this time")
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to this order")
+	ErrPromoCodeLimitReached  = errors.New("promo code has reached its usage limit")
+
+	// Payment errors
+	ErrOverpayment        = errors.New("payment is more than the amount due")
+	ErrInsufficientTender = errors.New("cash tendered does not cover the amount and tip")
+	ErrPaymentDeclined    = errors.New("card payment was declined")
+	ErrOrderAlreadyPaid   = errors.New("order is already fully paid")
+	ErrOrderNotPayable    = errors.New("only open and in progress orders take or void payments")
+	ErrPaymentVoided      = errors.New("payment is already voided")
+	ErrOrderHasPayments   = errors.New("order has payments; void them first")
+	ErrPaymentsOnRecord   = errors.New("order has voided payments on record; cancel it instead")
+	ErrOrderNotPaid       = errors.New("order is not fully paid; pay the rest or close it with a manager override")
 
 	// Order errors
 	ErrDuplicateOrder                = errors.New("models: duplicate order")
+	ErrForeignKeyConstraintCustomer  = errors.New("customer does not exist")
 	ErrForeignKeyConstraintOrderMenu = errors.New("menu item does not exist")
+	ErrInvalidModifier               = errors.New("modifier is not offered for this menu item")
+	ErrModifierLimitExceeded         = errors.New("too many modifiers chosen from one modifier group")
+	ErrMissingRequiredModifier       = errors.New("a required modifier group has no modifier chosen")
 	ErrInvalidFilterOption           = errors.New("wrong filter option chosen (should be menu/order/all)")
+	ErrInvalidOrderStatus            = errors.New("invalid order status; should be 'open', 'in progress', 'closed' or 'cancelled'")
+	ErrInvalidDate                   = errors.New("invalid date; should be YYYY-MM-DD or DD.MM.YYYY")
+	ErrMissingCancelReason           = errors.New("cancellation reason is required")
+	ErrInvalidSortOption             = errors.New("wrong sort option chosen (should be created_at/id/customer_name/status, optionally prefixed with '-')")
 
 	// Report errors
 	ErrInvalidPrice              = errors.New("invalid min/max prices given")
-	ErrInvalidPeriod             = errors.New("invalid period type; should be 'day' or 'month'")
-	ErrInvalidOrderedItemsFormat = errors.New("invalid format for ordered items by period: should be day/month or month/year")
+	ErrInvalidPeriod             = errors.New("invalid period type; should be 'hour', 'day', 'week', 'month' or 'year'")
+	ErrInvalidOrderedItemsFormat = errors.New("invalid format for ordered items by period: give from and to, or month and/or year, but not both")
+	ErrTooManyPeriods            = errors.New("date range has too many periods; use a longer period or a shorter range")
+	ErrInvalidCountOption        = errors.New("invalid count; should be 'items' or 'orders'")
+	ErrInvalidExportFormat       = errors.New("invalid format; should be 'json', 'csv' or 'xlsx'")
+	ErrInvalidGroupBy            = errors.New("invalid groupBy; should be 'day', 'week', 'month', 'hour' or 'category'")
+	ErrInvalidLimit              = errors.New("invalid limit; should be a positive integer")
+	ErrInvalidCompareTo          = errors.New("invalid compareTo; should be 'previousPeriod'")
+	ErrComparisonNeedsRange      = errors.New("compareTo=previousPeriod needs both from and to")
+	ErrInvalidDateRange          = errors.New("invalid date range; from is after to")
 )
+
+// OrderStatusTransitionError is returned when an order is asked to move
+// between two statuses that the order lifecycle does not allow
+type OrderStatusTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *OrderStatusTransitionError) Error() string {
+	return fmt.Sprintf("models: order cannot move from '%s' to '%s'", e.From, e.To)
+}
@@ -1,22 +1,73 @@
 package models
 
+import (
+	"strconv"
+	"time"
+)
+
+// reasons an inventory quantity changes, mirroring the valid_reason check in init.sql
+const (
+	InventoryReasonOrder           = "order"
+	InventoryReasonOrderUpdate     = "order_update"
+	InventoryReasonOrderCancel     = "order_cancel"
+	InventoryReasonDelivery        = "delivery"
+	InventoryReasonSpoilage        = "spoilage"
+	InventoryReasonSpill           = "spill"
+	InventoryReasonCountCorrection = "count_correction"
+)
+
+// IsInventoryReason reports whether reason is one of the known inventory transaction reasons
+func IsInventoryReason(reason string) bool {
+	switch reason {
+	case InventoryReasonOrder, InventoryReasonOrderUpdate, InventoryReasonOrderCancel:
+		return true
+	}
+	return IsAdjustmentReason(reason)
+}
+
+// IsAdjustmentReason reports whether reason may be given for a manual stock adjustment;
+// the order reasons are set by the order endpoints only
+func IsAdjustmentReason(reason string) bool {
+	switch reason {
+	case InventoryReasonDelivery, InventoryReasonSpoilage, InventoryReasonSpill, InventoryReasonCountCorrection:
+		return true
+	}
+	return false
+}
+
 type Inventory struct {
-	ID         int      `json:"id"`
-	Name       string   `json:"name"`
-	Quantity   int      `json:"quantity"`
-	Unit       string   `json:"unit"`
-	Categories []string `json:"categories"`
+	ID              int      `json:"id"`
+	Name            string   `json:"name"`
+	Quantity        int      `json:"quantity"`
+	Unit            string   `json:"unit"`
+	Categories      []string `json:"categories"`
+	MinQuantity     int      `json:"min_quantity"`     // stock below this is low; 0 disables the alert
+	ReorderQuantity int      `json:"reorder_quantity"` // suggested amount to order when stock is low
+	PackSize        int      `json:"pack_size"`        // units in a pack, for quantities given in packs; 0 if not sold in packs
+	UnitCost        float64  `json:"unit_cost"`        // cost of one unit, averaged over deliveries
 }
 
 type inventoryValidator struct {
 	validator map[string]string
 	inventory Inventory
+	metadata  bool
 }
 
 func NewInventoryValidator(inventory Inventory) *inventoryValidator {
 	return &inventoryValidator{
 		make(map[string]string),
 		inventory,
+		false,
+	}
+}
+
+// NewInventoryMetadataValidator validates an update of an item's metadata,
+// which cannot set the quantity; stock changes go through adjustments
+func NewInventoryMetadataValidator(inventory Inventory) *inventoryValidator {
+	return &inventoryValidator{
+		make(map[string]string),
+		inventory,
+		true,
 	}
 }
 
@@ -27,9 +78,23 @@ func (v *inventoryValidator) Validate() map[string]string {
 	if v.inventory.Unit == "" {
 		v.validator["Unit"] = "missing Unit"
 	}
-	if v.inventory.Quantity < 0 {
+	if v.metadata && v.inventory.Quantity != 0 {
+		v.validator["Quantity"] = "Quantity cannot be updated here; use POST /inventory/{id}/adjustments"
+	} else if v.inventory.Quantity < 0 {
 		v.validator["Quantity"] = "Quantity must be 0 or more"
 	}
+	if v.inventory.MinQuantity < 0 {
+		v.validator["MinQuantity"] = "MinQuantity must be 0 or more"
+	}
+	if v.inventory.ReorderQuantity < 0 {
+		v.validator["ReorderQuantity"] = "ReorderQuantity must be 0 or more"
+	}
+	if v.inventory.PackSize < 0 {
+		v.validator["PackSize"] = "PackSize must be 0 or more"
+	}
+	if v.inventory.UnitCost < 0 {
+		v.validator["UnitCost"] = "UnitCost must be 0 or more"
+	}
 
 	if len(v.validator) > 0 {
 		return v.validator
@@ -37,6 +102,16 @@ func (v *inventoryValidator) Validate() map[string]string {
 	return nil
 }
 
+// LowStockItem is an inventory item whose stock is below its minimum quantity
+type LowStockItem struct {
+	InventoryID     int    `json:"inventory_id"`
+	Name            string `json:"name"`
+	Quantity        int    `json:"quantity"`
+	Unit            string `json:"unit"`
+	MinQuantity     int    `json:"min_quantity"`
+	ReorderQuantity int    `json:"reorder_quantity"`
+}
+
 type InventoryLeftOverItem struct {
 	Name     string `json:"name"`
 	Quantity int    `json:"quantity"`
@@ -49,3 +124,108 @@ type InventoryLeftOversResponse struct {
 	TotalPages  int                     `json:"totalPages"`
 	Data        []InventoryLeftOverItem `json:"data"`
 }
+
+type InventoryTransaction struct {
+	ID              int       `json:"id"`
+	InventoryID     int       `json:"inventory_id"`
+	Name            string    `json:"name"`
+	OldQuantity     int       `json:"old_quantity"`
+	NewQuantity     int       `json:"new_quantity"`
+	Change          int       `json:"change"`
+	Reason          string    `json:"reason"`
+	Reference       string    `json:"reference,omitempty"`
+	Note            string    `json:"note,omitempty"`
+	TransactionDate time.Time `json:"transaction_date"`
+}
+
+type InventoryTransactionFilter struct {
+	InventoryID int    // 0 means all inventory items
+	From        string // YYYY-MM-DD, inclusive
+	To          string // YYYY-MM-DD, inclusive
+	Reason      string
+}
+
+// InventoryAdjustment changes the stock of one item by a signed Delta, given in
+// Unit or, if Unit is empty, in the item's own unit
+type InventoryAdjustment struct {
+	InventoryID int     `json:"inventory_id"`
+	Delta       float64 `json:"delta"`
+	Unit        string  `json:"unit,omitempty"`
+	Reason      string  `json:"reason"`
+	Note        string  `json:"note"`
+}
+
+// BatchInventoryAdjustment applies several adjustments at once, e.g. a whole
+// delivery note; Reason and Note apply to every item that does not set its own
+type BatchInventoryAdjustment struct {
+	Reason string                `json:"reason"`
+	Note   string                `json:"note"`
+	Items  []InventoryAdjustment `json:"items"`
+}
+
+// AdjustedInventory is the stock of an item after an adjustment
+type AdjustedInventory struct {
+	InventoryID int    `json:"inventory_id"`
+	Name        string `json:"name"`
+	Delta       int    `json:"delta"`
+	Quantity    int    `json:"quantity"`
+}
+
+type inventoryAdjustmentValidator struct {
+	errors      map[string]string
+	adjustments []InventoryAdjustment
+	units       map[int]InventoryUnit
+}
+
+func NewInventoryAdjustmentValidator(adjustments ...InventoryAdjustment) *inventoryAdjustmentValidator {
+	return &inventoryAdjustmentValidator{
+		errors:      make(map[string]string),
+		adjustments: adjustments,
+	}
+}
+
+// WithUnits makes the validator check adjustment units against the stock units
+// of the inventory items; items missing from units are not checked
+func (v *inventoryAdjustmentValidator) WithUnits(units map[int]InventoryUnit) *inventoryAdjustmentValidator {
+	v.units = units
+	return v
+}
+
+func (v *inventoryAdjustmentValidator) Validate() map[string]string {
+	if len(v.adjustments) < 1 {
+		v.errors["Items"] = "At least one adjustment is required"
+	}
+
+	inventoryIDSet := make(map[int]bool)
+	for i, adjustment := range v.adjustments {
+		// a single adjustment reports its fields without the Items prefix
+		key := ""
+		if len(v.adjustments) > 1 {
+			key = "Items[" + strconv.Itoa(i) + "]."
+		}
+
+		if inventoryIDSet[adjustment.InventoryID] {
+			v.errors[key+"InventoryID"] = "Duplicate InventoryID detected"
+		}
+		inventoryIDSet[adjustment.InventoryID] = true
+
+		if adjustment.Delta == 0 {
+			v.errors[key+"Delta"] = "Delta must not be 0"
+		}
+		if adjustment.Unit != "" && !IsUnit(adjustment.Unit) {
+			v.errors[key+"Unit"] = "Unit must be one of shots, ml, l, g, kg, units or pack"
+		} else if unit, ok := v.units[adjustment.InventoryID]; ok {
+			if problem := unitProblem(adjustment.Delta, adjustment.Unit, unit); problem != "" {
+				v.errors[key+"Unit"] = problem
+			}
+		}
+		if !IsAdjustmentReason(adjustment.Reason) {
+			v.errors[key+"Reason"] = "Reason must be delivery, spoilage, spill or count_correction"
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
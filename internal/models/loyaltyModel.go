@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+// loyalty units, mirroring the loyalty_unit enum in init.sql
+const (
+	LoyaltyUnitPoints = "points"
+	LoyaltyUnitStamps = "stamps"
+)
+
+// loyalty ledger events, mirroring the loyalty_event enum in init.sql
+const (
+	LoyaltyEventEarn   = "earn"
+	LoyaltyEventRedeem = "redeem"
+)
+
+// IsLoyaltyUnit reports whether unit is one of the known loyalty units
+func IsLoyaltyUnit(unit string) bool {
+	return unit == LoyaltyUnitPoints || unit == LoyaltyUnitStamps
+}
+
+// LoyaltyRule earns Earn units when an order of a customer closes: either per
+// PerAmount spent on the order, gross after discounts, or per item ordered
+// from CategoryID
+type LoyaltyRule struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Unit       string   `json:"unit"`
+	Earn       int      `json:"earn"`
+	PerAmount  *float64 `json:"per_amount,omitempty"`
+	CategoryID *int     `json:"category_id,omitempty"`
+}
+
+type loyaltyRuleValidator struct {
+	errors map[string]string
+	rule   LoyaltyRule
+}
+
+func NewLoyaltyRuleValidator(rule LoyaltyRule) *loyaltyRuleValidator {
+	return &loyaltyRuleValidator{
+		errors: make(map[string]string),
+		rule:   rule,
+	}
+}
+
+func (v *loyaltyRuleValidator) Validate() map[string]string {
+	if v.rule.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if !IsLoyaltyUnit(v.rule.Unit) {
+		v.errors["Unit"] = "Unit should be 'points' or 'stamps'"
+	}
+	if v.rule.Earn <= 0 {
+		v.errors["Earn"] = "Earn should be a positive integer"
+	}
+	if (v.rule.PerAmount == nil) == (v.rule.CategoryID == nil) {
+		v.errors["PerAmount"] = "Exactly one of per_amount and category_id is required"
+	} else if v.rule.PerAmount != nil && *v.rule.PerAmount <= 0 {
+		v.errors["PerAmount"] = "PerAmount should be positive"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// LoyaltyReward takes DiscountPercent off one unit of an order line, from
+// CategoryID or from any menu item when it is nil, for Cost units
+type LoyaltyReward struct {
+	ID              int     `json:"id"`
+	Name            string  `json:"name"`
+	Unit            string  `json:"unit"`
+	Cost            int     `json:"cost"`
+	CategoryID      *int    `json:"category_id,omitempty"`
+	DiscountPercent float64 `json:"discount_percent"` // 100 when not given
+}
+
+type loyaltyRewardValidator struct {
+	errors map[string]string
+	reward LoyaltyReward
+}
+
+func NewLoyaltyRewardValidator(reward LoyaltyReward) *loyaltyRewardValidator {
+	return &loyaltyRewardValidator{
+		errors: make(map[string]string),
+		reward: reward,
+	}
+}
+
+func (v *loyaltyRewardValidator) Validate() map[string]string {
+	if v.reward.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if !IsLoyaltyUnit(v.reward.Unit) {
+		v.errors["Unit"] = "Unit should be 'points' or 'stamps'"
+	}
+	if v.reward.Cost <= 0 {
+		v.errors["Cost"] = "Cost should be a positive integer"
+	}
+	if v.reward.DiscountPercent <= 0 || v.reward.DiscountPercent > 100 {
+		v.errors["DiscountPercent"] = "DiscountPercent should be above 0 and at most 100"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// LoyaltyEntry is one change to a customer's balance. Reversals of earlier
+// entries carry the same event with the opposite sign.
+type LoyaltyEntry struct {
+	ID        int       `json:"id"`
+	OrderID   *int      `json:"order_id,omitempty"`
+	Event     string    `json:"event"`
+	Unit      string    `json:"unit"`
+	Amount    int       `json:"amount"`
+	RuleID    *int      `json:"rule_id,omitempty"`
+	RewardID  *int      `json:"reward_id,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CustomerLoyalty struct {
+	CustomerID int             `json:"customer_id"`
+	Points     int             `json:"points"`
+	Stamps     int             `json:"stamps"`
+	Redeemable []LoyaltyReward `json:"redeemable"` // rewards the balance covers
+	Ledger     []LoyaltyEntry  `json:"ledger"`     // newest first
+}
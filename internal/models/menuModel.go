@@ -1,23 +1,50 @@
 package models
 
-import "strconv"
+import (
+	"strconv"
+	"time"
+)
 
 type MenuItem struct {
-	ID          int                 `json:"id"`
-	Name        string              `json:"name"`
-	Description string              `json:"description"`
-	Price       float64             `json:"price"`
-	Inventory   []MenuItemInventory `json:"inventory"`
+	ID                 int                 `json:"id"`
+	Name               string              `json:"name"`
+	Description        string              `json:"description"`
+	Price              float64             `json:"price"`
+	CategoryID         int                 `json:"category_id,omitempty"`
+	Category           string              `json:"category,omitempty"` // category name, set when reading
+	TaxClassID         int                 `json:"tax_class_id,omitempty"`
+	TakeawayTaxClassID int                 `json:"takeaway_tax_class_id,omitempty"` // for takeaway orders; 0 means TaxClassID
+	TaxClass           string              `json:"tax_class,omitempty"`             // tax class name, set when reading
+	Inventory          []MenuItemInventory `json:"inventory"`
+	// computed from current stock when reading; MaxServings is nil when the
+	// item has no recipe and is therefore never limited by the inventory
+	Available   bool `json:"available"`
+	MaxServings *int `json:"max_servings"`
 }
 
+// MenuAvailability describes a menu item that is about to sell out and the
+// ingredient that runs short first
+type MenuAvailability struct {
+	MenuID      int    `json:"menu_id"`
+	Name        string `json:"name"`
+	Available   bool   `json:"available"`
+	MaxServings int    `json:"max_servings"`
+	LimitedBy   string `json:"limited_by"`
+}
+
+// MenuItemInventory is one ingredient of a recipe. Quantity may be given in any
+// unit compatible with the inventory item and is stored as a whole number of
+// the item's own unit, which is what is returned when reading.
 type MenuItemInventory struct {
-	InventoryID int `json:"inventory_id"`
-	Quantity    int `json:"quantity"`
+	InventoryID int     `json:"inventory_id"`
+	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit,omitempty"`
 }
 
 type menuItemValidator struct {
 	errors map[string]string
 	menu   MenuItem
+	units  map[int]InventoryUnit
 }
 
 func NewMenuItemValidator(menu MenuItem) *menuItemValidator {
@@ -27,6 +54,13 @@ func NewMenuItemValidator(menu MenuItem) *menuItemValidator {
 	}
 }
 
+// WithUnits makes the validator check recipe units against the stock units of
+// the inventory items; items missing from units are not checked
+func (v *menuItemValidator) WithUnits(units map[int]InventoryUnit) *menuItemValidator {
+	v.units = units
+	return v
+}
+
 func (v *menuItemValidator) Validate() map[string]string {
 	if v.menu.Name == "" {
 		v.errors["Name"] = "Name is required"
@@ -54,6 +88,133 @@ func (v *menuItemValidator) Validate() map[string]string {
 		if inv.Quantity < 0 {
 			v.errors[key+".Quantity"] = "Quantity must be 0 or more"
 		}
+
+		if inv.Unit != "" && !IsUnit(inv.Unit) {
+			v.errors[key+".Unit"] = "Unit must be one of shots, ml, l, g, kg, units or pack"
+		} else if unit, ok := v.units[inv.InventoryID]; ok {
+			if problem := unitProblem(inv.Quantity, inv.Unit, unit); problem != "" {
+				v.errors[key+".Unit"] = problem
+			}
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+type MenuCategory struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	DisplayOrder int    `json:"display_order"`
+}
+
+// MenuCategoryGroup is one section of the grouped menu; items without a
+// category are collected in a group with ID 0
+type MenuCategoryGroup struct {
+	MenuCategory
+	Items []MenuItem `json:"items"`
+}
+
+type menuCategoryValidator struct {
+	errors   map[string]string
+	category MenuCategory
+}
+
+func NewMenuCategoryValidator(category MenuCategory) *menuCategoryValidator {
+	return &menuCategoryValidator{
+		errors:   make(map[string]string),
+		category: category,
+	}
+}
+
+func (v *menuCategoryValidator) Validate() map[string]string {
+	if v.category.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if v.category.DisplayOrder < 0 {
+		v.errors["DisplayOrder"] = "DisplayOrder must be 0 or more"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// TaxClass is a tax rate menu items are taxed at. Menu prices include the
+// tax, unless AddedToPrice is set as for sales taxes charged on top.
+type TaxClass struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Rate         float64 `json:"rate"` // percent
+	AddedToPrice bool    `json:"added_to_price"`
+}
+
+type taxClassValidator struct {
+	errors   map[string]string
+	taxClass TaxClass
+}
+
+func NewTaxClassValidator(taxClass TaxClass) *taxClassValidator {
+	return &taxClassValidator{
+		errors:   make(map[string]string),
+		taxClass: taxClass,
+	}
+}
+
+func (v *taxClassValidator) Validate() map[string]string {
+	if v.taxClass.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if v.taxClass.Rate < 0 || v.taxClass.Rate > 100 {
+		v.errors["Rate"] = "Rate must be a percentage from 0 to 100"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+type PriceHistory struct {
+	ID         int       `json:"id"`
+	MenuItemID int       `json:"menu_item_id"`
+	OldPrice   float64   `json:"old_price"`
+	NewPrice   float64   `json:"new_price"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ScheduledPriceChange struct {
+	ID          int        `json:"id"`
+	MenuItemID  int        `json:"menu_item_id"`
+	NewPrice    float64    `json:"new_price"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"` // nil while the change is pending
+}
+
+type scheduledPriceChangeValidator struct {
+	errors map[string]string
+	change ScheduledPriceChange
+}
+
+func NewScheduledPriceChangeValidator(change ScheduledPriceChange) *scheduledPriceChangeValidator {
+	return &scheduledPriceChangeValidator{
+		errors: make(map[string]string),
+		change: change,
+	}
+}
+
+func (v *scheduledPriceChangeValidator) Validate() map[string]string {
+	if v.change.NewPrice < 0 {
+		v.errors["NewPrice"] = "New price must be 0 or more"
+	}
+	if v.change.EffectiveAt.IsZero() {
+		v.errors["EffectiveAt"] = "Effective date is required"
+	} else if !v.change.EffectiveAt.After(time.Now()) {
+		v.errors["EffectiveAt"] = "Effective date must be in the future"
 	}
 
 	if len(v.errors) > 0 {
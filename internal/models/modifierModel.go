@@ -0,0 +1,107 @@
+package models
+
+import "strconv"
+
+type ModifierGroup struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Required  bool       `json:"required"`
+	MaxSelect int        `json:"max_select"`
+	Modifiers []Modifier `json:"modifiers"`
+}
+
+type Modifier struct {
+	ID         int                 `json:"id"`
+	Name       string              `json:"name"`
+	PriceDelta float64             `json:"price_delta"`
+	Inventory  []ModifierInventory `json:"inventory"`
+}
+
+// ModifierInventory is the change to the recipe per unit ordered; a negative
+// quantity takes part of an ingredient away (e.g. milk replaced by oat milk).
+// Like a recipe quantity it may be given in any compatible unit and is stored
+// as a whole number of the item's own unit.
+type ModifierInventory struct {
+	InventoryID int     `json:"inventory_id"`
+	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit,omitempty"`
+}
+
+type MenuItemModifierGroups struct {
+	GroupIDs []int `json:"modifier_group_ids"`
+}
+
+type modifierGroupValidator struct {
+	errors map[string]string
+	group  ModifierGroup
+	units  map[int]InventoryUnit
+}
+
+func NewModifierGroupValidator(group ModifierGroup) *modifierGroupValidator {
+	return &modifierGroupValidator{
+		errors: make(map[string]string),
+		group:  group,
+	}
+}
+
+// WithUnits makes the validator check modifier units against the stock units
+// of the inventory items; items missing from units are not checked
+func (v *modifierGroupValidator) WithUnits(units map[int]InventoryUnit) *modifierGroupValidator {
+	v.units = units
+	return v
+}
+
+func (v *modifierGroupValidator) Validate() map[string]string {
+	if v.group.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if v.group.MaxSelect < 1 {
+		v.errors["MaxSelect"] = "MaxSelect must be 1 or more"
+	}
+	if len(v.group.Modifiers) < 1 {
+		v.errors["Modifiers"] = "At least one modifier is required"
+	}
+
+	nameSet := make(map[string]bool)
+	idSet := make(map[int]bool)
+	for i, modifier := range v.group.Modifiers {
+		key := "Modifiers[" + strconv.Itoa(i) + "]"
+
+		if modifier.ID != 0 {
+			if idSet[modifier.ID] {
+				v.errors[key+".ID"] = "Duplicate modifier ID detected"
+			}
+			idSet[modifier.ID] = true
+		}
+
+		if modifier.Name == "" {
+			v.errors[key+".Name"] = "Name is required"
+		} else if nameSet[modifier.Name] {
+			v.errors[key+".Name"] = "Duplicate modifier name detected"
+		} else {
+			nameSet[modifier.Name] = true
+		}
+
+		inventoryIDSet := make(map[int]bool)
+		for _, inv := range modifier.Inventory {
+			invKey := key + ".Inventory[" + strconv.Itoa(inv.InventoryID) + "]"
+			if inventoryIDSet[inv.InventoryID] {
+				v.errors[invKey] = "Duplicate InventoryID detected"
+			}
+			inventoryIDSet[inv.InventoryID] = true
+
+			if inv.Unit != "" && !IsUnit(inv.Unit) {
+				v.errors[invKey+".Unit"] = "Unit must be one of shots, ml, l, g, kg, units or pack"
+			} else if unit, ok := v.units[inv.InventoryID]; ok {
+				if problem := unitProblem(inv.Quantity, inv.Unit, unit); problem != "" {
+					v.errors[invKey+".Unit"] = problem
+				}
+			}
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
@@ -1,24 +1,151 @@
 package models
 
 import (
+	"fmt"
+	"slices"
 	"strconv"
 	"time"
 )
 
 type Jsonb map[string]interface{}
 
+// order statuses, mirroring the status enum in init.sql
+const (
+	OrderStatusOpen       = "open"
+	OrderStatusInProgress = "in progress"
+	OrderStatusClosed     = "closed"
+	OrderStatusCancelled  = "cancelled"
+)
+
+// orderStatusTransitions lists the statuses an order may move to from a given status
+var orderStatusTransitions = map[string][]string{
+	OrderStatusOpen:       {OrderStatusInProgress, OrderStatusClosed, OrderStatusCancelled},
+	OrderStatusInProgress: {OrderStatusOpen, OrderStatusClosed, OrderStatusCancelled},
+	OrderStatusClosed:     {OrderStatusOpen},
+	OrderStatusCancelled:  {},
+}
+
+// IsOrderStatus reports whether status is one of the known order statuses
+func IsOrderStatus(status string) bool {
+	_, ok := orderStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionOrderStatus reports whether an order in status from may move to status to
+func CanTransitionOrderStatus(from, to string) bool {
+	for _, next := range orderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
 type Order struct {
 	ID                  int         `json:"id"`
+	CustomerID          *int        `json:"customer_id,omitempty"` // the name and preferences default to the customer's
 	CustomerName        string      `json:"customer_name"`
 	Status              string      `json:"status"`
 	CreatedAt           time.Time   `json:"created_at"`
 	CustomerPreferences Jsonb       `json:"customer_preferences"`
+	CancelReason        string      `json:"cancel_reason,omitempty"`
+	PromoCode           string      `json:"promo_code,omitempty"`
+	Discount            float64     `json:"discount,omitempty"` // order-wide promotions; set by the service
+	Takeaway            bool        `json:"takeaway"`           // lines are taxed at their takeaway tax class
+	NetTotal            float64     `json:"net_total"`          // sums of the lines after discounts; set by the repository
+	TaxTotal            float64     `json:"tax_total"`
+	GrossTotal          float64     `json:"gross_total"`
+	OverrideBy          string      `json:"override_by,omitempty"` // manager who closed the order before it was paid
 	Items               []OrderItem `json:"items"`
+	// promotions the service applied, for the repository to record
+	Promotions []AppliedPromotion `json:"-"`
+}
+
+// OrderClose closes an order. An order that is not fully paid closes only
+// with a manager override, which is recorded on the order.
+type OrderClose struct {
+	ManagerOverride bool   `json:"manager_override"`
+	Manager         string `json:"manager"`
+}
+
+type orderCloseValidator struct {
+	errors map[string]string
+	close  OrderClose
+}
+
+func NewOrderCloseValidator(close OrderClose) *orderCloseValidator {
+	return &orderCloseValidator{
+		errors: make(map[string]string),
+		close:  close,
+	}
+}
+
+func (v *orderCloseValidator) Validate() map[string]string {
+	if v.close.ManagerOverride && v.close.Manager == "" {
+		v.errors["Manager"] = "Manager is required for an override"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+type OrderFilter struct {
+	Status     string
+	Customer   string
+	CustomerID int    // 0 means all customers
+	From       string // YYYY-MM-DD, inclusive
+	To         string // YYYY-MM-DD, inclusive
+	Sort       string // created_at, id, customer_name or status; prefixed with '-' for descending
+	Page       int
+	PageSize   int
+}
+
+type OrdersResponse struct {
+	CurrentPage int     `json:"currentPage"`
+	HasNextPage bool    `json:"hasNextPage"`
+	PageSize    int     `json:"pageSize"`
+	TotalPages  int     `json:"totalPages"`
+	Data        []Order `json:"data"`
+}
+
+type OrderStatusHistory struct {
+	ID        int       `json:"id"`
+	OrderID   int       `json:"order_id"`
+	OldStatus string    `json:"old_status"`
+	NewStatus string    `json:"new_status"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// time the order spent in OldStatus before this change
+	SecondsInOldStatus float64 `json:"seconds_in_old_status"`
+}
+
+type OrderStatusHistoryFilter struct {
+	OrderID int    // 0 means all orders
+	From    string // YYYY-MM-DD, inclusive
+	To      string // YYYY-MM-DD, inclusive
+	Status  string // matches either the old or the new status
 }
 
 type OrderItem struct {
-	MenuID   int `json:"menu_id"`
-	Quantity int `json:"quantity"`
+	MenuID    int   `json:"menu_id"`
+	Quantity  int   `json:"quantity"`
+	Modifiers []int `json:"modifiers,omitempty"` // ids of the chosen modifiers
+	RewardID  *int  `json:"reward_id,omitempty"` // loyalty reward redeemed on one unit of the line
+	// price (including modifiers) and name of the menu item when the line was ordered; set by the repository
+	UnitPrice float64 `json:"unit_price,omitempty"`
+	ItemName  string  `json:"item_name,omitempty"`
+	// the line is left in place by an edit and keeps UnitPrice from when it
+	// was ordered; set by the service
+	KeepPrice bool `json:"-"`
+	// line promotions and the redeemed reward; set by the service and the repository
+	Discount      float64 `json:"discount,omitempty"`
+	OrderDiscount float64 `json:"order_discount,omitempty"` // the line's share of the order's discount; set by the service
+	// tax class and rate of the menu item when the line was ordered, and the
+	// tax on the line after discounts; set by the repository
+	TaxClass string  `json:"tax_class,omitempty"`
+	TaxRate  float64 `json:"tax_rate,omitempty"`
+	Tax      float64 `json:"tax,omitempty"`
 }
 
 type orderValidator struct {
@@ -41,14 +168,22 @@ func (v *orderValidator) Validate() map[string]string {
 		v.errors["Items"] = "At least one order item is required"
 	}
 
-	menuIDSet := make(map[int]bool)
+	// the same menu item may appear on several lines as long as the chosen modifiers differ
+	lineSet := make(map[string]bool)
 	for _, item := range v.order.Items {
 		key := "Items[" + strconv.Itoa(item.MenuID) + "]"
 
-		if menuIDSet[item.MenuID] {
+		modifierIDs := slices.Clone(item.Modifiers)
+		slices.Sort(modifierIDs)
+		lineKey := fmt.Sprint(item.MenuID, modifierIDs)
+		if lineSet[lineKey] {
 			v.errors[key+".MenuID"] = "Duplicate menu item ID detected"
 		} else {
-			menuIDSet[item.MenuID] = true
+			lineSet[lineKey] = true
+		}
+
+		if len(slices.Compact(modifierIDs)) != len(item.Modifiers) {
+			v.errors[key+".Modifiers"] = "Duplicate modifier ID detected"
 		}
 
 		if item.Quantity < 1 {
@@ -93,3 +228,44 @@ type BatchInventoryUpdate struct {
 	QuantityUsed int    `json:"quantity_used"`
 	Remaining    int    `json:"remaining"`
 }
+
+type OrderCancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+type OrderCancellation struct {
+	OrderID   int                `json:"order_id"`
+	Reason    string             `json:"reason"`
+	Restocked []InventoryRestock `json:"restocked"`
+}
+
+type InventoryRestock struct {
+	InventoryID int    `json:"inventory_id"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity_returned"`
+	Remaining   int    `json:"remaining"`
+}
+
+type BatchCancelRequest struct {
+	OrderIDs []int  `json:"order_ids"`
+	Reason   string `json:"reason"`
+}
+
+type BatchCancelResponse struct {
+	ProcessedOrders []BatchCancelledOrder `json:"processed_orders"`
+	Summary         BatchCancelSummary    `json:"summary"`
+}
+
+type BatchCancelSummary struct {
+	TotalOrders      int                `json:"total_orders"`
+	Cancelled        int                `json:"cancelled"`
+	Rejected         int                `json:"rejected"`
+	InventoryUpdates []InventoryRestock `json:"inventory_updates"`
+}
+
+type BatchCancelledOrder struct {
+	ID        int                `json:"order_id"`
+	Status    string             `json:"status"`
+	Reason    string             `json:"reason,omitempty"`
+	Restocked []InventoryRestock `json:"restocked,omitempty"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// payment tenders, mirroring the payment_tender enum in init.sql
+const (
+	PaymentTenderCash    = "cash"
+	PaymentTenderCard    = "card"
+	PaymentTenderVoucher = "voucher"
+)
+
+var paymentTenders = []string{PaymentTenderCash, PaymentTenderCard, PaymentTenderVoucher}
+
+// Payment is one tender paid toward an order; several make a split bill
+type Payment struct {
+	ID      int     `json:"id"`
+	OrderID int     `json:"order_id"`
+	Tender  string  `json:"tender"`
+	Amount  float64 `json:"amount"` // toward the order's gross total; 0 pays what is due
+	Tip     float64 `json:"tip,omitempty"`
+	// cash handed over, amount and tip included; defaults to exactly that
+	Tendered  float64 `json:"tendered,omitempty"`
+	ChangeDue float64 `json:"change_due"`     // set by the service
+	Card      string  `json:"card,omitempty"` // card token for the payment provider; not stored
+	// voucher code, or the provider's authorization for a card
+	Reference string     `json:"reference,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	VoidedAt  *time.Time `json:"voided_at,omitempty"`
+}
+
+// OrderPayments is the bill of an order. Voided payments are listed but do
+// not count.
+type OrderPayments struct {
+	OrderID    int       `json:"order_id"`
+	Status     string    `json:"status"`
+	GrossTotal float64   `json:"gross_total"`
+	Paid       float64   `json:"paid"`
+	Tips       float64   `json:"tips"`
+	Due        float64   `json:"due"`
+	Payments   []Payment `json:"payments"`
+}
+
+type paymentValidator struct {
+	errors  map[string]string
+	payment Payment
+}
+
+func NewPaymentValidator(payment Payment) *paymentValidator {
+	return &paymentValidator{
+		errors:  make(map[string]string),
+		payment: payment,
+	}
+}
+
+func (v *paymentValidator) Validate() map[string]string {
+	p := v.payment
+	if !slices.Contains(paymentTenders, p.Tender) {
+		v.errors["Tender"] = "Tender should be 'cash', 'card' or 'voucher'"
+	}
+	if p.Amount < 0 {
+		v.errors["Amount"] = "Amount must be 0 or more"
+	}
+	if p.Tip < 0 {
+		v.errors["Tip"] = "Tip must be 0 or more"
+	}
+	if p.Tendered < 0 || (p.Tendered > 0 && p.Tender != PaymentTenderCash) {
+		v.errors["Tendered"] = "Tendered must be 0 or more and is only given for cash"
+	}
+	if p.Tender == PaymentTenderVoucher && p.Reference == "" {
+		v.errors["Reference"] = "Reference is required for a voucher"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
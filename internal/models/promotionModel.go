@@ -0,0 +1,160 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// promotion kinds, mirroring the promotion_kind enum in init.sql
+const (
+	PromotionKindPercent  = "percent"
+	PromotionKindFixed    = "fixed"
+	PromotionKindBuyXGetY = "buy_x_get_y"
+)
+
+// PromotionTimeLayout is the format of the daily window of a promotion
+const PromotionTimeLayout = "15:04"
+
+var promotionKinds = []string{PromotionKindPercent, PromotionKindFixed, PromotionKindBuyXGetY}
+
+// Promotion is a discount rule. Limited by MenuID or CategoryID it discounts
+// the matching order lines, otherwise the whole order; buy-X-get-Y always
+// works on lines. With a Code it applies only to orders giving that code.
+type Promotion struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// percent off for percent and buy_x_get_y (off the free units), or the
+	// amount off for fixed: per matching unit, or once off the whole order
+	Value      float64    `json:"value"`
+	MenuID     *int       `json:"menu_id,omitempty"`
+	CategoryID *int       `json:"category_id,omitempty"`
+	Code       string     `json:"code,omitempty"`
+	UsageLimit *int       `json:"usage_limit,omitempty"` // orders that may use the code
+	TimesUsed  int        `json:"times_used"`            // orders not cancelled that used it; set by the repository
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	DailyFrom  string     `json:"daily_from,omitempty"` // HH:MM local time; the window may pass midnight
+	DailyTo    string     `json:"daily_to,omitempty"`
+	// buy BuyQuantity matching units and get GetQuantity more discounted by Value percent
+	BuyQuantity int `json:"buy_quantity,omitempty"`
+	GetQuantity int `json:"get_quantity,omitempty"`
+}
+
+// OnLines reports whether the promotion discounts order lines rather than the whole order
+func (p Promotion) OnLines() bool {
+	return p.MenuID != nil || p.CategoryID != nil || p.Kind == PromotionKindBuyXGetY
+}
+
+// Matches reports whether the promotion covers a line of the given menu item and category
+func (p Promotion) Matches(menuID, categoryID int) bool {
+	if p.MenuID != nil && *p.MenuID != menuID {
+		return false
+	}
+	if p.CategoryID != nil && *p.CategoryID != categoryID {
+		return false
+	}
+	return true
+}
+
+// ActiveAt reports whether the promotion applies to an order placed at t. The
+// daily window is read on t's clock, so t must be in the shop's location.
+func (p Promotion) ActiveAt(t time.Time) bool {
+	if p.StartsAt != nil && t.Before(*p.StartsAt) {
+		return false
+	}
+	if p.ExpiresAt != nil && !t.Before(*p.ExpiresAt) {
+		return false
+	}
+	if p.DailyFrom == "" {
+		return true
+	}
+
+	from, errFrom := time.Parse(PromotionTimeLayout, p.DailyFrom)
+	to, errTo := time.Parse(PromotionTimeLayout, p.DailyTo)
+	if errFrom != nil || errTo != nil {
+		return false
+	}
+	clock := t.Hour()*60 + t.Minute()
+	start, end := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+	if start <= end {
+		return clock >= start && clock < end
+	}
+	return clock >= start || clock < end
+}
+
+type promotionValidator struct {
+	errors    map[string]string
+	promotion Promotion
+}
+
+func NewPromotionValidator(promotion Promotion) *promotionValidator {
+	return &promotionValidator{
+		errors:    make(map[string]string),
+		promotion: promotion,
+	}
+}
+
+func (v *promotionValidator) Validate() map[string]string {
+	p := v.promotion
+	if p.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if !slices.Contains(promotionKinds, p.Kind) {
+		v.errors["Kind"] = "Kind should be 'percent', 'fixed' or 'buy_x_get_y'"
+	}
+	if p.Value <= 0 || (p.Kind != PromotionKindFixed && p.Value > 100) {
+		v.errors["Value"] = "Value should be a percentage above 0 and at most 100, or a positive amount for fixed"
+	}
+	if p.MenuID != nil && p.CategoryID != nil {
+		v.errors["MenuID"] = "Give menu_id or category_id, not both"
+	}
+	if p.Kind == PromotionKindBuyXGetY && (p.BuyQuantity < 1 || p.GetQuantity < 1) {
+		v.errors["BuyQuantity"] = "BuyQuantity and GetQuantity should be 1 or more"
+	}
+	if p.UsageLimit != nil && (p.Code == "" || *p.UsageLimit < 1) {
+		v.errors["UsageLimit"] = "UsageLimit should be 1 or more and needs a code"
+	}
+	if p.StartsAt != nil && p.ExpiresAt != nil && !p.ExpiresAt.After(*p.StartsAt) {
+		v.errors["ExpiresAt"] = "ExpiresAt should be after StartsAt"
+	}
+	if (p.DailyFrom == "") != (p.DailyTo == "") {
+		v.errors["DailyFrom"] = "Give both daily_from and daily_to, or neither"
+	} else if p.DailyFrom != "" {
+		_, errFrom := time.Parse(PromotionTimeLayout, p.DailyFrom)
+		_, errTo := time.Parse(PromotionTimeLayout, p.DailyTo)
+		if errFrom != nil || errTo != nil {
+			v.errors["DailyFrom"] = "DailyFrom and DailyTo should be HH:MM"
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// PricedLine is an order line as the pricing engine sees it
+type PricedLine struct {
+	MenuID     int
+	CategoryID int // 0 when the menu item has no category
+	Quantity   int
+	UnitPrice  float64 // with modifiers
+}
+
+// AppliedPromotion is a promotion that took Amount off an order
+type AppliedPromotion struct {
+	PromotionID int     `json:"promotion_id"`
+	Name        string  `json:"name"`
+	Code        string  `json:"code,omitempty"`
+	Amount      float64 `json:"amount"`
+}
+
+// OrderPricing is the outcome of pricing an order. The slices follow the
+// order of the lines priced.
+type OrderPricing struct {
+	LineDiscounts  []float64 // line promotions
+	OrderDiscounts []float64 // each line's share of OrderDiscount
+	OrderDiscount  float64   // order-wide promotions
+	Applied        []AppliedPromotion
+}
@@ -1,16 +1,131 @@
 package models
 
+// report groupings, see ReportQuery.GroupBy
+const (
+	ReportGroupByHour     = "hour"
+	ReportGroupByDay      = "day"
+	ReportGroupByWeek     = "week"
+	ReportGroupByMonth    = "month"
+	ReportGroupByCategory = "category"
+)
+
+// ReportCompareToPreviousPeriod compares a report with the equally long period
+// just before it
+const ReportCompareToPreviousPeriod = "previousPeriod"
+
+// IsReportGroupBy reports whether groupBy is one of the known report groupings
+func IsReportGroupBy(groupBy string) bool {
+	switch groupBy {
+	case ReportGroupByHour, ReportGroupByDay, ReportGroupByWeek, ReportGroupByMonth, ReportGroupByCategory:
+		return true
+	}
+	return false
+}
+
+// ReportQuery holds the report query parameters as given in the request
+type ReportQuery struct {
+	From      string
+	To        string
+	Limit     string
+	GroupBy   string
+	CompareTo string
+	Category  string
+}
+
+// ReportFilter selects the closed orders a report covers. From and To are
+// YYYY-MM-DD and inclusive; empty bounds are open. Limit 0 means no limit.
+type ReportFilter struct {
+	From     string
+	To       string
+	Limit    int
+	GroupBy  string
+	Category string
+}
+
 type ReportTotalSales struct {
-	OrdersCompleted int     `json:"orders_completed"` // Number of completed orders
+	From            string             `json:"from,omitempty"`
+	To              string             `json:"to,omitempty"`
+	OrdersCompleted int                `json:"orders_completed"` // Number of completed orders
+	TotalSales      float64            `json:"total_sales"`      // gross, after discounts
+	NetSales        float64            `json:"net_sales"`        // TotalSales less Tax
+	Tax             float64            `json:"tax"`
+	Discounts       float64            `json:"discounts"`    // promotions and rewards taken off
+	COGS            float64            `json:"cogs"`         // ingredient cost of the items sold
+	GrossProfit     float64            `json:"gross_profit"` // NetSales - COGS
+	GroupBy         string             `json:"group_by,omitempty"`
+	Groups          []ReportSalesGroup `json:"groups,omitempty"`
+	Previous        *ReportTotalSales  `json:"previous,omitempty"` // set with compareTo
+	Change          *ReportSalesChange `json:"change,omitempty"`   // current minus previous
+}
+
+// ReportSalesGroup is the sales of one hour, day, week, month or category
+type ReportSalesGroup struct {
+	Group           string  `json:"group"`
+	OrdersCompleted int     `json:"orders_completed"`
 	TotalSales      float64 `json:"total_sales"`
+	NetSales        float64 `json:"net_sales"`
+	Tax             float64 `json:"tax"`
+	Discounts       float64 `json:"discounts"`
+	COGS            float64 `json:"cogs"`
+	GrossProfit     float64 `json:"gross_profit"`
+}
+
+// ReportSalesChange is the difference between a period's sales and the previous
+// period's. Percentages are nil when the previous figure was 0.
+type ReportSalesChange struct {
+	OrdersCompleted   int      `json:"orders_completed"`
+	TotalSales        float64  `json:"total_sales"`
+	TotalSalesPercent *float64 `json:"total_sales_percent"`
+	GrossProfit       float64  `json:"gross_profit"`
+}
+
+// ReportTax sums the tax of the closed orders in a date range per tax class,
+// for tax filings
+type ReportTax struct {
+	From    string           `json:"from,omitempty"`
+	To      string           `json:"to,omitempty"`
+	Classes []ReportTaxClass `json:"classes"`
+	Net     float64          `json:"net"`
+	Tax     float64          `json:"tax"`
+	Gross   float64          `json:"gross"`
+}
+
+// ReportTaxClass is the sales taxed at one tax class and rate. Lines of menu
+// items without a tax class are reported as "untaxed".
+type ReportTaxClass struct {
+	TaxClass string  `json:"tax_class"`
+	Rate     float64 `json:"rate"`
+	Lines    int     `json:"lines"` // order lines taxed
+	Net      float64 `json:"net"`
+	Tax      float64 `json:"tax"`
+	Gross    float64 `json:"gross"`
+}
+
+// ReportMenuMargin is a menu item's price against the current cost of its recipe
+type ReportMenuMargin struct {
+	MenuID        int     `json:"menu_id"`
+	Name          string  `json:"name"`
+	Category      string  `json:"category,omitempty"`
+	Price         float64 `json:"price"`
+	Cost          float64 `json:"cost"`
+	Margin        float64 `json:"margin"`
+	MarginPercent float64 `json:"margin_percent"`
 }
 
 type ReportPopularItem struct {
+	MenuID         int     `json:"-"`
 	Name           string  `json:"name"`
 	Description    string  `json:"description"`
 	Price          float64 `json:"price"`
+	Category       string  `json:"category,omitempty"`
+	Group          string  `json:"group,omitempty"` // set with groupBy; items are ranked within their group
 	Rank           int     `json:"rank"`
 	TotalItemsSold int     `json:"total_items_sold"`
+	TotalRevenue   float64 `json:"total_revenue"`
+	// set with compareTo; nil when the item did not sell in the previous period
+	PreviousRank      *int `json:"previous_rank,omitempty"`
+	PreviousItemsSold *int `json:"previous_items_sold,omitempty"`
+	ItemsSoldChange   *int `json:"items_sold_change,omitempty"`
 }
 
 type ReportMenuSearchItem struct {
@@ -35,9 +150,56 @@ type ReportSearch struct {
 	TotalMatches  int                     `json:"total_matches"`
 }
 
+// ordered items periods, see OrderedItemsFilter.Period
+const (
+	OrderedItemsPeriodHour  = "hour"
+	OrderedItemsPeriodDay   = "day"
+	OrderedItemsPeriodWeek  = "week"
+	OrderedItemsPeriodMonth = "month"
+	OrderedItemsPeriodYear  = "year"
+)
+
+// IsOrderedItemsPeriod reports whether period is one of the known ordered items periods
+func IsOrderedItemsPeriod(period string) bool {
+	switch period {
+	case OrderedItemsPeriodHour, OrderedItemsPeriodDay, OrderedItemsPeriodWeek,
+		OrderedItemsPeriodMonth, OrderedItemsPeriodYear:
+		return true
+	}
+	return false
+}
+
+// what the ordered items report counts
+const (
+	OrderedItemsCountItems  = "items"
+	OrderedItemsCountOrders = "orders"
+)
+
+// OrderedItemsQuery holds the ordered items query parameters as given in the request
+type OrderedItemsQuery struct {
+	Period string
+	From   string
+	To     string
+	Month  string
+	Year   string
+	Count  string
+}
+
+// OrderedItemsFilter selects the orders placed from From to To (YYYY-MM-DD,
+// inclusive), counted per Period
+type OrderedItemsFilter struct {
+	Period string
+	From   string
+	To     string
+	Count  string
+}
+
 type ReportOrderedItems struct {
 	Period       string           `json:"period"`
 	Month        string           `json:"month,omitempty"`
 	Year         string           `json:"year,omitempty"`
-	OrderedItems []map[string]int `json:"orderedItems"`
+	From         string           `json:"from"`
+	To           string           `json:"to"`
+	Count        string           `json:"count"`
+	OrderedItems []map[string]int `json:"orderedItems"` // one entry per period, in time order
 }
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+type Shift struct {
+	ID       int        `json:"id"`
+	OpenedAt time.Time  `json:"opened_at"`
+	OpenedBy string     `json:"opened_by"`
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+	ClosedBy string     `json:"closed_by,omitempty"`
+	Forced   bool       `json:"forced"` // closed while orders were still open or in progress
+}
+
+type shiftValidator struct {
+	errors map[string]string
+	shift  Shift
+}
+
+func NewShiftValidator(shift Shift) *shiftValidator {
+	return &shiftValidator{
+		errors: make(map[string]string),
+		shift:  shift,
+	}
+}
+
+func (v *shiftValidator) Validate() map[string]string {
+	if v.shift.OpenedBy == "" {
+		v.errors["OpenedBy"] = "OpenedBy is required"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// ShiftClose closes a shift. Force closes it even though orders are still
+// open or in progress; they stay open and count in the next shift.
+type ShiftClose struct {
+	ClosedBy string `json:"closed_by"`
+	Force    bool   `json:"force"`
+}
+
+type shiftCloseValidator struct {
+	errors map[string]string
+	close  ShiftClose
+}
+
+func NewShiftCloseValidator(close ShiftClose) *shiftCloseValidator {
+	return &shiftCloseValidator{
+		errors: make(map[string]string),
+		close:  close,
+	}
+}
+
+func (v *shiftCloseValidator) Validate() map[string]string {
+	if v.close.ClosedBy == "" {
+		v.errors["ClosedBy"] = "ClosedBy is required"
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// ZReport is the snapshot of a shift taken when it closes. Orders count in the
+// shift they were taken, closed or cancelled in.
+type ZReport struct {
+	Shift
+	OrdersTaken       int                `json:"orders_taken"`
+	OrdersClosed      int                `json:"orders_closed"`
+	OrdersCancelled   int                `json:"orders_cancelled"`
+	OrdersOpen        int                `json:"orders_open"` // still open or in progress at close
+	Revenue           float64            `json:"revenue"`     // gross, of the orders closed
+	NetRevenue        float64            `json:"net_revenue"` // Revenue without tax
+	Tax               float64            `json:"tax"`
+	COGS              float64            `json:"cogs"`
+	GrossProfit       float64            `json:"gross_profit"`    // NetRevenue - COGS
+	CancelledValue    float64            `json:"cancelled_value"` // gross`
+	ItemsSold         []ZReportItem      `json:"items_sold"`
+	InventoryConsumed []ZReportInventory `json:"inventory_consumed"`
+}
+
+type ZReportItem struct {
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Revenue  float64 `json:"revenue"`
+}
+
+// ZReportInventory is the stock an inventory item lost during a shift: Consumed
+// by orders, net of cancellations, and Wasted through spoilage and spills
+type ZReportInventory struct {
+	InventoryID int    `json:"inventory_id"`
+	Name        string `json:"name"`
+	Unit        string `json:"unit"`
+	Consumed    int    `json:"consumed"`
+	Wasted      int    `json:"wasted"`
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type Supplier struct {
+	ID           int            `json:"id"`
+	Name         string         `json:"name"`
+	ContactName  string         `json:"contact_name"`
+	Email        string         `json:"email"`
+	Phone        string         `json:"phone"`
+	LeadTimeDays int            `json:"lead_time_days"`
+	Items        []SupplierItem `json:"items"`
+}
+
+// SupplierItem is an inventory item a supplier sells, in packs of PackSize
+// inventory units for PackPrice each
+type SupplierItem struct {
+	InventoryID int     `json:"inventory_id"`
+	Name        string  `json:"name,omitempty"` // inventory name, set when reading
+	PackSize    int     `json:"pack_size"`
+	PackPrice   float64 `json:"pack_price"`
+}
+
+type supplierValidator struct {
+	errors   map[string]string
+	supplier Supplier
+}
+
+func NewSupplierValidator(supplier Supplier) *supplierValidator {
+	return &supplierValidator{
+		errors:   make(map[string]string),
+		supplier: supplier,
+	}
+}
+
+func (v *supplierValidator) Validate() map[string]string {
+	if v.supplier.Name == "" {
+		v.errors["Name"] = "Name is required"
+	}
+	if v.supplier.LeadTimeDays < 0 {
+		v.errors["LeadTimeDays"] = "LeadTimeDays must be 0 or more"
+	}
+
+	inventoryIDSet := make(map[int]bool)
+	for _, item := range v.supplier.Items {
+		key := "Items[" + strconv.Itoa(item.InventoryID) + "]"
+
+		if inventoryIDSet[item.InventoryID] {
+			v.errors[key+".InventoryID"] = "Duplicate InventoryID detected"
+		} else {
+			inventoryIDSet[item.InventoryID] = true
+		}
+
+		if item.PackSize < 1 {
+			v.errors[key+".PackSize"] = "PackSize must be 1 or more"
+		}
+		if item.PackPrice < 0 {
+			v.errors[key+".PackPrice"] = "PackPrice must be 0 or more"
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// purchase order statuses, mirroring the purchase_order_status enum in init.sql
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusSent              = "sent"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+)
+
+// IsPurchaseOrderStatus reports whether status is one of the known purchase order statuses
+func IsPurchaseOrderStatus(status string) bool {
+	switch status {
+	case PurchaseOrderStatusDraft, PurchaseOrderStatusSent,
+		PurchaseOrderStatusPartiallyReceived, PurchaseOrderStatusReceived:
+		return true
+	}
+	return false
+}
+
+type PurchaseOrder struct {
+	ID           int                 `json:"id"`
+	SupplierID   int                 `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name,omitempty"`
+	Status       string              `json:"status"`
+	Note         string              `json:"note"`
+	CreatedAt    time.Time           `json:"created_at"`
+	SentAt       *time.Time          `json:"sent_at,omitempty"`
+	ReceivedAt   *time.Time          `json:"received_at,omitempty"`
+	Total        float64             `json:"total"`
+	Items        []PurchaseOrderItem `json:"items"`
+}
+
+// PurchaseOrderItem is a line of a purchase order. Pack size and price are
+// copied from the supplier when the line is written.
+type PurchaseOrderItem struct {
+	InventoryID   int     `json:"inventory_id"`
+	Name          string  `json:"name,omitempty"`
+	PacksOrdered  int     `json:"packs_ordered"`
+	PacksReceived int     `json:"packs_received"`
+	PackSize      int     `json:"pack_size"`
+	PackPrice     float64 `json:"pack_price"`
+}
+
+type PurchaseOrderFilter struct {
+	Status     string
+	SupplierID int // 0 means all suppliers
+}
+
+// PurchaseOrderReceipt lists the packs delivered per inventory item; no items
+// means everything still outstanding was delivered
+type PurchaseOrderReceipt struct {
+	Items []PurchaseOrderReceiptItem `json:"items"`
+}
+
+type PurchaseOrderReceiptItem struct {
+	InventoryID int `json:"inventory_id"`
+	Packs       int `json:"packs"`
+}
+
+type purchaseOrderValidator struct {
+	errors map[string]string
+	order  PurchaseOrder
+}
+
+func NewPurchaseOrderValidator(order PurchaseOrder) *purchaseOrderValidator {
+	return &purchaseOrderValidator{
+		errors: make(map[string]string),
+		order:  order,
+	}
+}
+
+func (v *purchaseOrderValidator) Validate() map[string]string {
+	if v.order.SupplierID < 1 {
+		v.errors["SupplierID"] = "SupplierID is required"
+	}
+	if len(v.order.Items) < 1 {
+		v.errors["Items"] = "At least one item is required"
+	}
+
+	inventoryIDSet := make(map[int]bool)
+	for _, item := range v.order.Items {
+		key := "Items[" + strconv.Itoa(item.InventoryID) + "]"
+
+		if inventoryIDSet[item.InventoryID] {
+			v.errors[key+".InventoryID"] = "Duplicate InventoryID detected"
+		} else {
+			inventoryIDSet[item.InventoryID] = true
+		}
+
+		if item.PacksOrdered < 1 {
+			v.errors[key+".PacksOrdered"] = "PacksOrdered must be 1 or more"
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+type purchaseOrderReceiptValidator struct {
+	errors  map[string]string
+	receipt PurchaseOrderReceipt
+}
+
+func NewPurchaseOrderReceiptValidator(receipt PurchaseOrderReceipt) *purchaseOrderReceiptValidator {
+	return &purchaseOrderReceiptValidator{
+		errors:  make(map[string]string),
+		receipt: receipt,
+	}
+}
+
+func (v *purchaseOrderReceiptValidator) Validate() map[string]string {
+	inventoryIDSet := make(map[int]bool)
+	for _, item := range v.receipt.Items {
+		key := "Items[" + strconv.Itoa(item.InventoryID) + "]"
+
+		if inventoryIDSet[item.InventoryID] {
+			v.errors[key+".InventoryID"] = "Duplicate InventoryID detected"
+		} else {
+			inventoryIDSet[item.InventoryID] = true
+		}
+
+		if item.Packs < 1 {
+			v.errors[key+".Packs"] = "Packs must be 1 or more"
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+// PurchaseOrderStatusError is returned when a purchase order is not in a status
+// that allows the requested operation
+type PurchaseOrderStatusError struct {
+	Status    string
+	Operation string
+}
+
+func (e *PurchaseOrderStatusError) Error() string {
+	return fmt.Sprintf("models: cannot %s a purchase order that is '%s'", e.Operation, e.Status)
+}
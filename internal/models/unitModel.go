@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// units inventory is stocked in, mirroring the unit enum in init.sql
+const (
+	UnitShots = "shots"
+	UnitMl    = "ml"
+	UnitG     = "g"
+	UnitUnits = "units"
+)
+
+// UnitPack is a pack of an inventory item; its size is set per item
+const UnitPack = "pack"
+
+// unitConversions maps every unit a quantity may be given in to the stock
+// unit it converts to and the factor to apply
+var unitConversions = map[string]struct {
+	base   string
+	factor float64
+}{
+	UnitShots: {UnitShots, 1},
+	UnitMl:    {UnitMl, 1},
+	"l":       {UnitMl, 1000},
+	UnitG:     {UnitG, 1},
+	"kg":      {UnitG, 1000},
+	UnitUnits: {UnitUnits, 1},
+}
+
+// InventoryUnit is the stock unit of an inventory item and, if it is sold in
+// packs, the number of stock units in a pack
+type InventoryUnit struct {
+	Unit     string
+	PackSize int
+}
+
+// IsUnit reports whether unit is a unit a quantity may be given in
+func IsUnit(unit string) bool {
+	_, ok := unitConversions[unit]
+	return ok || unit == UnitPack
+}
+
+// NormalizeQuantity converts quantity given in unit to a whole number of the
+// inventory item's stock unit. An empty unit means the stock unit already.
+func NormalizeQuantity(quantity float64, unit string, target InventoryUnit) (int, error) {
+	factor := 1.0
+	switch unit {
+	case "", target.Unit:
+	case UnitPack:
+		if target.PackSize < 1 {
+			return 0, fmt.Errorf("%w: item has no pack size", ErrIncompatibleUnit)
+		}
+		factor = float64(target.PackSize)
+	default:
+		conversion, ok := unitConversions[unit]
+		if !ok {
+			return 0, ErrUnknownUnit
+		}
+		if conversion.base != target.Unit {
+			return 0, fmt.Errorf("%w: %s cannot be converted to %s", ErrIncompatibleUnit, unit, target.Unit)
+		}
+		factor = conversion.factor
+	}
+
+	normalized := quantity * factor
+	rounded := math.Round(normalized)
+	// allow for float noise such as 0.1 l * 1000
+	if math.Abs(normalized-rounded) > 1e-6 {
+		return 0, fmt.Errorf("%w: %v %s is not a whole number of %s", ErrFractionalQuantity, quantity, unit, target.Unit)
+	}
+
+	return int(rounded), nil
+}
+
+// unitProblem describes why quantity in unit cannot be used for an item stocked
+// in target, or returns "" if it can. Validators use it for their error maps.
+func unitProblem(quantity float64, unit string, target InventoryUnit) string {
+	_, err := NormalizeQuantity(quantity, unit, target)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrUnknownUnit):
+		return "Unit must be one of shots, ml, l, g, kg, units or pack"
+	case errors.Is(err, ErrFractionalQuantity):
+		return "Quantity must be a whole number of " + target.Unit
+	case unit == UnitPack:
+		return "Item has no pack size"
+	default:
+		return fmt.Sprintf("Unit %s is not compatible with %s", unit, target.Unit)
+	}
+}
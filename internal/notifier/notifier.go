@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+// Notifier delivers low stock alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert LowStockAlert) error
+}
+
+type LowStockAlert struct {
+	CreatedAt time.Time             `json:"created_at"`
+	Items     []models.LowStockItem `json:"items"`
+}
+
+var ErrUnknownNotifier = errors.New("unknown notifier; should be 'log', 'webhook' or 'file'")
+
+// New returns the notifier of the given kind. target is the URL for a webhook
+// and the path for a file notifier; the log notifier ignores it.
+func New(kind, target string, logger *slog.Logger) (Notifier, error) {
+	switch kind {
+	case "", "log":
+		return NewLogNotifier(logger), nil
+	case "webhook":
+		if target == "" {
+			return nil, errors.New("webhook notifier needs a URL")
+		}
+		return NewWebhookNotifier(target), nil
+	case "file":
+		if target == "" {
+			return nil, errors.New("file notifier needs a path")
+		}
+		return NewFileNotifier(target), nil
+	}
+	return nil, ErrUnknownNotifier
+}
+
+type logNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *logNotifier {
+	return &logNotifier{logger: logger}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, alert LowStockAlert) error {
+	for _, item := range alert.Items {
+		n.logger.Warn("low stock",
+			"inventory_id", item.InventoryID,
+			"name", item.Name,
+			"quantity", item.Quantity,
+			"unit", item.Unit,
+			"min_quantity", item.MinQuantity,
+			"reorder_quantity", item.ReorderQuantity,
+		)
+	}
+	return nil
+}
+
+// webhookNotifier posts every alert as JSON to a URL
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, alert LowStockAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// fileNotifier appends every alert as a JSON line to a file
+type fileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *fileNotifier {
+	return &fileNotifier{path: path}
+}
+
+func (n *fileNotifier) Notify(ctx context.Context, alert LowStockAlert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
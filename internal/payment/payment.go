@@ -0,0 +1,66 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"frappuccino/internal/models"
+)
+
+// PaymentProvider charges and refunds card payments. Amounts include the tip.
+type PaymentProvider interface {
+	// Charge takes amount from the card and returns the authorization to
+	// refund it with; a declined card is models.ErrPaymentDeclined
+	Charge(ctx context.Context, card string, amount float64) (string, error)
+	Refund(ctx context.Context, authorization string, amount float64) error
+}
+
+var ErrUnknownProvider = errors.New("unknown payment provider; should be 'fake'")
+
+// New returns the payment provider of the given kind
+func New(kind string) (PaymentProvider, error) {
+	switch kind {
+	case "", "fake":
+		return NewFakeProvider(), nil
+	}
+	return nil, ErrUnknownProvider
+}
+
+// FakeDeclinedCard is the card the fake provider declines
+const FakeDeclinedCard = "declined"
+
+// fakeProvider approves every card but FakeDeclinedCard without contacting
+// anyone, so payments can be tried out offline. It forgets its charges on
+// restart, after which any authorization may be refunded once.
+type fakeProvider struct {
+	mu       sync.Mutex
+	next     int
+	refunded map[string]bool
+}
+
+func NewFakeProvider() *fakeProvider {
+	return &fakeProvider{refunded: make(map[string]bool)}
+}
+
+func (p *fakeProvider) Charge(ctx context.Context, card string, amount float64) (string, error) {
+	if card == FakeDeclinedCard {
+		return "", models.ErrPaymentDeclined
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next++
+	return fmt.Sprintf("fake-%06d", p.next), nil
+}
+
+func (p *fakeProvider) Refund(ctx context.Context, authorization string, amount float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.refunded[authorization] {
+		return fmt.Errorf("authorization %s is already refunded", authorization)
+	}
+	p.refunded[authorization] = true
+	return nil
+}
@@ -0,0 +1,147 @@
+package postgre
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type customerRepositoryPostgres struct {
+	pq     *sql.DB
+	logger *slog.Logger
+}
+
+func NewCustomerRepositoryPostgres(db *sql.DB, logger *slog.Logger) *customerRepositoryPostgres {
+	return &customerRepositoryPostgres{
+		pq:     db,
+		logger: logger,
+	}
+}
+
+const customerColumns = "id, name, COALESCE(email, ''), COALESCE(phone, ''), default_preferences, created_at"
+
+func scanCustomer(row interface{ Scan(...any) error }) (models.Customer, error) {
+	var customer models.Customer
+	var prefsBytes []byte
+	err := row.Scan(&customer.ID, &customer.Name, &customer.Email, &customer.Phone, &prefsBytes, &customer.CreatedAt)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	err = json.Unmarshal(prefsBytes, &customer.DefaultPreferences)
+	return customer, err
+}
+
+// customerError maps constraint violations on customers to model errors
+func customerError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case "23505":
+			return models.ErrDuplicateCustomer
+		}
+	}
+	return err
+}
+
+func (m *customerRepositoryPostgres) Insert(customer models.Customer) (int, error) {
+	prefsJSON, err := json.Marshal(customer.DefaultPreferences)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = m.pq.QueryRow(`
+		INSERT INTO customers (name, email, phone, default_preferences)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		RETURNING id`,
+		customer.Name, customer.Email, customer.Phone, prefsJSON).
+		Scan(&id)
+	if err != nil {
+		m.logger.Error("Failed to insert customer", "error", err)
+		return 0, customerError(err)
+	}
+
+	return id, nil
+}
+
+// RetrieveAll returns the customers whose name, email or phone contains search,
+// or all customers when search is empty
+func (m *customerRepositoryPostgres) RetrieveAll(search string) ([]models.Customer, error) {
+	rows, err := m.pq.Query(`
+		SELECT `+customerColumns+`
+		FROM customers
+		WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%' OR phone LIKE '%' || $1 || '%'
+		ORDER BY name, id`, search)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, rows.Err()
+}
+
+func (m *customerRepositoryPostgres) RetrieveByID(id int) (models.Customer, error) {
+	customer, err := scanCustomer(m.pq.QueryRow("SELECT "+customerColumns+" FROM customers WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Customer{}, models.ErrNoRecord
+		}
+		return models.Customer{}, err
+	}
+
+	return customer, nil
+}
+
+func (m *customerRepositoryPostgres) Update(id int, customer models.Customer) error {
+	prefsJSON, err := json.Marshal(customer.DefaultPreferences)
+	if err != nil {
+		return err
+	}
+
+	result, err := m.pq.Exec(`
+		UPDATE customers
+		SET name = $1, email = NULLIF($2, ''), phone = NULLIF($3, ''), default_preferences = $4
+		WHERE id = $5`,
+		customer.Name, customer.Email, customer.Phone, prefsJSON, id)
+	if err != nil {
+		m.logger.Error("Failed to update customer", "error", err)
+		return customerError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// Delete removes the customer; their orders keep the name they were placed under
+func (m *customerRepositoryPostgres) Delete(id int) error {
+	result, err := m.pq.Exec("DELETE FROM customers WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
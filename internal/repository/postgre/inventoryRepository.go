@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 
 	"frappuccino/internal/models"
 
@@ -23,10 +25,11 @@ func NewInventoryRepositoryWithPostgres(db *sql.DB, logger *slog.Logger) *invent
 	}
 }
 
-func (m *inventoryRepositoryPostgres) Insert(name, unit string, quantity int, categories []string) error {
+func (m *inventoryRepositoryPostgres) Insert(inventory models.Inventory) error {
 	_, err := m.pq.Exec(
-		"INSERT INTO inventory (name, quantity, unit, categories) VALUES ($1, $2, $3, $4)",
-		name, quantity, unit, pq.Array(categories),
+		"INSERT INTO inventory (name, quantity, unit, categories, min_quantity, reorder_quantity, pack_size, unit_cost) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		inventory.Name, inventory.Quantity, inventory.Unit, pq.Array(inventory.Categories),
+		inventory.MinQuantity, inventory.ReorderQuantity, inventory.PackSize, inventory.UnitCost,
 	)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
@@ -47,12 +50,16 @@ func (m *inventoryRepositoryPostgres) Insert(name, unit string, quantity int, ca
 
 func (m *inventoryRepositoryPostgres) RetrieveByID(id int) (models.Inventory, error) {
 	var inventory models.Inventory
-	err := m.pq.QueryRow("SELECT * FROM inventory WHERE id = $1", id).Scan(
+	err := m.pq.QueryRow("SELECT id, name, quantity, unit, categories, min_quantity, reorder_quantity, pack_size, unit_cost FROM inventory WHERE id = $1", id).Scan(
 		&inventory.ID,
 		&inventory.Name,
 		&inventory.Quantity,
 		&inventory.Unit,
 		pq.Array(&inventory.Categories),
+		&inventory.MinQuantity,
+		&inventory.ReorderQuantity,
+		&inventory.PackSize,
+		&inventory.UnitCost,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -65,7 +72,7 @@ func (m *inventoryRepositoryPostgres) RetrieveByID(id int) (models.Inventory, er
 }
 
 func (m *inventoryRepositoryPostgres) RetrieveAll() ([]models.Inventory, error) {
-	rows, err := m.pq.Query("SELECT * FROM inventory")
+	rows, err := m.pq.Query("SELECT id, name, quantity, unit, categories, min_quantity, reorder_quantity, pack_size, unit_cost FROM inventory")
 	if err != nil {
 		m.logger.Error("Failed to execute Query", "error", err)
 		return nil, err
@@ -82,6 +89,10 @@ func (m *inventoryRepositoryPostgres) RetrieveAll() ([]models.Inventory, error)
 			&inventory.Quantity,
 			&inventory.Unit,
 			pq.Array(&inventory.Categories),
+			&inventory.MinQuantity,
+			&inventory.ReorderQuantity,
+			&inventory.PackSize,
+			&inventory.UnitCost,
 		)
 		if err != nil {
 			return nil, err
@@ -97,10 +108,36 @@ func (m *inventoryRepositoryPostgres) RetrieveAll() ([]models.Inventory, error)
 	return InventoryAll, err
 }
 
-func (m *inventoryRepositoryPostgres) Update(id int, name, unit string, quantity int, categories []string) error {
+// setInventoryReason tags the quantity changes made later in tx with a reason,
+// reference and note, which the log_inventory_transaction trigger records
+func setInventoryReason(tx *sql.Tx, reason, reference string) error {
+	return setInventoryReasonWithNote(tx, reason, reference, "")
+}
+
+func setInventoryReasonWithNote(tx *sql.Tx, reason, reference, note string) error {
+	_, err := tx.Exec(`
+		SELECT set_config('app.inventory_reason', $1, true),
+		       set_config('app.inventory_reference', $2, true),
+		       set_config('app.inventory_note', $3, true)
+	`, reason, reference, note)
+	return err
+}
+
+// orderReference is the inventory transaction reference of stock moved by an order
+func orderReference(orderID int) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// Update changes the metadata of an inventory item. The quantity only changes
+// through Adjust and the order endpoints, so concurrent changes add up. The unit
+// cost may be corrected here; deliveries average it with the price paid. The
+// unit cannot change: no two stock units convert into each other, so the stock,
+// recipes, thresholds and cost would all be misread.
+func (m *inventoryRepositoryPostgres) Update(id int, inventory models.Inventory) error {
 	result, err := m.pq.Exec(
-		"UPDATE inventory SET name=$1, unit=$2, quantity=$3, categories=$4 WHERE id=$5",
-		name, unit, quantity, pq.Array(categories), id,
+		"UPDATE inventory SET name=$1, categories=$3, min_quantity=$4, reorder_quantity=$5, pack_size=$6, unit_cost=$7 WHERE id=$8 AND unit=$2",
+		inventory.Name, inventory.Unit, pq.Array(inventory.Categories),
+		inventory.MinQuantity, inventory.ReorderQuantity, inventory.PackSize, inventory.UnitCost, id,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -121,11 +158,90 @@ func (m *inventoryRepositoryPostgres) Update(id int, name, unit string, quantity
 	}
 
 	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if rowsAffected == 0 {
+		var exists bool
+		err = m.pq.QueryRow("SELECT EXISTS (SELECT 1 FROM inventory WHERE id = $1)", id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return models.ErrInventoryUnitChange
+		}
 		return models.ErrNoRecord
 	}
 
-	return err
+	return nil
+}
+
+// RetrieveUnits returns the stock unit and pack size of the given inventory
+// items; ids that do not exist are left out
+func (m *inventoryRepositoryPostgres) RetrieveUnits(ids []int) (map[int]models.InventoryUnit, error) {
+	rows, err := m.pq.Query("SELECT id, unit, pack_size FROM inventory WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := make(map[int]models.InventoryUnit)
+	for rows.Next() {
+		var id int
+		var unit models.InventoryUnit
+		if err := rows.Scan(&id, &unit.Unit, &unit.PackSize); err != nil {
+			return nil, err
+		}
+		units[id] = unit
+	}
+
+	return units, rows.Err()
+}
+
+// Adjust applies all adjustments in one transaction as quantity = quantity + delta,
+// so the positive_quantity check rejects the whole batch if any item would go
+// below zero. Items are locked in id order to avoid deadlocks between batches.
+func (m *inventoryRepositoryPostgres) Adjust(adjustments []models.InventoryAdjustment, reference string) ([]models.AdjustedInventory, error) {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	adjustments = slices.Clone(adjustments)
+	slices.SortFunc(adjustments, func(a, b models.InventoryAdjustment) int {
+		return a.InventoryID - b.InventoryID
+	})
+
+	adjusted := make([]models.AdjustedInventory, 0, len(adjustments))
+	for _, adjustment := range adjustments {
+		err = setInventoryReasonWithNote(tx, adjustment.Reason, reference, adjustment.Note)
+		if err != nil {
+			return nil, err
+		}
+
+		item := models.AdjustedInventory{InventoryID: adjustment.InventoryID, Delta: int(adjustment.Delta)}
+		err = tx.QueryRow("UPDATE inventory SET quantity = quantity + $1 WHERE id = $2 RETURNING name, quantity",
+			item.Delta, adjustment.InventoryID).
+			Scan(&item.Name, &item.Quantity)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, models.ErrNoRecord
+			}
+			if pqErr, ok := err.(*pq.Error); ok {
+				switch pqErr.Code {
+				case "23514":
+					return nil, models.ErrNegativeQuantity
+				}
+			}
+			return nil, err
+		}
+		adjusted = append(adjusted, item)
+	}
+
+	return adjusted, tx.Commit()
 }
 
 func (m *inventoryRepositoryPostgres) Delete(id int) error {
@@ -171,3 +287,85 @@ func (m *inventoryRepositoryPostgres) GetLeftOvers(sortColumn string, page, page
 
 	return leftovers, totalPages, nil
 }
+
+// Transactions returns the recorded quantity changes matching filter, oldest first
+func (m *inventoryRepositoryPostgres) Transactions(filter models.InventoryTransactionFilter) ([]models.InventoryTransaction, error) {
+	var queryArgs []any
+	var conditions []string
+	if filter.InventoryID != 0 {
+		queryArgs = append(queryArgs, filter.InventoryID)
+		conditions = append(conditions, fmt.Sprintf("t.inventory_id = $%v", len(queryArgs)))
+	}
+	if filter.From != "" {
+		queryArgs = append(queryArgs, filter.From)
+		conditions = append(conditions, fmt.Sprintf("t.transaction_date::date >= $%v", len(queryArgs)))
+	}
+	if filter.To != "" {
+		queryArgs = append(queryArgs, filter.To)
+		conditions = append(conditions, fmt.Sprintf("t.transaction_date::date <= $%v", len(queryArgs)))
+	}
+	if filter.Reason != "" {
+		queryArgs = append(queryArgs, filter.Reason)
+		conditions = append(conditions, fmt.Sprintf("t.reason = $%v", len(queryArgs)))
+	}
+
+	query := `
+		SELECT t.id, t.inventory_id, i.name, t.old_quantity, t.new_quantity, t.reason,
+		       COALESCE(t.reference, ''), COALESCE(t.note, ''), t.transaction_date
+		FROM inventory_transactions t
+		JOIN inventory i ON i.id = t.inventory_id`
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	query += "\n\t\tORDER BY t.transaction_date, t.id"
+
+	rows, err := m.pq.Query(query, queryArgs...)
+	if err != nil {
+		m.logger.Error("Failed to execute inventory transactions query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []models.InventoryTransaction{}
+	for rows.Next() {
+		var t models.InventoryTransaction
+		err := rows.Scan(&t.ID, &t.InventoryID, &t.Name, &t.OldQuantity, &t.NewQuantity, &t.Reason, &t.Reference, &t.Note, &t.TransactionDate)
+		if err != nil {
+			m.logger.Error("Failed to scan inventory transaction row", "error", err)
+			return nil, err
+		}
+		t.Change = t.NewQuantity - t.OldQuantity
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// LowStock returns the items whose stock is below their minimum quantity,
+// the relatively emptiest first
+func (m *inventoryRepositoryPostgres) LowStock() ([]models.LowStockItem, error) {
+	rows, err := m.pq.Query(`
+		SELECT id, name, quantity, unit, min_quantity, reorder_quantity
+		FROM inventory
+		WHERE quantity < min_quantity
+		ORDER BY quantity::float / min_quantity, name
+	`)
+	if err != nil {
+		m.logger.Error("Failed to execute low stock query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.LowStockItem{}
+	for rows.Next() {
+		var item models.LowStockItem
+		err := rows.Scan(&item.InventoryID, &item.Name, &item.Quantity, &item.Unit, &item.MinQuantity, &item.ReorderQuantity)
+		if err != nil {
+			m.logger.Error("Failed to scan low stock row", "error", err)
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
@@ -0,0 +1,337 @@
+package postgre
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type loyaltyRepositoryPostgres struct {
+	pq     *sql.DB
+	logger *slog.Logger
+}
+
+func NewLoyaltyRepositoryPostgres(db *sql.DB, logger *slog.Logger) *loyaltyRepositoryPostgres {
+	return &loyaltyRepositoryPostgres{
+		pq:     db,
+		logger: logger,
+	}
+}
+
+// loyaltyError maps a missing menu category to its model error
+func loyaltyError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case "23503":
+			return models.ErrForeignKeyConstraintMenuCategory
+		}
+	}
+	return err
+}
+
+func (m *loyaltyRepositoryPostgres) InsertRule(rule models.LoyaltyRule) (int, error) {
+	var id int
+	err := m.pq.QueryRow(`
+		INSERT INTO loyalty_rules (name, unit, earn, per_amount, category_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		rule.Name, rule.Unit, rule.Earn, rule.PerAmount, rule.CategoryID).
+		Scan(&id)
+	if err != nil {
+		m.logger.Error("Failed to insert loyalty rule", "error", err)
+		return 0, loyaltyError(err)
+	}
+
+	return id, nil
+}
+
+func (m *loyaltyRepositoryPostgres) RetrieveRules() ([]models.LoyaltyRule, error) {
+	rows, err := m.pq.Query("SELECT id, name, unit, earn, per_amount, category_id FROM loyalty_rules ORDER BY id")
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.LoyaltyRule{}
+	for rows.Next() {
+		var rule models.LoyaltyRule
+		var perAmount sql.NullFloat64
+		var categoryID sql.NullInt32
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Unit, &rule.Earn, &perAmount, &categoryID); err != nil {
+			return nil, err
+		}
+		if perAmount.Valid {
+			rule.PerAmount = &perAmount.Float64
+		}
+		rule.CategoryID = nullIntPtr(categoryID)
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (m *loyaltyRepositoryPostgres) UpdateRule(id int, rule models.LoyaltyRule) error {
+	result, err := m.pq.Exec(`
+		UPDATE loyalty_rules
+		SET name = $1, unit = $2, earn = $3, per_amount = $4, category_id = $5
+		WHERE id = $6`,
+		rule.Name, rule.Unit, rule.Earn, rule.PerAmount, rule.CategoryID, id)
+	if err != nil {
+		m.logger.Error("Failed to update loyalty rule", "error", err)
+		return loyaltyError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+func (m *loyaltyRepositoryPostgres) DeleteRule(id int) error {
+	result, err := m.pq.Exec("DELETE FROM loyalty_rules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+func (m *loyaltyRepositoryPostgres) InsertReward(reward models.LoyaltyReward) (int, error) {
+	var id int
+	err := m.pq.QueryRow(`
+		INSERT INTO loyalty_rewards (name, unit, cost, category_id, discount_percent)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		reward.Name, reward.Unit, reward.Cost, reward.CategoryID, reward.DiscountPercent).
+		Scan(&id)
+	if err != nil {
+		m.logger.Error("Failed to insert loyalty reward", "error", err)
+		return 0, loyaltyError(err)
+	}
+
+	return id, nil
+}
+
+func (m *loyaltyRepositoryPostgres) RetrieveRewards() ([]models.LoyaltyReward, error) {
+	rows, err := m.pq.Query("SELECT id, name, unit, cost, category_id, discount_percent FROM loyalty_rewards ORDER BY unit, cost, id")
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rewards := []models.LoyaltyReward{}
+	for rows.Next() {
+		var reward models.LoyaltyReward
+		var categoryID sql.NullInt32
+		if err := rows.Scan(&reward.ID, &reward.Name, &reward.Unit, &reward.Cost, &categoryID, &reward.DiscountPercent); err != nil {
+			return nil, err
+		}
+		reward.CategoryID = nullIntPtr(categoryID)
+		rewards = append(rewards, reward)
+	}
+
+	return rewards, rows.Err()
+}
+
+func (m *loyaltyRepositoryPostgres) UpdateReward(id int, reward models.LoyaltyReward) error {
+	result, err := m.pq.Exec(`
+		UPDATE loyalty_rewards
+		SET name = $1, unit = $2, cost = $3, category_id = $4, discount_percent = $5
+		WHERE id = $6`,
+		reward.Name, reward.Unit, reward.Cost, reward.CategoryID, reward.DiscountPercent, id)
+	if err != nil {
+		m.logger.Error("Failed to update loyalty reward", "error", err)
+		return loyaltyError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+func (m *loyaltyRepositoryPostgres) DeleteReward(id int) error {
+	result, err := m.pq.Exec("DELETE FROM loyalty_rewards WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// Balance returns the customer's balance per loyalty unit
+func (m *loyaltyRepositoryPostgres) Balance(customerID int) (map[string]int, error) {
+	rows, err := m.pq.Query(`
+		SELECT unit, SUM(amount) FROM loyalty_ledger
+		WHERE customer_id = $1
+		GROUP BY unit`, customerID)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := make(map[string]int)
+	for rows.Next() {
+		var unit string
+		var amount int
+		if err := rows.Scan(&unit, &amount); err != nil {
+			return nil, err
+		}
+		balance[unit] = amount
+	}
+
+	return balance, rows.Err()
+}
+
+// Ledger returns the customer's loyalty entries, newest first
+func (m *loyaltyRepositoryPostgres) Ledger(customerID int) ([]models.LoyaltyEntry, error) {
+	rows, err := m.pq.Query(`
+		SELECT id, order_id, event, unit, amount, rule_id, reward_id, COALESCE(note, ''), created_at
+		FROM loyalty_ledger
+		WHERE customer_id = $1
+		ORDER BY created_at DESC, id DESC`, customerID)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ledger := []models.LoyaltyEntry{}
+	for rows.Next() {
+		var entry models.LoyaltyEntry
+		var orderID, ruleID, rewardID sql.NullInt32
+		err := rows.Scan(&entry.ID, &orderID, &entry.Event, &entry.Unit, &entry.Amount, &ruleID, &rewardID, &entry.Note, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.OrderID = nullIntPtr(orderID)
+		entry.RuleID = nullIntPtr(ruleID)
+		entry.RewardID = nullIntPtr(rewardID)
+		ledger = append(ledger, entry)
+	}
+
+	return ledger, rows.Err()
+}
+
+// earnLoyalty credits the order's customer under every loyalty rule. Spending
+// is what the customer pays: the lines' gross amounts after discounts, tax
+// included whatever the tax class. The unit a reward made free earns no stamp.
+func earnLoyalty(tx *sql.Tx, orderID int) error {
+	query := `
+		INSERT INTO loyalty_ledger (customer_id, order_id, event, unit, amount, rule_id)
+		SELECT o.customer_id, o.id, 'earn', r.unit, earned.amount, r.id
+		FROM orders o
+		CROSS JOIN loyalty_rules r
+		CROSS JOIN LATERAL (
+			SELECT CASE
+				WHEN r.per_amount IS NOT NULL
+					THEN FLOOR(SUM(` + lineGross + `) / r.per_amount)::int * r.earn
+				ELSE COALESCE(SUM(oi.quantity - (oi.reward_id IS NOT NULL)::int)
+					FILTER (WHERE mi.category_id = r.category_id), 0)::int * r.earn
+			END AS amount
+			FROM order_item oi
+			LEFT JOIN menu_items mi ON mi.id = oi.menu_item_id
+			WHERE oi.order_id = o.id
+		) earned
+		WHERE o.id = $1 AND o.customer_id IS NOT NULL AND earned.amount > 0`
+
+	_, err := tx.Exec(query, orderID)
+	return err
+}
+
+// reverseLoyalty cancels out what the order has so far earned or redeemed
+// (as given by event) with entries of the opposite sign
+func reverseLoyalty(tx *sql.Tx, orderID int, event, note string) error {
+	_, err := tx.Exec(`
+		INSERT INTO loyalty_ledger (customer_id, order_id, event, unit, amount, rule_id, reward_id, note)
+		SELECT customer_id, order_id, event, unit, -SUM(amount), rule_id, reward_id, $3
+		FROM loyalty_ledger
+		WHERE order_id = $1 AND event = $2
+		GROUP BY customer_id, order_id, event, unit, rule_id, reward_id
+		HAVING SUM(amount) <> 0`, orderID, event, note)
+	return err
+}
+
+// redeemReward applies the line's reward as a discount on one unit of it, on
+// top of any promotion but never below zero, and takes its cost from the
+// customer's balance
+func redeemReward(tx *sql.Tx, customerID *int, orderID, orderItemID int, item models.OrderItem) error {
+	if customerID == nil {
+		return models.ErrRewardNeedsCustomer
+	}
+
+	var unit string
+	var cost int
+	var applies bool
+	err := tx.QueryRow(`
+		SELECT r.unit, r.cost,
+		       COALESCE(r.category_id IS NULL OR r.category_id = (SELECT category_id FROM menu_items WHERE id = $2), false)
+		FROM loyalty_rewards r
+		WHERE r.id = $1`, *item.RewardID, item.MenuID).
+		Scan(&unit, &cost, &applies)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrForeignKeyConstraintReward
+		}
+		return err
+	}
+	if !applies {
+		return models.ErrRewardNotApplicable
+	}
+
+	// the customer row lock keeps two orders from spending the same balance
+	var balance int
+	err = tx.QueryRow(`
+		SELECT COALESCE((SELECT SUM(amount) FROM loyalty_ledger WHERE customer_id = c.id AND unit = $2), 0)
+		FROM customers c
+		WHERE c.id = $1
+		FOR UPDATE`, *customerID, unit).
+		Scan(&balance)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrForeignKeyConstraintCustomer
+		}
+		return err
+	}
+	if balance < cost {
+		return models.ErrInsufficientLoyalty
+	}
+
+	_, err = tx.Exec(`
+		UPDATE order_item oi
+		SET discount = LEAST(oi.discount + ROUND(oi.unit_price * r.discount_percent / 100, 2),
+		                     oi.quantity * oi.unit_price - oi.order_discount),
+		    reward_id = r.id
+		FROM loyalty_rewards r
+		WHERE oi.id = $1 AND r.id = $2`, orderItemID, *item.RewardID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO loyalty_ledger (customer_id, order_id, event, unit, amount, reward_id)
+		VALUES ($1, $2, 'redeem', $3, $4, $5)`,
+		*customerID, orderID, unit, -cost, *item.RewardID)
+	return err
+}
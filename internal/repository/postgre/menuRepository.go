@@ -31,8 +31,10 @@ func (m *menuRepositoryPostgres) InsertMenuItem(menuItem models.MenuItem) error
 	defer tx.Rollback()
 
 	var menuID int
-	err = tx.QueryRow(`INSERT INTO menu_items (name, description, price) VALUES ($1, $2, $3) RETURNING id`,
-		menuItem.Name, menuItem.Description, menuItem.Price).
+	err = tx.QueryRow(`
+		INSERT INTO menu_items (name, description, price, category_id, tax_class_id, takeaway_tax_class_id)
+		VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, 0), NULLIF($6, 0)) RETURNING id`,
+		menuItem.Name, menuItem.Description, menuItem.Price, menuItem.CategoryID, menuItem.TaxClassID, menuItem.TakeawayTaxClassID).
 		Scan(&menuID)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
@@ -41,6 +43,8 @@ func (m *menuRepositoryPostgres) InsertMenuItem(menuItem models.MenuItem) error
 				return models.ErrDuplicateMenuItem
 			case "23514":
 				return models.ErrNegativePrice
+			case "23503":
+				return menuItemForeignKeyError(pqErr)
 			}
 		}
 		return err
@@ -49,7 +53,7 @@ func (m *menuRepositoryPostgres) InsertMenuItem(menuItem models.MenuItem) error
 	for _, inv := range menuItem.Inventory {
 		_, err = tx.Exec(
 			"INSERT INTO menu_item_inventory (menu_id, inventory_id, quantity) VALUES ($1, $2, $3)",
-			menuID, inv.InventoryID, inv.Quantity,
+			menuID, inv.InventoryID, int(inv.Quantity),
 		)
 		if err != nil {
 			if pgErr, ok := err.(*pq.Error); ok {
@@ -67,65 +71,162 @@ func (m *menuRepositoryPostgres) InsertMenuItem(menuItem models.MenuItem) error
 	return tx.Commit()
 }
 
-func (m *menuRepositoryPostgres) RetrieveAll() ([]models.MenuItem, error) {
+// menuServingsQuery yields, per menu item with a recipe, how many servings the
+// current stock allows: the smallest stock/recipe ratio over its ingredients
+const menuServingsQuery = `
+	SELECT recipe.menu_id, MIN(stock.quantity / recipe.quantity) AS max_servings
+	FROM menu_item_inventory AS recipe
+	JOIN inventory AS stock ON stock.id = recipe.inventory_id
+	WHERE recipe.quantity > 0
+	GROUP BY recipe.menu_id
+`
+
+// RetrieveAll returns the menu ordered by category display order and item name.
+// A non-empty category restricts the result to that category (case-insensitive).
+func (m *menuRepositoryPostgres) RetrieveAll(category string) ([]models.MenuItem, error) {
 	rows, err := m.pq.Query(`
-		SELECT menu.id, menu.name, menu.description, menu.price, inventory.inventory_id, inventory.quantity
+		SELECT menu.id, menu.name, menu.description, menu.price, menu.category_id, category.name,
+		       menu.tax_class_id, menu.takeaway_tax_class_id, tax.name,
+		       servings.max_servings, inventory.inventory_id, inventory.quantity, item.unit
 		FROM menu_items AS menu
+		LEFT JOIN menu_categories AS category
+		ON menu.category_id = category.id
+		LEFT JOIN tax_classes AS tax
+		ON menu.tax_class_id = tax.id
+		LEFT JOIN (`+menuServingsQuery+`) AS servings
+		ON menu.id = servings.menu_id
 		LEFT JOIN menu_item_inventory AS inventory
 		ON menu.id=inventory.menu_id
-	`)
+		LEFT JOIN inventory AS item
+		ON item.id = inventory.inventory_id
+		WHERE $1 = '' OR lower(category.name) = lower($1)
+		ORDER BY category.display_order NULLS LAST, category.name, menu.name, menu.id
+	`, category)
 	if err != nil {
 		m.logger.Error("Failed to execute Query", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	menuMap := make(map[int]*models.MenuItem)
+	menuItems := []models.MenuItem{}
+	menuIndex := make(map[int]int)
 	for rows.Next() {
 		var id int
 		var name, description string
 		var price float64
-		var inventoryID, quantity sql.NullInt32
+		var categoryID, taxClassID, takeawayTaxClassID, maxServings, inventoryID, quantity sql.NullInt32
+		var categoryName, taxClass, unit sql.NullString
 
-		err := rows.Scan(&id, &name, &description, &price, &inventoryID, &quantity)
+		err := rows.Scan(&id, &name, &description, &price, &categoryID, &categoryName,
+			&taxClassID, &takeawayTaxClassID, &taxClass, &maxServings, &inventoryID, &quantity, &unit)
 		if err != nil {
 			m.logger.Error("Failed to scan row", "error", err)
 			return nil, err
 		}
 
-		if _, ok := menuMap[id]; !ok {
-			menuMap[id] = &models.MenuItem{
-				ID:          id,
-				Name:        name,
-				Description: description,
-				Price:       price,
-				Inventory:   []models.MenuItemInventory{},
+		idx, ok := menuIndex[id]
+		if !ok {
+			idx = len(menuItems)
+			menuIndex[id] = idx
+			menuItem := models.MenuItem{
+				ID:                 id,
+				Name:               name,
+				Description:        description,
+				Price:              price,
+				CategoryID:         int(categoryID.Int32),
+				Category:           categoryName.String,
+				TaxClassID:         int(taxClassID.Int32),
+				TakeawayTaxClassID: int(takeawayTaxClassID.Int32),
+				TaxClass:           taxClass.String,
+				Inventory:          []models.MenuItemInventory{},
 			}
+			setAvailability(&menuItem, maxServings)
+			menuItems = append(menuItems, menuItem)
 		}
 
 		if inventoryID.Valid {
-			menuMap[id].Inventory = append(menuMap[id].Inventory, models.MenuItemInventory{
+			menuItems[idx].Inventory = append(menuItems[idx].Inventory, models.MenuItemInventory{
 				InventoryID: int(inventoryID.Int32),
-				Quantity:    int(quantity.Int32),
+				Quantity:    float64(quantity.Int32),
+				Unit:        unit.String,
 			})
 		}
 	}
 
-	var menuItems []models.MenuItem
-	for _, menu := range menuMap {
-		menuItems = append(menuItems, *menu)
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
 	return menuItems, nil
 }
 
+// setAvailability fills the stock-derived fields of a menu item; items without
+// a recipe are always available
+func setAvailability(menuItem *models.MenuItem, maxServings sql.NullInt32) {
+	if !maxServings.Valid {
+		menuItem.MaxServings = nil
+		menuItem.Available = true
+		return
+	}
+
+	servings := int(maxServings.Int32)
+	menuItem.MaxServings = &servings
+	menuItem.Available = servings > 0
+}
+
+// RetrieveAvailability lists the menu items that can be served at most
+// threshold more times, fewest servings first
+func (m *menuRepositoryPostgres) RetrieveAvailability(threshold int) ([]models.MenuAvailability, error) {
+	rows, err := m.pq.Query(`
+		SELECT DISTINCT ON (servings.max_servings, menu.name, menu.id)
+		       menu.id, menu.name, servings.max_servings, stock.name
+		FROM menu_items AS menu
+		JOIN (`+menuServingsQuery+`) AS servings
+		ON menu.id = servings.menu_id
+		JOIN menu_item_inventory AS recipe
+		ON recipe.menu_id = menu.id AND recipe.quantity > 0
+		JOIN inventory AS stock
+		ON stock.id = recipe.inventory_id AND stock.quantity / recipe.quantity = servings.max_servings
+		WHERE servings.max_servings <= $1
+		ORDER BY servings.max_servings, menu.name, menu.id, stock.name
+	`, threshold)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.MenuAvailability{}
+	for rows.Next() {
+		var item models.MenuAvailability
+		err := rows.Scan(&item.MenuID, &item.Name, &item.MaxServings, &item.LimitedBy)
+		if err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+		item.Available = item.MaxServings > 0
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
 func (m *menuRepositoryPostgres) RetrieveByID(id int) (models.MenuItem, error) {
 	rows, err := m.pq.Query(`
-		SELECT menu.id, menu.name, menu.description, menu.price, 
-		       inventory.inventory_id, inventory.quantity
+		SELECT menu.id, menu.name, menu.description, menu.price, menu.category_id, category.name,
+		       menu.tax_class_id, menu.takeaway_tax_class_id, tax.name,
+		       servings.max_servings, inventory.inventory_id, inventory.quantity, item.unit
 		FROM menu_items AS menu
+		LEFT JOIN menu_categories AS category
+		ON menu.category_id = category.id
+		LEFT JOIN tax_classes AS tax
+		ON menu.tax_class_id = tax.id
+		LEFT JOIN (`+menuServingsQuery+`) AS servings
+		ON menu.id = servings.menu_id
 		LEFT JOIN menu_item_inventory AS inventory
 		ON menu.id = inventory.menu_id
+		LEFT JOIN inventory AS item
+		ON item.id = inventory.inventory_id
 		WHERE menu.id = $1
 	`, id)
 	if err != nil {
@@ -136,25 +237,40 @@ func (m *menuRepositoryPostgres) RetrieveByID(id int) (models.MenuItem, error) {
 
 	var menuItem models.MenuItem
 	for rows.Next() {
-		var inventoryID, quantity sql.NullInt32
+		var categoryID, taxClassID, takeawayTaxClassID, maxServings, inventoryID, quantity sql.NullInt32
+		var categoryName, taxClass, unit sql.NullString
 
 		err = rows.Scan(
 			&menuItem.ID,
 			&menuItem.Name,
 			&menuItem.Description,
 			&menuItem.Price,
+			&categoryID,
+			&categoryName,
+			&taxClassID,
+			&takeawayTaxClassID,
+			&taxClass,
+			&maxServings,
 			&inventoryID,
 			&quantity,
+			&unit,
 		)
 		if err != nil {
 			m.logger.Error("Failed to scan row", "error", err)
 			return models.MenuItem{}, err
 		}
+		menuItem.CategoryID = int(categoryID.Int32)
+		menuItem.Category = categoryName.String
+		menuItem.TaxClassID = int(taxClassID.Int32)
+		menuItem.TakeawayTaxClassID = int(takeawayTaxClassID.Int32)
+		menuItem.TaxClass = taxClass.String
+		setAvailability(&menuItem, maxServings)
 
 		if inventoryID.Valid {
 			menuItem.Inventory = append(menuItem.Inventory, models.MenuItemInventory{
 				InventoryID: int(inventoryID.Int32),
-				Quantity:    int(quantity.Int32),
+				Quantity:    float64(quantity.Int32),
+				Unit:        unit.String,
 			})
 		}
 	}
@@ -174,10 +290,23 @@ func (m *menuRepositoryPostgres) UpdateMenuItem(menuID int, menuItem models.Menu
 	}
 	defer tx.Rollback()
 
+	err = m.updateMenuItem(tx, menuID, menuItem)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateMenuItem replaces the menu item and its recipe within tx
+func (m *menuRepositoryPostgres) updateMenuItem(tx *sql.Tx, menuID int, menuItem models.MenuItem) error {
 	result, err := tx.Exec(`
 		UPDATE menu_items
-		SET name = $1, description = $2, price = $3 WHERE id = $4
-	`, menuItem.Name, menuItem.Description, menuItem.Price, menuID)
+		SET name = $1, description = $2, price = $3, category_id = NULLIF($4, 0),
+			tax_class_id = NULLIF($5, 0), takeaway_tax_class_id = NULLIF($6, 0)
+		WHERE id = $7
+	`, menuItem.Name, menuItem.Description, menuItem.Price, menuItem.CategoryID,
+		menuItem.TaxClassID, menuItem.TakeawayTaxClassID, menuID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.ErrNoRecord
@@ -188,6 +317,8 @@ func (m *menuRepositoryPostgres) UpdateMenuItem(menuID int, menuItem models.Menu
 				return models.ErrDuplicateMenuItem
 			case "23514":
 				return models.ErrNegativePrice
+			case "23503":
+				return menuItemForeignKeyError(pqErr)
 			}
 		}
 		return err
@@ -207,7 +338,7 @@ func (m *menuRepositoryPostgres) UpdateMenuItem(menuID int, menuItem models.Menu
 	for _, inv := range menuItem.Inventory {
 		_, err = tx.Exec(
 			"INSERT INTO menu_item_inventory (menu_id, inventory_id, quantity) VALUES ($1, $2, $3)",
-			menuID, inv.InventoryID, inv.Quantity,
+			menuID, inv.InventoryID, int(inv.Quantity),
 		)
 		if err != nil {
 			if pgErr, ok := err.(*pq.Error); ok {
@@ -222,7 +353,7 @@ func (m *menuRepositoryPostgres) UpdateMenuItem(menuID int, menuItem models.Menu
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func (m *menuRepositoryPostgres) Delete(id int) error {
@@ -240,3 +371,645 @@ func (m *menuRepositoryPostgres) Delete(id int) error {
 
 	return err
 }
+
+func (m *menuRepositoryPostgres) InsertCategory(category models.MenuCategory) error {
+	_, err := m.pq.Exec("INSERT INTO menu_categories (name, display_order) VALUES ($1, $2)",
+		category.Name, category.DisplayOrder)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateMenuCategory
+			}
+		}
+		m.logger.Error("Failed to insert menu category", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *menuRepositoryPostgres) RetrieveCategories() ([]models.MenuCategory, error) {
+	rows, err := m.pq.Query("SELECT id, name, display_order FROM menu_categories ORDER BY display_order, name")
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.MenuCategory{}
+	for rows.Next() {
+		var category models.MenuCategory
+		if err := rows.Scan(&category.ID, &category.Name, &category.DisplayOrder); err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (m *menuRepositoryPostgres) UpdateCategory(id int, category models.MenuCategory) error {
+	result, err := m.pq.Exec("UPDATE menu_categories SET name = $1, display_order = $2 WHERE id = $3",
+		category.Name, category.DisplayOrder, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateMenuCategory
+			}
+		}
+		m.logger.Error("Failed to update menu category", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// DeleteCategory removes the category; its menu items become uncategorized
+func (m *menuRepositoryPostgres) DeleteCategory(id int) error {
+	result, err := m.pq.Exec("DELETE FROM menu_categories WHERE id = $1", id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// menuItemForeignKeyError tells a missing category from a missing tax class
+func menuItemForeignKeyError(pqErr *pq.Error) error {
+	if pqErr.Constraint == "menu_items_category_id_fkey" {
+		return models.ErrForeignKeyConstraintMenuCategory
+	}
+	return models.ErrForeignKeyConstraintTaxClass
+}
+
+func (m *menuRepositoryPostgres) InsertTaxClass(taxClass models.TaxClass) error {
+	_, err := m.pq.Exec("INSERT INTO tax_classes (name, rate, added_to_price) VALUES ($1, $2, $3)",
+		taxClass.Name, taxClass.Rate, taxClass.AddedToPrice)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateTaxClass
+			}
+		}
+		m.logger.Error("Failed to insert tax class", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *menuRepositoryPostgres) RetrieveTaxClasses() ([]models.TaxClass, error) {
+	rows, err := m.pq.Query("SELECT id, name, rate, added_to_price FROM tax_classes ORDER BY name")
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	taxClasses := []models.TaxClass{}
+	for rows.Next() {
+		var taxClass models.TaxClass
+		if err := rows.Scan(&taxClass.ID, &taxClass.Name, &taxClass.Rate, &taxClass.AddedToPrice); err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+		taxClasses = append(taxClasses, taxClass)
+	}
+
+	return taxClasses, rows.Err()
+}
+
+// UpdateTaxClass changes the rate for orders taken from now on; order lines
+// keep the rate they were taxed at
+func (m *menuRepositoryPostgres) UpdateTaxClass(id int, taxClass models.TaxClass) error {
+	result, err := m.pq.Exec("UPDATE tax_classes SET name = $1, rate = $2, added_to_price = $3 WHERE id = $4",
+		taxClass.Name, taxClass.Rate, taxClass.AddedToPrice, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateTaxClass
+			}
+		}
+		m.logger.Error("Failed to update tax class", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// DeleteTaxClass removes a tax class no menu item uses
+func (m *menuRepositoryPostgres) DeleteTaxClass(id int) error {
+	result, err := m.pq.Exec("DELETE FROM tax_classes WHERE id = $1", id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23503":
+				return models.ErrTaxClassInUse
+			}
+		}
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+func (m *menuRepositoryPostgres) InsertModifierGroup(group models.ModifierGroup) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var groupID int
+	err = tx.QueryRow("INSERT INTO modifier_groups (name, required, max_select) VALUES ($1, $2, $3) RETURNING id",
+		group.Name, group.Required, group.MaxSelect).
+		Scan(&groupID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateModifierGroup
+			}
+		}
+		return err
+	}
+
+	err = insertModifiers(tx, groupID, group.Modifiers)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertModifiers(tx *sql.Tx, groupID int, modifiers []models.Modifier) error {
+	for _, modifier := range modifiers {
+		var modifierID int
+		err := tx.QueryRow("INSERT INTO modifiers (group_id, name, price_delta) VALUES ($1, $2, $3) RETURNING id",
+			groupID, modifier.Name, modifier.PriceDelta).
+			Scan(&modifierID)
+		if err != nil {
+			return err
+		}
+
+		err = insertModifierInventory(tx, modifierID, modifier.Inventory)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertModifierInventory(tx *sql.Tx, modifierID int, inventory []models.ModifierInventory) error {
+	for _, inv := range inventory {
+		_, err := tx.Exec("INSERT INTO modifier_inventory (modifier_id, inventory_id, quantity) VALUES ($1, $2, $3)",
+			modifierID, inv.InventoryID, inv.Quantity)
+		if err != nil {
+			if pgErr, ok := err.(*pq.Error); ok {
+				switch pgErr.Code {
+				case "23503":
+					return models.ErrForeignKeyConstraintMenuInventory
+				}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *menuRepositoryPostgres) RetrieveModifierGroups() ([]models.ModifierGroup, error) {
+	return m.retrieveModifierGroups("")
+}
+
+func (m *menuRepositoryPostgres) RetrieveModifierGroupByID(id int) (models.ModifierGroup, error) {
+	groups, err := m.retrieveModifierGroups("WHERE g.id = $1", id)
+	if err != nil {
+		return models.ModifierGroup{}, err
+	}
+	if len(groups) == 0 {
+		return models.ModifierGroup{}, models.ErrNoRecord
+	}
+
+	return groups[0], nil
+}
+
+// RetrieveMenuItemModifierGroups returns the modifier groups offered on a menu item
+func (m *menuRepositoryPostgres) RetrieveMenuItemModifierGroups(menuID int) ([]models.ModifierGroup, error) {
+	return m.retrieveModifierGroups("WHERE g.id IN (SELECT group_id FROM menu_item_modifier_groups WHERE menu_id = $1)", menuID)
+}
+
+func (m *menuRepositoryPostgres) retrieveModifierGroups(where string, args ...any) ([]models.ModifierGroup, error) {
+	rows, err := m.pq.Query(`
+		SELECT g.id, g.name, g.required, g.max_select, mo.id, mo.name, mo.price_delta, inv.inventory_id, inv.quantity, i.unit
+		FROM modifier_groups g
+		LEFT JOIN modifiers mo ON mo.group_id = g.id
+		LEFT JOIN modifier_inventory inv ON inv.modifier_id = mo.id
+		LEFT JOIN inventory i ON i.id = inv.inventory_id
+		`+where+`
+		ORDER BY g.name, g.id, mo.id, inv.inventory_id
+	`, args...)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []models.ModifierGroup{}
+	groupIndex := make(map[int]int)
+	modifierIndex := make(map[int]int)
+	for rows.Next() {
+		var group models.ModifierGroup
+		var modifierID, inventoryID, quantity sql.NullInt32
+		var modifierName, unit sql.NullString
+		var priceDelta sql.NullFloat64
+
+		err := rows.Scan(&group.ID, &group.Name, &group.Required, &group.MaxSelect,
+			&modifierID, &modifierName, &priceDelta, &inventoryID, &quantity, &unit)
+		if err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+
+		gi, ok := groupIndex[group.ID]
+		if !ok {
+			gi = len(groups)
+			groupIndex[group.ID] = gi
+			group.Modifiers = []models.Modifier{}
+			groups = append(groups, group)
+		}
+
+		if !modifierID.Valid {
+			continue
+		}
+		mi, ok := modifierIndex[int(modifierID.Int32)]
+		if !ok {
+			mi = len(groups[gi].Modifiers)
+			modifierIndex[int(modifierID.Int32)] = mi
+			groups[gi].Modifiers = append(groups[gi].Modifiers, models.Modifier{
+				ID:         int(modifierID.Int32),
+				Name:       modifierName.String,
+				PriceDelta: priceDelta.Float64,
+				Inventory:  []models.ModifierInventory{},
+			})
+		}
+
+		if inventoryID.Valid {
+			groups[gi].Modifiers[mi].Inventory = append(groups[gi].Modifiers[mi].Inventory, models.ModifierInventory{
+				InventoryID: int(inventoryID.Int32),
+				Quantity:    float64(quantity.Int32),
+				Unit:        unit.String,
+			})
+		}
+	}
+
+	return groups, rows.Err()
+}
+
+// UpdateModifierGroup updates the group and its modifiers in place: modifiers
+// given with an id keep it, modifiers without one are added, and modifiers
+// left out are deleted. Order lines keep the modifiers still in the group, and
+// modifiers deleted from it keep their snapshotted name and price.
+func (m *menuRepositoryPostgres) UpdateModifierGroup(id int, group models.ModifierGroup) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE modifier_groups SET name = $1, required = $2, max_select = $3 WHERE id = $4",
+		group.Name, group.Required, group.MaxSelect, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateModifierGroup
+			}
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	rows, err := tx.Query("SELECT id FROM modifiers WHERE group_id = $1 FOR UPDATE", id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+	existing := make(map[int]bool)
+	for rows.Next() {
+		var modifierID int
+		if err := rows.Scan(&modifierID); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[modifierID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	kept := []int{}
+	var added []models.Modifier
+	for _, modifier := range group.Modifiers {
+		if modifier.ID == 0 {
+			added = append(added, modifier)
+			continue
+		}
+		if !existing[modifier.ID] {
+			return models.ErrModifierNotInGroup
+		}
+		kept = append(kept, modifier.ID)
+	}
+
+	_, err = tx.Exec("DELETE FROM modifiers WHERE group_id = $1 AND NOT (id = ANY($2))", id, pq.Array(kept))
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	// names are unique in the group only once the transaction ends, so kept
+	// modifiers may swap names
+	_, err = tx.Exec("SET CONSTRAINTS unique_modifier_name DEFERRED")
+	if err != nil {
+		return err
+	}
+	for _, modifier := range group.Modifiers {
+		if modifier.ID == 0 {
+			continue
+		}
+		_, err = tx.Exec("UPDATE modifiers SET name = $1, price_delta = $2 WHERE id = $3",
+			modifier.Name, modifier.PriceDelta, modifier.ID)
+		if err != nil {
+			m.logger.Error("Failed to update modifier", "error", err)
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM modifier_inventory WHERE modifier_id = $1", modifier.ID)
+		if err != nil {
+			return err
+		}
+		err = insertModifierInventory(tx, modifier.ID, modifier.Inventory)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = insertModifiers(tx, id, added)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *menuRepositoryPostgres) DeleteModifierGroup(id int) error {
+	result, err := m.pq.Exec("DELETE FROM modifier_groups WHERE id = $1", id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// SetMenuItemModifierGroups replaces the modifier groups offered on a menu item
+func (m *menuRepositoryPostgres) SetMenuItemModifierGroups(menuID int, groupIDs []int) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM menu_items WHERE id = $1)", menuID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return models.ErrNoRecord
+	}
+
+	_, err = tx.Exec("DELETE FROM menu_item_modifier_groups WHERE menu_id = $1", menuID)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	for _, groupID := range groupIDs {
+		_, err = tx.Exec("INSERT INTO menu_item_modifier_groups (menu_id, group_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			menuID, groupID)
+		if err != nil {
+			if pgErr, ok := err.(*pq.Error); ok {
+				switch pgErr.Code {
+				case "23503":
+					return models.ErrForeignKeyConstraintModifierGroup
+				}
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *menuRepositoryPostgres) RetrievePriceHistory(menuID int) ([]models.PriceHistory, error) {
+	rows, err := m.pq.Query(`
+		SELECT id, menu_item_id, old_price, new_price, updated_at
+		FROM price_history
+		WHERE menu_item_id = $1
+		ORDER BY updated_at DESC, id DESC
+	`, menuID)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.PriceHistory{}
+	for rows.Next() {
+		var entry models.PriceHistory
+		err := rows.Scan(&entry.ID, &entry.MenuItemID, &entry.OldPrice, &entry.NewPrice, &entry.UpdatedAt)
+		if err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+
+	return history, rows.Err()
+}
+
+func (m *menuRepositoryPostgres) InsertScheduledPriceChange(change models.ScheduledPriceChange) (int, error) {
+	var id int
+	err := m.pq.QueryRow(`
+		INSERT INTO scheduled_price_changes (menu_item_id, new_price, effective_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, change.MenuItemID, change.NewPrice, change.EffectiveAt).
+		Scan(&id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23503":
+				return 0, models.ErrNoRecord
+			case "23514":
+				return 0, models.ErrNegativePrice
+			}
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// RetrieveScheduledPriceChanges returns the pending and applied price changes
+// of a menu item, by effective date
+func (m *menuRepositoryPostgres) RetrieveScheduledPriceChanges(menuID int) ([]models.ScheduledPriceChange, error) {
+	return m.retrieveScheduledPriceChanges("WHERE menu_item_id = $1", menuID)
+}
+
+// RetrieveDueScheduledPriceChanges returns the pending price changes whose
+// effective date has passed, oldest first
+func (m *menuRepositoryPostgres) RetrieveDueScheduledPriceChanges() ([]models.ScheduledPriceChange, error) {
+	return m.retrieveScheduledPriceChanges("WHERE applied_at IS NULL AND effective_at <= now()")
+}
+
+func (m *menuRepositoryPostgres) retrieveScheduledPriceChanges(where string, args ...any) ([]models.ScheduledPriceChange, error) {
+	rows, err := m.pq.Query(`
+		SELECT id, menu_item_id, new_price, effective_at, created_at, applied_at
+		FROM scheduled_price_changes
+		`+where+`
+		ORDER BY effective_at, id
+	`, args...)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.ScheduledPriceChange{}
+	for rows.Next() {
+		var change models.ScheduledPriceChange
+		var appliedAt sql.NullTime
+		err := rows.Scan(&change.ID, &change.MenuItemID, &change.NewPrice, &change.EffectiveAt, &change.CreatedAt, &appliedAt)
+		if err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+		if appliedAt.Valid {
+			change.AppliedAt = &appliedAt.Time
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// DeleteScheduledPriceChange removes a pending price change; applied changes
+// are part of the price history and cannot be removed
+func (m *menuRepositoryPostgres) DeleteScheduledPriceChange(menuID, id int) error {
+	result, err := m.pq.Exec("DELETE FROM scheduled_price_changes WHERE id = $1 AND menu_item_id = $2 AND applied_at IS NULL",
+		id, menuID)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// ApplyScheduledPriceChange stores the menu item that update makes of a
+// pending price change and marks the change applied, in one transaction. The
+// change and its menu item stay locked while update runs, so no concurrent
+// edit of the item is lost; the price history trigger records the change. A
+// change already applied or removed is ErrNoRecord.
+func (m *menuRepositoryPostgres) ApplyScheduledPriceChange(id int, update func(change models.ScheduledPriceChange) (models.MenuItem, error)) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	change := models.ScheduledPriceChange{ID: id}
+	err = tx.QueryRow(`
+		SELECT c.menu_item_id, c.new_price, c.effective_at, c.created_at
+		FROM scheduled_price_changes c
+		JOIN menu_items mi ON mi.id = c.menu_item_id
+		WHERE c.id = $1 AND c.applied_at IS NULL
+		FOR UPDATE`, id).
+		Scan(&change.MenuItemID, &change.NewPrice, &change.EffectiveAt, &change.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	menuItem, err := update(change)
+	if err != nil {
+		return err
+	}
+
+	err = m.updateMenuItem(tx, change.MenuItemID, menuItem)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE scheduled_price_changes SET applied_at = now() WHERE id = $1", id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -3,8 +3,11 @@ package postgre
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 	"time"
 
 	"frappuccino/internal/models"
@@ -39,9 +42,9 @@ func (m *orderRepositoryPostgres) Insert(order models.Order) (int, error) {
 	}
 
 	var orderID int
-	err = tx.QueryRow(`INSERT INTO orders (customer_name, order_status, customer_preferences) 
-		VALUES ($1, $2, $3) RETURNING id`,
-		order.CustomerName, "open", prefsJSON).
+	err = tx.QueryRow(`INSERT INTO orders (customer_name, customer_id, order_status, customer_preferences, promo_code, discount, takeaway) 
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7) RETURNING id`,
+		order.CustomerName, order.CustomerID, "open", prefsJSON, order.PromoCode, order.Discount, order.Takeaway).
 		Scan(&orderID)
 	if err != nil {
 		m.logger.Error(err.Error())
@@ -49,6 +52,8 @@ func (m *orderRepositoryPostgres) Insert(order models.Order) (int, error) {
 			switch pqErr.Code {
 			case "23505":
 				return orderID, models.ErrDuplicateOrder
+			case "23503":
+				return orderID, models.ErrForeignKeyConstraintCustomer
 			case "22P02":
 				return orderID, models.ErrInvalidEnumTypeInventory
 			}
@@ -57,129 +62,511 @@ func (m *orderRepositoryPostgres) Insert(order models.Order) (int, error) {
 	}
 
 	for _, menu := range order.Items {
-		_, err = tx.Exec("INSERT INTO order_item (order_id, menu_item_id, quantity) VALUES ($1, $2, $3)",
-			orderID, menu.MenuID, menu.Quantity)
+		err = insertOrderItem(tx, order.CustomerID, orderID, menu)
 		if err != nil {
 			m.logger.Error(err.Error())
-			if pgErr, ok := err.(*pq.Error); ok {
-				switch pgErr.Code {
-				case "23503":
-					return orderID, models.ErrForeignKeyConstraintOrderMenu
-				case "23514":
-					return orderID, models.ErrNegativeQuantity
-				}
+			return orderID, err
+		}
+	}
+
+	err = setOrderTotals(tx, orderID)
+	if err != nil {
+		m.logger.Error("Failed to total order", "error", err)
+		return orderID, err
+	}
+
+	err = recordPromotions(tx, orderID, order.Promotions)
+	if err != nil {
+		m.logger.Error("Failed to record promotions", "error", err)
+		return orderID, err
+	}
+
+	usage, err := recipeUsage(tx, order.Items)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return orderID, err
+	}
+
+	err = setInventoryReason(tx, models.InventoryReasonOrder, orderReference(orderID))
+	if err != nil {
+		return orderID, err
+	}
+
+	err = adjustOrderInventory(tx, orderID, usage)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return orderID, err
+	}
+
+	return orderID, tx.Commit()
+}
+
+// insertOrderItem stores an order line together with the menu item's current
+// price, name, tax class and ingredient cost, so later menu, tax and inventory
+// changes do not rewrite the order's revenue, tax or cost. The order must be
+// stored first, as whether it is takeaway picks the tax class. The line's discounts are the ones the
+// service priced; a reward on the line is redeemed on top of them from the
+// balance of customerID.
+func insertOrderItem(tx *sql.Tx, customerID *int, orderID int, item models.OrderItem) error {
+	var orderItemID int
+	err := tx.QueryRow(`
+		INSERT INTO order_item (order_id, menu_item_id, quantity, unit_price, item_name, tax_class, tax_rate, tax_added)
+		SELECT $1, mi.id, $3, CASE WHEN $4 THEN $5 ELSE mi.price END, mi.name, tc.name, COALESCE(tc.rate, 0), COALESCE(tc.added_to_price, false)
+		FROM menu_items mi
+		JOIN orders o ON o.id = $1
+		LEFT JOIN tax_classes tc ON tc.id = CASE
+			WHEN o.takeaway THEN COALESCE(mi.takeaway_tax_class_id, mi.tax_class_id)
+			ELSE mi.tax_class_id
+		END
+		WHERE mi.id = $2
+		RETURNING id`,
+		orderID, item.MenuID, item.Quantity, item.KeepPrice, item.UnitPrice).
+		Scan(&orderItemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrForeignKeyConstraintOrderMenu
+		}
+		if pgErr, ok := err.(*pq.Error); ok {
+			switch pgErr.Code {
+			case "23503":
+				return models.ErrForeignKeyConstraintOrderMenu
+			case "23514":
+				return models.ErrNegativeQuantity
 			}
-			return 0, err
 		}
+		return err
+	}
 
-		rows, err := tx.Query("SELECT inventory_id, quantity FROM menu_item_inventory WHERE menu_id=$1", menu.MenuID)
+	err = insertOrderItemModifiers(tx, orderItemID, item)
+	if err != nil {
+		return err
+	}
+
+	err = setOrderItemCost(tx, orderItemID, item)
+	if err != nil {
+		return err
+	}
+
+	if item.Discount > 0 || item.OrderDiscount > 0 {
+		_, err = tx.Exec("UPDATE order_item SET discount = $1, order_discount = $2 WHERE id = $3",
+			item.Discount, item.OrderDiscount, orderItemID)
 		if err != nil {
-			m.logger.Error(err.Error())
-			return orderID, err
+			return err
 		}
-		defer rows.Close()
+	}
+
+	if item.RewardID != nil {
+		return redeemReward(tx, customerID, orderID, orderItemID, item)
+	}
+	return nil
+}
+
+// setOrderTotals sums the order's lines into its net, tax and gross totals.
+// It runs once the lines are final, discounts and rewards included.
+func setOrderTotals(tx *sql.Tx, orderID int) error {
+	_, err := tx.Exec(`
+		UPDATE orders o SET net_total = t.net, tax_total = t.tax, gross_total = t.net + t.tax
+		FROM (
+			SELECT
+				COALESCE(SUM(line_total - CASE WHEN tax_added THEN 0 ELSE tax END), 0) AS net,
+				COALESCE(SUM(tax), 0) AS tax
+			FROM order_item
+			WHERE order_id = $1
+		) t
+		WHERE o.id = $1`, orderID)
+	return err
+}
+
+// setOrderItemCost stores the current cost of the ingredients one unit of the
+// line uses, modifiers included
+func setOrderItemCost(tx *sql.Tx, orderItemID int, item models.OrderItem) error {
+	perUnit, err := unitUsage(tx, item)
+	if err != nil {
+		return err
+	}
+
+	inventoryIDs := make([]int, 0, len(perUnit))
+	quantities := make([]int, 0, len(perUnit))
+	for inventoryID, quantity := range perUnit {
+		inventoryIDs = append(inventoryIDs, inventoryID)
+		quantities = append(quantities, quantity)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE order_item SET unit_cost = (
+			SELECT COALESCE(SUM(u.quantity * i.unit_cost), 0)
+			FROM unnest($1::int[], $2::int[]) AS u (inventory_id, quantity)
+			JOIN inventory i ON i.id = u.inventory_id
+		)
+		WHERE id = $3`,
+		pq.Array(inventoryIDs), pq.Array(quantities), orderItemID)
+	return err
+}
+
+// insertOrderItemModifiers checks the chosen modifiers against the modifier
+// groups offered on the menu item, stores them on the line and adds their
+// price deltas to the line's unit price
+func insertOrderItemModifiers(tx *sql.Tx, orderItemID int, item models.OrderItem) error {
+	// one row per chosen modifier of each offered group, or a single row
+	// without modifier for groups where nothing was chosen
+	rows, err := tx.Query(`
+		SELECT g.id, g.required, g.max_select, m.id, m.name, m.price_delta
+		FROM menu_item_modifier_groups mg
+		JOIN modifier_groups g ON g.id = mg.group_id
+		LEFT JOIN modifiers m ON m.group_id = g.id AND m.id = ANY($2)
+		WHERE mg.menu_id = $1`,
+		item.MenuID, pq.Array(item.Modifiers))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-		type invUse struct {
-			inventoryID int
-			totalNeeded int
+	type chosenModifier struct {
+		id         int
+		name       string
+		priceDelta float64
+	}
+	var chosen []chosenModifier
+	chosenPerGroup := make(map[int]int)
+	for rows.Next() {
+		var groupID, maxSelect int
+		var required bool
+		var modifierID sql.NullInt32
+		var name sql.NullString
+		var priceDelta sql.NullFloat64
+		if err := rows.Scan(&groupID, &required, &maxSelect, &modifierID, &name, &priceDelta); err != nil {
+			return err
 		}
-		var inventoryList []invUse
-		for rows.Next() {
-			var inventoryID, perItemQuantity int
-			if err := rows.Scan(&inventoryID, &perItemQuantity); err != nil {
-				return orderID, err
+
+		if !modifierID.Valid {
+			if required {
+				return models.ErrMissingRequiredModifier
 			}
-			inventoryList = append(inventoryList, invUse{inventoryID, perItemQuantity * menu.Quantity})
-		}
-
-		for _, item := range inventoryList {
-			_, err := tx.Exec("UPDATE inventory SET quantity = quantity - $1 WHERE id = $2", item.totalNeeded, item.inventoryID)
-			if err != nil {
-				m.logger.Error(err.Error())
-				if pqErr, ok := err.(*pq.Error); ok {
-					switch pqErr.Code {
-					case "23514":
-						return orderID, models.ErrNegativeQuantity
-					}
+			continue
+		}
+
+		chosenPerGroup[groupID]++
+		if chosenPerGroup[groupID] > maxSelect {
+			return models.ErrModifierLimitExceeded
+		}
+		chosen = append(chosen, chosenModifier{int(modifierID.Int32), name.String, priceDelta.Float64})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if len(chosen) != len(item.Modifiers) {
+		return models.ErrInvalidModifier
+	}
+	if len(chosen) == 0 {
+		return nil
+	}
+
+	var totalDelta float64
+	for _, modifier := range chosen {
+		_, err := tx.Exec("INSERT INTO order_item_modifier (order_item_id, modifier_id, name, price_delta) VALUES ($1, $2, $3, $4)",
+			orderItemID, modifier.id, modifier.name, modifier.priceDelta)
+		if err != nil {
+			return err
+		}
+		totalDelta += modifier.priceDelta
+	}
+	if item.KeepPrice {
+		// the kept unit price has the modifiers in it already
+		return nil
+	}
+
+	_, err = tx.Exec("UPDATE order_item SET unit_price = unit_price + $1 WHERE id = $2", totalDelta, orderItemID)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			switch pgErr.Code {
+			case "23514":
+				return models.ErrNegativePrice
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// recipeUsage sums the ingredients consumed by the given order lines, keyed by
+// inventory id. Each unit uses the menu item's recipe adjusted by the chosen
+// modifiers; an ingredient never goes below zero per unit.
+func recipeUsage(tx *sql.Tx, items []models.OrderItem) (map[int]int, error) {
+	usage := make(map[int]int)
+	for _, item := range items {
+		perUnit, err := unitUsage(tx, item)
+		if err != nil {
+			return nil, err
+		}
+
+		for inventoryID, quantity := range perUnit {
+			usage[inventoryID] += quantity * item.Quantity
+		}
+	}
+
+	return usage, nil
+}
+
+// unitUsage returns the ingredients one unit of the order line consumes
+func unitUsage(tx *sql.Tx, item models.OrderItem) (map[int]int, error) {
+	perUnit, err := queryUsage(tx, "SELECT inventory_id, quantity FROM menu_item_inventory WHERE menu_id=$1", item.MenuID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(item.Modifiers) > 0 {
+		modifierDelta, err := queryUsage(tx, `
+			SELECT inventory_id, SUM(quantity) FROM modifier_inventory
+			WHERE modifier_id = ANY($1)
+			GROUP BY inventory_id`, pq.Array(item.Modifiers))
+		if err != nil {
+			return nil, err
+		}
+		for inventoryID, quantity := range modifierDelta {
+			perUnit[inventoryID] = max(perUnit[inventoryID]+quantity, 0)
+		}
+	}
+
+	return perUnit, nil
+}
+
+func queryUsage(tx *sql.Tx, query string, args ...any) (map[int]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[int]int)
+	for rows.Next() {
+		var inventoryID, quantity int
+		if err := rows.Scan(&inventoryID, &quantity); err != nil {
+			return nil, err
+		}
+		usage[inventoryID] += quantity
+	}
+
+	return usage, rows.Err()
+}
+
+// adjustOrderInventory takes the given quantities from stock (or returns them
+// when negative) and records them against the order in order_inventory_usage,
+// so a cancellation can give back exactly what the order consumed.
+// Inventory rows are updated in id order to keep lock ordering consistent.
+func adjustOrderInventory(tx *sql.Tx, orderID int, delta map[int]int) error {
+	inventoryIDs := make([]int, 0, len(delta))
+	for inventoryID := range delta {
+		inventoryIDs = append(inventoryIDs, inventoryID)
+	}
+	sort.Ints(inventoryIDs)
+
+	for _, inventoryID := range inventoryIDs {
+		quantity := delta[inventoryID]
+		if quantity == 0 {
+			continue
+		}
+
+		_, err := tx.Exec("UPDATE inventory SET quantity = quantity - $1 WHERE id = $2", quantity, inventoryID)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok {
+				switch pqErr.Code {
+				case "23514":
+					return models.ErrNegativeQuantity
 				}
-				return orderID, err
 			}
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO order_inventory_usage (order_id, inventory_id, quantity) VALUES ($1, $2, $3)
+			ON CONFLICT (order_id, inventory_id)
+			DO UPDATE SET quantity = order_inventory_usage.quantity + EXCLUDED.quantity`,
+			orderID, inventoryID, quantity)
+		if err != nil {
+			return err
 		}
 	}
 
-	return orderID, tx.Commit()
+	return nil
 }
 
-func (m *orderRepositoryPostgres) RetrieveAll() ([]models.Order, error) {
-	rows, err := m.pq.Query(`
-		SELECT o.id, o.customer_name, o.order_status, o.created_at, o.customer_preferences,
-		       oi.menu_item_id, oi.quantity
-		FROM orders o
+// RetrieveAll returns one page of orders matching the filter, with their items,
+// in the order given by filter.Sort, along with the total number of pages.
+// sortColumn must already be a safe column expression.
+func (m *orderRepositoryPostgres) RetrieveAll(filter models.OrderFilter, sortColumn string, descending bool) ([]models.Order, int, error) {
+	var queryArgs []any
+	var conditions []string
+	if filter.Status != "" {
+		queryArgs = append(queryArgs, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("o.order_status = $%v", len(queryArgs)))
+	}
+	if filter.Customer != "" {
+		queryArgs = append(queryArgs, filter.Customer)
+		conditions = append(conditions, fmt.Sprintf("o.customer_name = $%v", len(queryArgs)))
+	}
+	if filter.CustomerID != 0 {
+		queryArgs = append(queryArgs, filter.CustomerID)
+		conditions = append(conditions, fmt.Sprintf("o.customer_id = $%v", len(queryArgs)))
+	}
+	if filter.From != "" {
+		queryArgs = append(queryArgs, filter.From)
+		conditions = append(conditions, fmt.Sprintf("o.created_at::date >= $%v", len(queryArgs)))
+	}
+	if filter.To != "" {
+		queryArgs = append(queryArgs, filter.To)
+		conditions = append(conditions, fmt.Sprintf("o.created_at::date <= $%v", len(queryArgs)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var totalOrders int
+	err := m.pq.QueryRow("SELECT COUNT(*) FROM orders o "+where, queryArgs...).Scan(&totalOrders)
+	if err != nil {
+		m.logger.Error("Failed to count orders", "error", err)
+		return nil, 0, err
+	}
+	totalPages := (totalOrders + filter.PageSize - 1) / filter.PageSize
+
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	orderBy := fmt.Sprintf("%s %s, o.id %s", sortColumn, direction, direction)
+
+	queryArgs = append(queryArgs, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	rows, err := m.pq.Query(fmt.Sprintf(`
+		SELECT o.id, o.customer_name, o.customer_id, o.order_status, o.created_at, o.customer_preferences, o.cancel_reason,
+		       COALESCE(o.promo_code, ''), o.discount, o.takeaway, o.net_total, o.tax_total, o.gross_total,
+		       COALESCE(o.override_by, ''),
+		       oi.menu_item_id, oi.quantity, oi.unit_price, oi.item_name, oi.discount, oi.order_discount, oi.reward_id,
+		       oi.tax_class, oi.tax_rate, oi.tax,
+		       ARRAY(SELECT oim.modifier_id FROM order_item_modifier oim
+		             WHERE oim.order_item_id = oi.id AND oim.modifier_id IS NOT NULL
+		             ORDER BY oim.modifier_id) AS modifiers
+		FROM (
+			SELECT * FROM orders o
+			%s
+			ORDER BY %s
+			LIMIT $%v OFFSET $%v
+		) o
 		LEFT JOIN order_item oi ON o.id = oi.order_id
-		ORDER BY o.id
-	`)
+		ORDER BY %s
+	`, where, orderBy, len(queryArgs)-1, len(queryArgs), orderBy), queryArgs...)
 	if err != nil {
 		m.logger.Error("Failed to execute order query", "error", err)
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	orderMap := make(map[int]*models.Order)
+	orders := []models.Order{}
+	orderIndex := make(map[int]int)
 
 	for rows.Next() {
 		var (
-			orderID      int
-			customerName string
-			status       string
-			createdAt    time.Time
-			prefsBytes   []byte
-			menuItemID   sql.NullInt32
-			quantity     sql.NullInt32
+			orderID       int
+			customerName  string
+			customerID    sql.NullInt32
+			status        string
+			createdAt     time.Time
+			prefsBytes    []byte
+			cancelReason  sql.NullString
+			promoCode     string
+			discount      float64
+			takeaway      bool
+			netTotal      float64
+			taxTotal      float64
+			grossTotal    float64
+			overrideBy    string
+			menuItemID    sql.NullInt32
+			quantity      sql.NullInt32
+			unitPrice     sql.NullFloat64
+			itemName      sql.NullString
+			lineDiscount  sql.NullFloat64
+			orderDiscount sql.NullFloat64
+			rewardID      sql.NullInt32
+			taxClass      sql.NullString
+			taxRate       sql.NullFloat64
+			tax           sql.NullFloat64
+			modifiers     pq.Int64Array
 		)
 
-		err := rows.Scan(&orderID, &customerName, &status, &createdAt, &prefsBytes, &menuItemID, &quantity)
+		err := rows.Scan(&orderID, &customerName, &customerID, &status, &createdAt, &prefsBytes, &cancelReason,
+			&promoCode, &discount, &takeaway, &netTotal, &taxTotal, &grossTotal, &overrideBy,
+			&menuItemID, &quantity, &unitPrice, &itemName, &lineDiscount, &orderDiscount, &rewardID,
+			&taxClass, &taxRate, &tax, &modifiers)
 		if err != nil {
 			m.logger.Error("Failed to scan order row", "error", err)
-			return nil, err
+			return nil, 0, err
 		}
 
-		if _, ok := orderMap[orderID]; !ok {
+		idx, ok := orderIndex[orderID]
+		if !ok {
 			var prefs models.Jsonb
 			if err := json.Unmarshal(prefsBytes, &prefs); err != nil {
 				m.logger.Error("Failed to unmarshal customer_preferences", "error", err)
-				return nil, err
+				return nil, 0, err
 			}
 
-			orderMap[orderID] = &models.Order{
+			idx = len(orders)
+			orderIndex[orderID] = idx
+			orders = append(orders, models.Order{
 				ID:                  orderID,
 				CustomerName:        customerName,
+				CustomerID:          nullIntPtr(customerID),
 				Status:              status,
 				CreatedAt:           createdAt,
 				CustomerPreferences: prefs,
+				CancelReason:        cancelReason.String,
+				PromoCode:           promoCode,
+				Discount:            discount,
+				Takeaway:            takeaway,
+				NetTotal:            netTotal,
+				TaxTotal:            taxTotal,
+				GrossTotal:          grossTotal,
+				OverrideBy:          overrideBy,
 				Items:               []models.OrderItem{},
-			}
+			})
 		}
 
 		if menuItemID.Valid {
-			orderMap[orderID].Items = append(orderMap[orderID].Items, models.OrderItem{
-				MenuID:   int(menuItemID.Int32),
-				Quantity: int(quantity.Int32),
+			orders[idx].Items = append(orders[idx].Items, models.OrderItem{
+				MenuID:        int(menuItemID.Int32),
+				Quantity:      int(quantity.Int32),
+				Modifiers:     intSlice(modifiers),
+				RewardID:      nullIntPtr(rewardID),
+				UnitPrice:     unitPrice.Float64,
+				ItemName:      itemName.String,
+				Discount:      lineDiscount.Float64,
+				OrderDiscount: orderDiscount.Float64,
+				TaxClass:      taxClass.String,
+				TaxRate:       taxRate.Float64,
+				Tax:           tax.Float64,
 			})
 		}
 	}
 
-	var orders []models.Order
-	for _, order := range orderMap {
-		orders = append(orders, *order)
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
 	}
 
-	return orders, nil
+	return orders, totalPages, nil
 }
 
 func (m *orderRepositoryPostgres) RetrieveByID(id int) (models.Order, error) {
 	rows, err := m.pq.Query(`
-		SELECT o.id, o.customer_name, o.order_status, o.created_at, o.customer_preferences,
-		       oi.menu_item_id, oi.quantity
+		SELECT o.id, o.customer_name, o.customer_id, o.order_status, o.created_at, o.customer_preferences, o.cancel_reason,
+		       COALESCE(o.promo_code, ''), o.discount, o.takeaway, o.net_total, o.tax_total, o.gross_total,
+		       COALESCE(o.override_by, ''),
+		       oi.menu_item_id, oi.quantity, oi.unit_price, oi.item_name, oi.discount, oi.order_discount, oi.reward_id,
+		       oi.tax_class, oi.tax_rate, oi.tax,
+		       ARRAY(SELECT oim.modifier_id FROM order_item_modifier oim
+		             WHERE oim.order_item_id = oi.id AND oim.modifier_id IS NOT NULL
+		             ORDER BY oim.modifier_id) AS modifiers
 		FROM "orders" o
 		LEFT JOIN order_item oi ON o.id = oi.order_id
 		WHERE o.id = $1
@@ -193,16 +580,37 @@ func (m *orderRepositoryPostgres) RetrieveByID(id int) (models.Order, error) {
 	var order models.Order
 	for rows.Next() {
 		var (
-			orderID      int
-			customerName string
-			status       string
-			createdAt    time.Time
-			prefsBytes   []byte
-			menuItemID   sql.NullInt32
-			quantity     sql.NullInt32
+			orderID       int
+			customerName  string
+			customerID    sql.NullInt32
+			status        string
+			createdAt     time.Time
+			prefsBytes    []byte
+			cancelReason  sql.NullString
+			promoCode     string
+			discount      float64
+			takeaway      bool
+			netTotal      float64
+			taxTotal      float64
+			grossTotal    float64
+			overrideBy    string
+			menuItemID    sql.NullInt32
+			quantity      sql.NullInt32
+			unitPrice     sql.NullFloat64
+			itemName      sql.NullString
+			lineDiscount  sql.NullFloat64
+			orderDiscount sql.NullFloat64
+			rewardID      sql.NullInt32
+			taxClass      sql.NullString
+			taxRate       sql.NullFloat64
+			tax           sql.NullFloat64
+			modifiers     pq.Int64Array
 		)
 
-		err := rows.Scan(&orderID, &customerName, &status, &createdAt, &prefsBytes, &menuItemID, &quantity)
+		err := rows.Scan(&orderID, &customerName, &customerID, &status, &createdAt, &prefsBytes, &cancelReason,
+			&promoCode, &discount, &takeaway, &netTotal, &taxTotal, &grossTotal, &overrideBy,
+			&menuItemID, &quantity, &unitPrice, &itemName, &lineDiscount, &orderDiscount, &rewardID,
+			&taxClass, &taxRate, &tax, &modifiers)
 		if err != nil {
 			m.logger.Error("Failed to scan order row", "error", err)
 			return models.Order{}, err
@@ -218,17 +626,35 @@ func (m *orderRepositoryPostgres) RetrieveByID(id int) (models.Order, error) {
 			order = models.Order{
 				ID:                  orderID,
 				CustomerName:        customerName,
+				CustomerID:          nullIntPtr(customerID),
 				Status:              status,
 				CreatedAt:           createdAt,
 				CustomerPreferences: prefs,
+				CancelReason:        cancelReason.String,
+				PromoCode:           promoCode,
+				Discount:            discount,
+				Takeaway:            takeaway,
+				NetTotal:            netTotal,
+				TaxTotal:            taxTotal,
+				GrossTotal:          grossTotal,
+				OverrideBy:          overrideBy,
 				Items:               []models.OrderItem{},
 			}
 		}
 
 		if menuItemID.Valid {
 			order.Items = append(order.Items, models.OrderItem{
-				MenuID:   int(menuItemID.Int32),
-				Quantity: int(quantity.Int32),
+				MenuID:        int(menuItemID.Int32),
+				Quantity:      int(quantity.Int32),
+				Modifiers:     intSlice(modifiers),
+				RewardID:      nullIntPtr(rewardID),
+				UnitPrice:     unitPrice.Float64,
+				ItemName:      itemName.String,
+				Discount:      lineDiscount.Float64,
+				OrderDiscount: orderDiscount.Float64,
+				TaxClass:      taxClass.String,
+				TaxRate:       taxRate.Float64,
+				Tax:           tax.Float64,
 			})
 		}
 	}
@@ -256,15 +682,18 @@ func (m *orderRepositoryPostgres) Update(orderID int, order models.Order) error
 
 	result, err := tx.Exec(`
 		UPDATE orders
-		SET customer_name = $1, customer_preferences = $2
-		WHERE id = $3 AND order_status=$4
-	`, order.CustomerName, prefsJSON, orderID, "open")
+		SET customer_name = $1, customer_id = $2, customer_preferences = $3, promo_code = NULLIF($4, ''), discount = $5,
+			takeaway = $6
+		WHERE id = $7 AND order_status=$8
+	`, order.CustomerName, order.CustomerID, prefsJSON, order.PromoCode, order.Discount, order.Takeaway, orderID, "open")
 	if err != nil {
 		m.logger.Error(err.Error())
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code {
 			case "23505":
 				return models.ErrDuplicateOrder
+			case "23503":
+				return models.ErrForeignKeyConstraintCustomer
 			}
 		}
 		m.logger.Error("Failed to update order", "error", err)
@@ -280,6 +709,23 @@ func (m *orderRepositoryPostgres) Update(orderID int, order models.Order) error
 		return models.ErrNoRecord
 	}
 
+	// payments were made against the old total
+	paid, err := hasPayments(tx, orderID)
+	if err != nil {
+		return err
+	}
+	if paid {
+		return models.ErrOrderHasPayments
+	}
+
+	// rewards redeemed on the old lines go back to the customer and are
+	// redeemed again for the new lines
+	err = reverseLoyalty(tx, orderID, models.LoyaltyEventRedeem, "order updated")
+	if err != nil {
+		m.logger.Error("Failed to reverse loyalty redemptions", "error", err)
+		return err
+	}
+
 	_, err = tx.Exec("DELETE FROM order_item WHERE order_id = $1", orderID)
 	if err != nil {
 		m.logger.Error("Failed to delete order items", "error", err)
@@ -287,45 +733,201 @@ func (m *orderRepositoryPostgres) Update(orderID int, order models.Order) error
 	}
 
 	for _, item := range order.Items {
-		_, err = tx.Exec(
-			"INSERT INTO order_item (order_id, menu_item_id, quantity) VALUES ($1, $2, $3)",
-			orderID, item.MenuID, item.Quantity,
-		)
+		err = insertOrderItem(tx, order.CustomerID, orderID, item)
 		if err != nil {
-			m.logger.Error(err.Error())
-			if pgErr, ok := err.(*pq.Error); ok {
-				switch pgErr.Code {
-				case "23503":
-					return models.ErrForeignKeyConstraintOrderMenu
-				case "23514":
-					return models.ErrNegativeQuantity
-				}
-			}
 			m.logger.Error("Failed to insert order item", "menu_id", item.MenuID, "error", err)
 			return err
 		}
 	}
 
+	err = setOrderTotals(tx, orderID)
+	if err != nil {
+		m.logger.Error("Failed to total order", "error", err)
+		return err
+	}
+
+	err = recordPromotions(tx, orderID, order.Promotions)
+	if err != nil {
+		m.logger.Error("Failed to record promotions", "error", err)
+		return err
+	}
+
+	// only the difference between what the order already consumed and what
+	// the new items need is taken from (or returned to) the inventory
+	oldUsage, err := recordedUsage(tx, orderID)
+	if err != nil {
+		m.logger.Error("Failed to retrieve order inventory usage", "error", err)
+		return err
+	}
+
+	delta, err := recipeUsage(tx, order.Items)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return err
+	}
+	for inventoryID, quantity := range oldUsage {
+		delta[inventoryID] -= quantity
+	}
+
+	err = setInventoryReason(tx, models.InventoryReasonOrderUpdate, orderReference(orderID))
+	if err != nil {
+		return err
+	}
+
+	err = adjustOrderInventory(tx, orderID, delta)
+	if err != nil {
+		m.logger.Error("Failed to adjust inventory", "error", err)
+		return err
+	}
+
 	return tx.Commit()
 }
 
+// PriceLines returns the order lines with the current menu price, modifiers
+// included, and category of each, for pricing before the order is stored.
+// Lines that keep their price are priced at their UnitPrice.
+func (m *orderRepositoryPostgres) PriceLines(items []models.OrderItem) ([]models.PricedLine, error) {
+	lines := make([]models.PricedLine, 0, len(items))
+	for _, item := range items {
+		line := models.PricedLine{MenuID: item.MenuID, Quantity: item.Quantity}
+		err := m.pq.QueryRow(`
+			SELECT CASE WHEN $3 THEN $4
+			            ELSE mi.price + COALESCE((SELECT SUM(price_delta) FROM modifiers WHERE id = ANY($2)), 0)
+			       END,
+			       COALESCE(mi.category_id, 0)
+			FROM menu_items mi
+			WHERE mi.id = $1`, item.MenuID, pq.Array(item.Modifiers), item.KeepPrice, item.UnitPrice).
+			Scan(&line.UnitPrice, &line.CategoryID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, models.ErrForeignKeyConstraintOrderMenu
+			}
+			m.logger.Error("Failed to price order line", "menu_id", item.MenuID, "error", err)
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// recordedUsage returns the ingredients taken from inventory for the order so far
+func recordedUsage(tx *sql.Tx, orderID int) (map[int]int, error) {
+	return queryUsage(tx, "SELECT inventory_id, quantity FROM order_inventory_usage WHERE order_id=$1", orderID)
+}
+
+// Delete removes the order unless payments were ever taken for it. Payments
+// that still count must be voided first, and voided ones are kept as records,
+// so such an order can only be cancelled.
 func (m *orderRepositoryPostgres) Delete(id int) error {
-	result, err := m.pq.Exec("DELETE FROM orders WHERE id=$1", id)
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the order so no payment is taken while it is deleted
+	_, _, err = orderDue(tx, id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNoRecord) {
+			m.logger.Error("Failed to lock order", "error", err)
+		}
+		return err
+	}
+
+	paid, err := hasPayments(tx, id)
+	if err != nil {
+		m.logger.Error("Failed to check order payments", "error", err)
+		return err
+	}
+	if paid {
+		return models.ErrOrderHasPayments
+	}
+
+	var voided bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM payments WHERE order_id = $1)", id).Scan(&voided)
+	if err != nil {
+		m.logger.Error("Failed to check voided payments", "error", err)
+		return err
+	}
+	if voided {
+		return models.ErrPaymentsOnRecord
+	}
+
+	_, err = tx.Exec("DELETE FROM orders WHERE id=$1", id)
 	if err != nil {
 		m.logger.Error("Failed to execute query", "error", err)
 		return err
 	}
 
+	return tx.Commit()
+}
+
+func (m *orderRepositoryPostgres) RetrieveStatus(id int) (string, error) {
+	var status string
+	err := m.pq.QueryRow("SELECT order_status FROM orders WHERE id=$1", id).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", models.ErrNoRecord
+		}
+		m.logger.Error("Failed to retrieve order status", "error", err)
+		return "", err
+	}
+
+	return status, nil
+}
+
+// UpdateStatus moves the order from one status to another. The update only
+// applies while the order is still in status from, so a concurrent change is
+// reported as a transition error instead of being silently overwritten.
+func (m *orderRepositoryPostgres) UpdateStatus(id int, from, to string) error {
+	result, err := m.pq.Exec(`UPDATE orders SET order_status=$1 WHERE id=$2 AND order_status=$3`, to, id, from)
+	if err != nil {
+		m.logger.Error("Failed to update order status", "error", err)
+		return err
+	}
+
 	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		m.logger.Error("Failed to check rows affected", "error", err)
+		return err
+	}
 	if rowsAffected == 0 {
-		return models.ErrNoRecord
+		current, err := m.RetrieveStatus(id)
+		if err != nil {
+			return err
+		}
+		return &models.OrderStatusTransitionError{From: current, To: to}
 	}
 
-	return err
+	return nil
 }
 
-func (m *orderRepositoryPostgres) Close(id int) error {
-	result, err := m.pq.Exec(`UPDATE orders SET order_status=$1 WHERE id=$2`, "closed", id)
+// Close moves the order from status from to closed and, in the same
+// transaction, credits its customer's loyalty balance. An order closed again
+// after a reopen first gives back what it earned the last time. An order that
+// is not fully paid only closes when overrideBy names the manager allowing it.
+func (m *orderRepositoryPostgres) Close(id int, from string, overrideBy string) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, due, err := orderDue(tx, id)
+	if err != nil {
+		return err
+	}
+	if due <= 0 {
+		overrideBy = ""
+	} else if overrideBy == "" {
+		return fmt.Errorf("%w: %.2f due", models.ErrOrderNotPaid, due)
+	}
+
+	result, err := tx.Exec(`UPDATE orders SET order_status=$1, override_by=NULLIF($2, '') WHERE id=$3 AND order_status=$4`,
+		models.OrderStatusClosed, overrideBy, id, from)
 	if err != nil {
 		m.logger.Error("Failed to close order", "error", err)
 		return err
@@ -337,10 +939,174 @@ func (m *orderRepositoryPostgres) Close(id int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return models.ErrNoRecord
+		current, err := m.RetrieveStatus(id)
+		if err != nil {
+			return err
+		}
+		return &models.OrderStatusTransitionError{From: current, To: models.OrderStatusClosed}
 	}
 
-	return nil
+	err = reverseLoyalty(tx, id, models.LoyaltyEventEarn, "order closed again")
+	if err != nil {
+		m.logger.Error("Failed to reverse loyalty earnings", "error", err)
+		return err
+	}
+
+	err = earnLoyalty(tx, id)
+	if err != nil {
+		m.logger.Error("Failed to earn loyalty", "order_id", id, "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel moves the order from status from to cancelled and, in the same
+// transaction, returns to stock every ingredient the order consumed and
+// reverses its loyalty earnings and redemptions. Its payments must be voided
+// first.
+func (m *orderRepositoryPostgres) Cancel(id int, from, reason string) ([]models.InventoryRestock, error) {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE orders SET order_status=$1, cancel_reason=$2 WHERE id=$3 AND order_status=$4`,
+		models.OrderStatusCancelled, reason, id, from)
+	if err != nil {
+		m.logger.Error("Failed to cancel order", "error", err)
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		m.logger.Error("Failed to check rows affected", "error", err)
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		current, err := m.RetrieveStatus(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &models.OrderStatusTransitionError{From: current, To: models.OrderStatusCancelled}
+	}
+
+	paid, err := hasPayments(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if paid {
+		return nil, models.ErrOrderHasPayments
+	}
+
+	rows, err := tx.Query(`
+		SELECT inventory_id, quantity FROM order_inventory_usage
+		WHERE order_id=$1 AND quantity > 0
+		ORDER BY inventory_id`, id)
+	if err != nil {
+		m.logger.Error("Failed to retrieve order inventory usage", "error", err)
+		return nil, err
+	}
+
+	restocked := []models.InventoryRestock{}
+	for rows.Next() {
+		var restock models.InventoryRestock
+		if err := rows.Scan(&restock.InventoryID, &restock.Quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		restocked = append(restocked, restock)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, event := range []string{models.LoyaltyEventEarn, models.LoyaltyEventRedeem} {
+		err = reverseLoyalty(tx, id, event, "order cancelled")
+		if err != nil {
+			m.logger.Error("Failed to reverse loyalty", "event", event, "error", err)
+			return nil, err
+		}
+	}
+
+	err = setInventoryReason(tx, models.InventoryReasonOrderCancel, orderReference(id))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, restock := range restocked {
+		err := tx.QueryRow("UPDATE inventory SET quantity = quantity + $1 WHERE id = $2 RETURNING name, quantity",
+			restock.Quantity, restock.InventoryID).
+			Scan(&restocked[i].Name, &restocked[i].Remaining)
+		if err != nil {
+			m.logger.Error("Failed to restock inventory", "inventory_id", restock.InventoryID, "error", err)
+			return nil, err
+		}
+	}
+
+	return restocked, tx.Commit()
+}
+
+// StatusHistory reads the status changes recorded by the after_order_status_update
+// trigger. The time spent in the old status is measured from the previous change,
+// or from the order creation for the first one, before any filter is applied.
+func (m *orderRepositoryPostgres) StatusHistory(filter models.OrderStatusHistoryFilter) ([]models.OrderStatusHistory, error) {
+	var queryArgs []any
+	var conditions []string
+	if filter.OrderID != 0 {
+		queryArgs = append(queryArgs, filter.OrderID)
+		conditions = append(conditions, fmt.Sprintf("order_id = $%v", len(queryArgs)))
+	}
+	if filter.From != "" {
+		queryArgs = append(queryArgs, filter.From)
+		conditions = append(conditions, fmt.Sprintf("updated_at::date >= $%v", len(queryArgs)))
+	}
+	if filter.To != "" {
+		queryArgs = append(queryArgs, filter.To)
+		conditions = append(conditions, fmt.Sprintf("updated_at::date <= $%v", len(queryArgs)))
+	}
+	if filter.Status != "" {
+		queryArgs = append(queryArgs, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("(old_status = $%[1]v OR new_status = $%[1]v)", len(queryArgs)))
+	}
+
+	query := `
+		SELECT id, order_id, old_status, new_status, updated_at, seconds_in_old_status
+		FROM (
+			SELECT h.id, h.order_id, h.old_status, h.new_status, h.updated_at,
+			       EXTRACT(EPOCH FROM h.updated_at - COALESCE(
+			           LAG(h.updated_at) OVER (PARTITION BY h.order_id ORDER BY h.updated_at, h.id),
+			           o.created_at)) AS seconds_in_old_status
+			FROM order_status_history h
+			JOIN orders o ON o.id = h.order_id
+		) history`
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	query += "\n\t\tORDER BY updated_at, id"
+
+	rows, err := m.pq.Query(query, queryArgs...)
+	if err != nil {
+		m.logger.Error("Failed to execute status history query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.OrderStatusHistory{}
+	for rows.Next() {
+		var entry models.OrderStatusHistory
+		err := rows.Scan(&entry.ID, &entry.OrderID, &entry.OldStatus, &entry.NewStatus, &entry.UpdatedAt, &entry.SecondsInOldStatus)
+		if err != nil {
+			m.logger.Error("Failed to scan status history row", "error", err)
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+
+	return history, rows.Err()
 }
 
 func (m *orderRepositoryPostgres) NumberOfOrderedItems(startDate string, endDate string) (map[string]int, error) {
@@ -383,10 +1149,9 @@ func (m *orderRepositoryPostgres) NumberOfOrderedItems(startDate string, endDate
 
 func (m *orderRepositoryPostgres) GetBatchTotalOrderPrice(orderID int) (float64, error) {
 	query := `
-		SELECT COALESCE(SUM(oi.quantity * mi.price))
-		FROM order_item oi
-		JOIN menu_items mi ON oi.menu_item_id = mi.id
-		WHERE oi.order_id=$1
+		SELECT gross_total
+		FROM orders
+		WHERE id=$1
 	`
 	var totalOrderPrice float64
 	err := m.pq.QueryRow(query, orderID).Scan(&totalOrderPrice)
@@ -399,13 +1164,12 @@ func (m *orderRepositoryPostgres) GetBatchTotalOrderPrice(orderID int) (float64,
 
 func (m *orderRepositoryPostgres) GetBatchInventoryUpdates(orderIDs []int) ([]models.BatchInventoryUpdate, error) {
 	query := `
-		SELECT inv.id, inv.name, SUM(mii.quantity * oi.quantity) AS total_used, inv.quantity AS remaining
+		SELECT inv.id, inv.name, SUM(u.quantity) AS total_used, inv.quantity AS remaining
 		FROM inventory inv
-		JOIN menu_item_inventory mii ON inv.id = mii.inventory_id
-		JOIN order_item oi ON oi.menu_item_id = mii.menu_id
-		JOIN orders o ON o.id = oi.order_id
-		WHERE o.id = ANY($1)
+		JOIN order_inventory_usage u ON inv.id = u.inventory_id
+		WHERE u.order_id = ANY($1)
 		GROUP BY inv.id, inv.name, inv.quantity
+		ORDER BY inv.id
 	`
 	rows, err := m.pq.Query(query, pq.Array(orderIDs))
 	if err != nil {
@@ -427,3 +1191,20 @@ func (m *orderRepositoryPostgres) GetBatchInventoryUpdates(orderIDs []int) ([]mo
 
 	return updates, nil
 }
+
+// nullIntPtr returns nil for a NULL column
+func nullIntPtr(value sql.NullInt32) *int {
+	if !value.Valid {
+		return nil
+	}
+	id := int(value.Int32)
+	return &id
+}
+
+func intSlice(values []int64) []int {
+	ints := make([]int, len(values))
+	for i, value := range values {
+		ints[i] = int(value)
+	}
+	return ints
+}
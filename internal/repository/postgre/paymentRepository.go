@@ -0,0 +1,207 @@
+package postgre
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"frappuccino/internal/models"
+)
+
+type paymentRepositoryPostgres struct {
+	pq     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPaymentRepositoryPostgres(db *sql.DB, logger *slog.Logger) *paymentRepositoryPostgres {
+	return &paymentRepositoryPostgres{
+		pq:     db,
+		logger: logger,
+	}
+}
+
+const paymentColumns = `id, order_id, tender, amount, tip, COALESCE(tendered, 0), change_due,
+	COALESCE(reference, ''), created_at, voided_at`
+
+func scanPayment(row interface{ Scan(...any) error }) (models.Payment, error) {
+	var payment models.Payment
+	var voidedAt sql.NullTime
+	err := row.Scan(&payment.ID, &payment.OrderID, &payment.Tender, &payment.Amount, &payment.Tip, &payment.Tendered,
+		&payment.ChangeDue, &payment.Reference, &payment.CreatedAt, &voidedAt)
+	if voidedAt.Valid {
+		payment.VoidedAt = &voidedAt.Time
+	}
+	return payment, err
+}
+
+// orderDue locks the order and returns its status and what is left to pay of
+// its gross total
+func orderDue(tx *sql.Tx, orderID int) (string, float64, error) {
+	var status string
+	var due float64
+	err := tx.QueryRow(`
+		SELECT o.order_status, o.gross_total - COALESCE((
+			SELECT SUM(p.amount) FROM payments p
+			WHERE p.order_id = o.id AND p.voided_at IS NULL
+		), 0)
+		FROM orders o
+		WHERE o.id = $1
+		FOR UPDATE`, orderID).
+		Scan(&status, &due)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, models.ErrNoRecord
+	}
+	return status, due, err
+}
+
+// hasPayments reports whether the order has payments that are not voided
+func hasPayments(tx *sql.Tx, orderID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM payments WHERE order_id = $1 AND voided_at IS NULL)", orderID).
+		Scan(&exists)
+	return exists, err
+}
+
+// payable checks that an order in status may take or void payments
+func payable(status string) error {
+	if status != models.OrderStatusOpen && status != models.OrderStatusInProgress {
+		return models.ErrOrderNotPayable
+	}
+	return nil
+}
+
+// Insert stores a payment unless it is more than the order's amount due
+func (m *paymentRepositoryPostgres) Insert(payment models.Payment) (models.Payment, error) {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return models.Payment{}, err
+	}
+	defer tx.Rollback()
+
+	status, due, err := orderDue(tx, payment.OrderID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if err := payable(status); err != nil {
+		return models.Payment{}, err
+	}
+	if due <= 0 {
+		return models.Payment{}, models.ErrOrderAlreadyPaid
+	}
+	if payment.Amount > due {
+		return models.Payment{}, models.ErrOverpayment
+	}
+
+	var tendered *float64
+	if payment.Tender == models.PaymentTenderCash {
+		tendered = &payment.Tendered
+	}
+	stored, err := scanPayment(tx.QueryRow(`
+		INSERT INTO payments (order_id, tender, amount, tip, tendered, change_due, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+		RETURNING `+paymentColumns,
+		payment.OrderID, payment.Tender, payment.Amount, payment.Tip, tendered, payment.ChangeDue, payment.Reference))
+	if err != nil {
+		m.logger.Error("Failed to insert payment", "error", err)
+		return models.Payment{}, err
+	}
+
+	return stored, tx.Commit()
+}
+
+// RetrieveByOrder returns the order's bill with its payments, oldest first
+func (m *paymentRepositoryPostgres) RetrieveByOrder(orderID int) (models.OrderPayments, error) {
+	bill := models.OrderPayments{OrderID: orderID, Payments: []models.Payment{}}
+	err := m.pq.QueryRow("SELECT order_status, gross_total FROM orders WHERE id = $1", orderID).
+		Scan(&bill.Status, &bill.GrossTotal)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OrderPayments{}, models.ErrNoRecord
+		}
+		m.logger.Error("Failed to retrieve order", "error", err)
+		return models.OrderPayments{}, err
+	}
+
+	rows, err := m.pq.Query("SELECT "+paymentColumns+" FROM payments WHERE order_id = $1 ORDER BY created_at, id", orderID)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return models.OrderPayments{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return models.OrderPayments{}, err
+		}
+		bill.Payments = append(bill.Payments, payment)
+	}
+
+	return bill, rows.Err()
+}
+
+func (m *paymentRepositoryPostgres) RetrieveByID(orderID, id int) (models.Payment, error) {
+	payment, err := scanPayment(m.pq.QueryRow(
+		"SELECT "+paymentColumns+" FROM payments WHERE id = $1 AND order_id = $2", id, orderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Payment{}, models.ErrNoRecord
+		}
+		m.logger.Error("Failed to retrieve payment", "error", err)
+		return models.Payment{}, err
+	}
+
+	return payment, nil
+}
+
+// Void marks a payment as no longer counting toward the order. The order and
+// the payment stay locked while refund runs, and the void is only committed
+// once refund succeeds, so a payment is never refunded twice.
+func (m *paymentRepositoryPostgres) Void(orderID, id int, refund func(models.Payment) error) (models.Payment, error) {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return models.Payment{}, err
+	}
+	defer tx.Rollback()
+
+	status, _, err := orderDue(tx, orderID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if err := payable(status); err != nil {
+		return models.Payment{}, err
+	}
+
+	payment, err := scanPayment(tx.QueryRow(
+		"SELECT "+paymentColumns+" FROM payments WHERE id = $1 AND order_id = $2 FOR UPDATE", id, orderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Payment{}, models.ErrNoRecord
+		}
+		m.logger.Error("Failed to retrieve payment", "error", err)
+		return models.Payment{}, err
+	}
+	if payment.VoidedAt != nil {
+		return models.Payment{}, models.ErrPaymentVoided
+	}
+
+	payment, err = scanPayment(tx.QueryRow(
+		"UPDATE payments SET voided_at = now() WHERE id = $1 RETURNING "+paymentColumns, id))
+	if err != nil {
+		m.logger.Error("Failed to void payment", "error", err)
+		return models.Payment{}, err
+	}
+
+	if err := refund(payment); err != nil {
+		return models.Payment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		m.logger.Error("Failed to commit voided payment after refund", "payment_id", id, "error", err)
+		return models.Payment{}, err
+	}
+	return payment, nil
+}
@@ -0,0 +1,219 @@
+package postgre
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type promotionRepositoryPostgres struct {
+	pq     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPromotionRepositoryPostgres(db *sql.DB, logger *slog.Logger) *promotionRepositoryPostgres {
+	return &promotionRepositoryPostgres{
+		pq:     db,
+		logger: logger,
+	}
+}
+
+// promotionColumns selects a promotion with the number of orders, cancelled
+// ones aside, that used it
+const promotionColumns = `
+	p.id, p.name, p.kind, p.value, p.menu_item_id, p.category_id, COALESCE(p.code, ''), p.usage_limit,
+	(SELECT COUNT(*) FROM order_promotions op JOIN orders o ON o.id = op.order_id
+	 WHERE op.promotion_id = p.id AND o.order_status <> 'cancelled'),
+	p.starts_at, p.expires_at,
+	COALESCE(to_char(p.daily_from, 'HH24:MI'), ''), COALESCE(to_char(p.daily_to, 'HH24:MI'), ''),
+	p.buy_quantity, p.get_quantity`
+
+func scanPromotion(row interface{ Scan(...any) error }) (models.Promotion, error) {
+	var promotion models.Promotion
+	var menuID, categoryID, usageLimit sql.NullInt32
+	var startsAt, expiresAt sql.NullTime
+	err := row.Scan(&promotion.ID, &promotion.Name, &promotion.Kind, &promotion.Value, &menuID, &categoryID,
+		&promotion.Code, &usageLimit, &promotion.TimesUsed, &startsAt, &expiresAt,
+		&promotion.DailyFrom, &promotion.DailyTo, &promotion.BuyQuantity, &promotion.GetQuantity)
+	promotion.MenuID = nullIntPtr(menuID)
+	promotion.CategoryID = nullIntPtr(categoryID)
+	promotion.UsageLimit = nullIntPtr(usageLimit)
+	promotion.StartsAt = nullTimePtr(startsAt)
+	promotion.ExpiresAt = nullTimePtr(expiresAt)
+	return promotion, err
+}
+
+func nullTimePtr(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+// promotionError maps constraint violations on promotions to model errors
+func promotionError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case "23505":
+			return models.ErrDuplicatePromoCode
+		case "23503":
+			if pqErr.Constraint == "promotions_category_id_fkey" {
+				return models.ErrForeignKeyConstraintMenuCategory
+			}
+			return models.ErrForeignKeyConstraintOrderMenu
+		}
+	}
+	return err
+}
+
+func (m *promotionRepositoryPostgres) Insert(promotion models.Promotion) (int, error) {
+	var id int
+	err := m.pq.QueryRow(`
+		INSERT INTO promotions (name, kind, value, menu_item_id, category_id, code, usage_limit,
+			starts_at, expires_at, daily_from, daily_to, buy_quantity, get_quantity)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, NULLIF($10, '')::time, NULLIF($11, '')::time, $12, $13)
+		RETURNING id`,
+		promotion.Name, promotion.Kind, promotion.Value, promotion.MenuID, promotion.CategoryID, promotion.Code,
+		promotion.UsageLimit, promotion.StartsAt, promotion.ExpiresAt, promotion.DailyFrom, promotion.DailyTo,
+		promotion.BuyQuantity, promotion.GetQuantity).
+		Scan(&id)
+	if err != nil {
+		m.logger.Error("Failed to insert promotion", "error", err)
+		return 0, promotionError(err)
+	}
+
+	return id, nil
+}
+
+func (m *promotionRepositoryPostgres) RetrieveAll() ([]models.Promotion, error) {
+	return m.retrieve("SELECT " + promotionColumns + " FROM promotions p ORDER BY p.id")
+}
+
+// RetrieveForPricing returns the promotions without a code, and the one with
+// the given code if there is one
+func (m *promotionRepositoryPostgres) RetrieveForPricing(code string) ([]models.Promotion, error) {
+	return m.retrieve("SELECT "+promotionColumns+" FROM promotions p WHERE p.code IS NULL OR upper(p.code) = upper($1) ORDER BY p.id", code)
+}
+
+func (m *promotionRepositoryPostgres) retrieve(query string, args ...any) ([]models.Promotion, error) {
+	rows, err := m.pq.Query(query, args...)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []models.Promotion{}
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, rows.Err()
+}
+
+func (m *promotionRepositoryPostgres) RetrieveByID(id int) (models.Promotion, error) {
+	promotion, err := scanPromotion(m.pq.QueryRow("SELECT "+promotionColumns+" FROM promotions p WHERE p.id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Promotion{}, models.ErrNoRecord
+		}
+		return models.Promotion{}, err
+	}
+
+	return promotion, nil
+}
+
+func (m *promotionRepositoryPostgres) Update(id int, promotion models.Promotion) error {
+	result, err := m.pq.Exec(`
+		UPDATE promotions
+		SET name = $1, kind = $2, value = $3, menu_item_id = $4, category_id = $5, code = NULLIF($6, ''),
+			usage_limit = $7, starts_at = $8, expires_at = $9, daily_from = NULLIF($10, '')::time,
+			daily_to = NULLIF($11, '')::time, buy_quantity = $12, get_quantity = $13
+		WHERE id = $14`,
+		promotion.Name, promotion.Kind, promotion.Value, promotion.MenuID, promotion.CategoryID, promotion.Code,
+		promotion.UsageLimit, promotion.StartsAt, promotion.ExpiresAt, promotion.DailyFrom, promotion.DailyTo,
+		promotion.BuyQuantity, promotion.GetQuantity, id)
+	if err != nil {
+		m.logger.Error("Failed to update promotion", "error", err)
+		return promotionError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// Delete removes the promotion; orders keep the discounts it gave them
+func (m *promotionRepositoryPostgres) Delete(id int) error {
+	result, err := m.pq.Exec("DELETE FROM promotions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// recordPromotions stores the promotions applied to the order in place of any
+// stored before. A promotion with a usage limit is locked while its uses by
+// other orders are counted, so concurrent orders cannot both take the last use.
+func recordPromotions(tx *sql.Tx, orderID int, applied []models.AppliedPromotion) error {
+	_, err := tx.Exec("DELETE FROM order_promotions WHERE order_id = $1", orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, promotion := range applied {
+		var usageLimit sql.NullInt32
+		err := tx.QueryRow("SELECT usage_limit FROM promotions WHERE id = $1 FOR UPDATE", promotion.PromotionID).
+			Scan(&usageLimit)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.ErrInvalidPromoCode
+			}
+			return err
+		}
+
+		if usageLimit.Valid {
+			var used int
+			err := tx.QueryRow(`
+				SELECT COUNT(*) FROM order_promotions op
+				JOIN orders o ON o.id = op.order_id
+				WHERE op.promotion_id = $1 AND op.order_id <> $2 AND o.order_status <> 'cancelled'`,
+				promotion.PromotionID, orderID).
+				Scan(&used)
+			if err != nil {
+				return err
+			}
+			if used >= int(usageLimit.Int32) {
+				return models.ErrPromoCodeLimitReached
+			}
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO order_promotions (order_id, promotion_id, name, code, amount)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5)`,
+			orderID, promotion.PromotionID, promotion.Name, promotion.Code, promotion.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
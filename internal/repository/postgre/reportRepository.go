@@ -4,10 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
-	"strconv"
+	"strings"
 
 	"frappuccino/internal/models"
-	"frappuccino/internal/utils"
 
 	"github.com/lib/pq"
 )
@@ -24,18 +23,65 @@ func NewReportRepositoryPostgres(db *sql.DB, logger *slog.Logger) *reportReposit
 	}
 }
 
-func (m *reportRepositoryPostgres) GetTotalSales() (models.ReportTotalSales, error) {
+// reportGroupExpressions are the SQL keys closed orders are grouped by for each
+// report grouping; they sort in time order as text
+var reportGroupExpressions = map[string]string{
+	"":                           "''::text",
+	models.ReportGroupByHour:     "to_char(o.created_at, 'YYYY-MM-DD HH24:00')",
+	models.ReportGroupByDay:      "to_char(o.created_at, 'YYYY-MM-DD')",
+	models.ReportGroupByWeek:     "to_char(date_trunc('week', o.created_at), 'YYYY-MM-DD')",
+	models.ReportGroupByMonth:    "to_char(o.created_at, 'YYYY-MM')",
+	models.ReportGroupByCategory: "COALESCE(mc.name, '')",
+}
+
+// reportConditions returns the WHERE conditions and their arguments selecting
+// the closed orders the filter covers. Queries using it alias orders as o and
+// menu_categories as mc.
+func reportConditions(filter models.ReportFilter) ([]string, []any) {
+	queryArgs := []any{}
+	conditions := []string{"o.order_status = 'closed'"}
+	if filter.From != "" {
+		queryArgs = append(queryArgs, filter.From)
+		conditions = append(conditions, fmt.Sprintf("o.created_at::date >= $%v", len(queryArgs)))
+	}
+	if filter.To != "" {
+		queryArgs = append(queryArgs, filter.To)
+		conditions = append(conditions, fmt.Sprintf("o.created_at::date <= $%v", len(queryArgs)))
+	}
+	if filter.Category != "" {
+		queryArgs = append(queryArgs, filter.Category)
+		conditions = append(conditions, fmt.Sprintf("lower(mc.name) = lower($%v)", len(queryArgs)))
+	}
+	return conditions, queryArgs
+}
+
+// an order line's net and gross amounts after discounts; its tax is either
+// part of line_total or added on top of it
+const (
+	lineNet   = "oi.line_total - CASE WHEN oi.tax_added THEN 0 ELSE oi.tax END"
+	lineGross = "oi.line_total + CASE WHEN oi.tax_added THEN oi.tax ELSE 0 END"
+)
+
+// GetTotalSales sums the gross and net revenue after discounts, the tax, the
+// discounts and the ingredient cost of the closed orders the filter covers
+func (m *reportRepositoryPostgres) GetTotalSales(filter models.ReportFilter) (models.ReportTotalSales, error) {
+	conditions, queryArgs := reportConditions(filter)
 	query := `
 		SELECT
 			COUNT(DISTINCT o.id) AS orders_completed,
-			COALESCE(SUM(oi.quantity * mi.price), 0) AS total_sales
+			COALESCE(SUM(` + lineGross + `), 0) AS total_sales,
+			COALESCE(SUM(` + lineNet + `), 0) AS net_sales,
+			COALESCE(SUM(oi.tax), 0) AS tax,
+			COALESCE(SUM(oi.discount + oi.order_discount), 0) AS discounts,
+			COALESCE(ROUND(SUM(oi.quantity * oi.unit_cost), 2), 0) AS cogs
 		FROM orders o
 		JOIN order_item oi ON o.id = oi.order_id
-		JOIN menu_items mi ON oi.menu_item_id = mi.id
-		WHERE o.order_status = 'closed';
-	`
+		LEFT JOIN menu_items mi ON mi.id = oi.menu_item_id
+		LEFT JOIN menu_categories mc ON mc.id = mi.category_id
+		WHERE ` + strings.Join(conditions, " AND ")
+
 	var report models.ReportTotalSales
-	err := m.pq.QueryRow(query).Scan(&report.OrdersCompleted, &report.TotalSales)
+	err := m.pq.QueryRow(query, queryArgs...).Scan(&report.OrdersCompleted, &report.TotalSales, &report.NetSales, &report.Tax, &report.Discounts, &report.COGS)
 	if err != nil {
 		m.logger.Error(err.Error())
 		return models.ReportTotalSales{}, err
@@ -44,23 +90,155 @@ func (m *reportRepositoryPostgres) GetTotalSales() (models.ReportTotalSales, err
 	return report, nil
 }
 
-func (m *reportRepositoryPostgres) GetPopularMenuItems() ([]models.ReportPopularItem, error) {
+// EachSalesGroup is GetTotalSales per hour, day, week, month or category as
+// given by filter.GroupBy. It calls fn with each group in group order as its
+// row is read; groups without sales are left out.
+func (m *reportRepositoryPostgres) EachSalesGroup(filter models.ReportFilter, fn func(models.ReportSalesGroup) error) error {
+	conditions, queryArgs := reportConditions(filter)
 	query := `
 		SELECT
-			mi.name,
-			mi.description,
-			mi.price,
-			SUM(oi.quantity) AS total_items_sold,
-			RANK() OVER (ORDER BY SUM(oi.quantity) DESC) AS rank
-		FROM order_item oi
-		JOIN menu_items mi ON oi.menu_item_id = mi.id
-		JOIN orders o ON oi.order_id = o.id
-		WHERE o.order_status = 'closed'
-		GROUP BY mi.id
-		ORDER BY total_items_sold DESC
-		LIMIT 5;
-	`
-	rows, err := m.pq.Query(query)
+			` + reportGroupExpressions[filter.GroupBy] + ` AS grp,
+			COUNT(DISTINCT o.id) AS orders_completed,
+			COALESCE(SUM(` + lineGross + `), 0) AS total_sales,
+			COALESCE(SUM(` + lineNet + `), 0) AS net_sales,
+			COALESCE(SUM(oi.tax), 0) AS tax,
+			COALESCE(SUM(oi.discount + oi.order_discount), 0) AS discounts,
+			COALESCE(ROUND(SUM(oi.quantity * oi.unit_cost), 2), 0) AS cogs
+		FROM orders o
+		JOIN order_item oi ON o.id = oi.order_id
+		LEFT JOIN menu_items mi ON mi.id = oi.menu_item_id
+		LEFT JOIN menu_categories mc ON mc.id = mi.category_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY grp
+		ORDER BY grp`
+
+	rows, err := m.pq.Query(query, queryArgs...)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group models.ReportSalesGroup
+		err = rows.Scan(&group.Group, &group.OrdersCompleted, &group.TotalSales, &group.NetSales, &group.Tax, &group.Discounts, &group.COGS)
+		if err != nil {
+			m.logger.Error(err.Error())
+			return err
+		}
+		if err := fn(group); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetTaxSummary sums the closed orders the filter covers per tax class and
+// rate, as the lines were taxed when ordered
+func (m *reportRepositoryPostgres) GetTaxSummary(filter models.ReportFilter) ([]models.ReportTaxClass, error) {
+	conditions, queryArgs := reportConditions(filter)
+	query := `
+		SELECT
+			COALESCE(oi.tax_class, 'untaxed'),
+			oi.tax_rate,
+			COUNT(*),
+			SUM(` + lineNet + `),
+			SUM(oi.tax),
+			SUM(` + lineGross + `)
+		FROM orders o
+		JOIN order_item oi ON o.id = oi.order_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY oi.tax_class, oi.tax_rate
+		ORDER BY oi.tax_class NULLS LAST, oi.tax_rate`
+
+	rows, err := m.pq.Query(query, queryArgs...)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := []models.ReportTaxClass{}
+	for rows.Next() {
+		var class models.ReportTaxClass
+		err = rows.Scan(&class.TaxClass, &class.Rate, &class.Lines, &class.Net, &class.Tax, &class.Gross)
+		if err != nil {
+			m.logger.Error(err.Error())
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+
+	return classes, rows.Err()
+}
+
+// MenuMargins prices every menu item's recipe at the current inventory unit
+// costs, lowest margin first
+func (m *reportRepositoryPostgres) MenuMargins() ([]models.ReportMenuMargin, error) {
+	rows, err := m.pq.Query(`
+		SELECT mi.id, mi.name, COALESCE(mc.name, ''), mi.price,
+			ROUND(COALESCE(SUM(mii.quantity * i.unit_cost), 0), 2) AS cost
+		FROM menu_items mi
+		LEFT JOIN menu_categories mc ON mc.id = mi.category_id
+		LEFT JOIN menu_item_inventory mii ON mii.menu_id = mi.id
+		LEFT JOIN inventory i ON i.id = mii.inventory_id
+		GROUP BY mi.id, mc.name
+		ORDER BY (mi.price - COALESCE(SUM(mii.quantity * i.unit_cost), 0)) / NULLIF(mi.price, 0) NULLS FIRST, mi.id
+	`)
+	if err != nil {
+		m.logger.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	margins := []models.ReportMenuMargin{}
+	for rows.Next() {
+		var margin models.ReportMenuMargin
+		err = rows.Scan(&margin.MenuID, &margin.Name, &margin.Category, &margin.Price, &margin.Cost)
+		if err != nil {
+			m.logger.Error(err.Error())
+			return nil, err
+		}
+		margins = append(margins, margin)
+	}
+
+	return margins, rows.Err()
+}
+
+// GetPopularMenuItems ranks the best selling menu items of the closed orders the
+// filter covers, within each group when filter.GroupBy is set, keeping the
+// first filter.Limit items of each group
+func (m *reportRepositoryPostgres) GetPopularMenuItems(filter models.ReportFilter) ([]models.ReportPopularItem, error) {
+	conditions, queryArgs := reportConditions(filter)
+	queryArgs = append(queryArgs, filter.Limit)
+	query := fmt.Sprintf(`
+		SELECT menu_id, name, description, price, category, grp, total_items_sold, total_revenue, rank
+		FROM (
+			SELECT
+				mi.id AS menu_id,
+				mi.name,
+				mi.description,
+				mi.price,
+				COALESCE(mc.name, '') AS category,
+				%s AS grp,
+				SUM(oi.quantity) AS total_items_sold,
+				SUM(`+lineGross+`) AS total_revenue,
+				RANK() OVER w AS rank,
+				ROW_NUMBER() OVER w AS position
+			FROM order_item oi
+			JOIN menu_items mi ON oi.menu_item_id = mi.id
+			LEFT JOIN menu_categories mc ON mi.category_id = mc.id
+			JOIN orders o ON oi.order_id = o.id
+			WHERE %s
+			GROUP BY mi.id, mc.name, grp
+			WINDOW w AS (PARTITION BY %[1]s ORDER BY SUM(oi.quantity) DESC)
+		) ranked
+		WHERE $%[3]d = 0 OR position <= $%[3]d
+		ORDER BY grp, position`,
+		reportGroupExpressions[filter.GroupBy], strings.Join(conditions, " AND "), len(queryArgs))
+
+	rows, err := m.pq.Query(query, queryArgs...)
 	if err != nil {
 		m.logger.Error(err.Error())
 		return nil, err
@@ -71,7 +249,8 @@ func (m *reportRepositoryPostgres) GetPopularMenuItems() ([]models.ReportPopular
 	for rows.Next() {
 		var item models.ReportPopularItem
 
-		err = rows.Scan(&item.Name, &item.Description, &item.Price, &item.TotalItemsSold, &item.Rank)
+		err = rows.Scan(&item.MenuID, &item.Name, &item.Description, &item.Price, &item.Category, &item.Group,
+			&item.TotalItemsSold, &item.TotalRevenue, &item.Rank)
 		if err != nil {
 			m.logger.Error(err.Error())
 			return nil, err
@@ -80,10 +259,12 @@ func (m *reportRepositoryPostgres) GetPopularMenuItems() ([]models.ReportPopular
 		popularItems = append(popularItems, item)
 	}
 
-	return popularItems, nil
+	return popularItems, rows.Err()
 }
 
-func (m *reportRepositoryPostgres) TextSearchMenu(query string, minPrice float64, maxPrice float64) ([]models.ReportMenuSearchItem, error) {
+// EachMenuMatch calls fn with each menu item matching the search, most
+// relevant first, as its row is read
+func (m *reportRepositoryPostgres) EachMenuMatch(query string, minPrice float64, maxPrice float64, fn func(models.ReportMenuSearchItem) error) error {
 	queryArgs := []any{query}
 	dbQuery := `
 		WITH q AS (
@@ -94,12 +275,10 @@ func (m *reportRepositoryPostgres) TextSearchMenu(query string, minPrice float64
 		CROSS JOIN q
 		WHERE tsv @@ q.q`
 	if minPrice != -1 {
-		fmt.Println(minPrice)
 		queryArgs = append(queryArgs, minPrice)
 		dbQuery += fmt.Sprintf(" AND price >= $%v", len(queryArgs))
 	}
 	if maxPrice != -1 {
-		fmt.Println(maxPrice)
 		queryArgs = append(queryArgs, maxPrice)
 		dbQuery += fmt.Sprintf(" AND price <= $%v", len(queryArgs))
 	}
@@ -108,30 +287,33 @@ func (m *reportRepositoryPostgres) TextSearchMenu(query string, minPrice float64
 	rows, err := m.pq.Query(dbQuery, queryArgs...)
 	if err != nil {
 		m.logger.Error(err.Error())
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var results []models.ReportMenuSearchItem
 	for rows.Next() {
 		var resItem models.ReportMenuSearchItem
 		err = rows.Scan(&resItem.Id, &resItem.Name, &resItem.Description, &resItem.Price, &resItem.Relevance)
 		if err != nil {
 			m.logger.Error(err.Error())
-			return nil, err
+			return err
+		}
+		if err := fn(resItem); err != nil {
+			return err
 		}
-		results = append(results, resItem)
 	}
-	return results, nil
+	return rows.Err()
 }
 
-func (m *reportRepositoryPostgres) TextSearchOrders(query string, minPrice float64, maxPrice float64) ([]models.ReportOrderSearchItem, error) {
+// EachOrderMatch calls fn with each order with menu items matching the search,
+// most relevant first, as its row is read
+func (m *reportRepositoryPostgres) EachOrderMatch(query string, minPrice float64, maxPrice float64, fn func(models.ReportOrderSearchItem) error) error {
 	queryArgs := []any{query}
 	dbQuery := `
 		WITH q AS (
 			SELECT plainto_tsquery('english', $1) as q
 		)
-		SELECT o.id, o.customer_name, array_agg(mi.name), SUM(oi.quantity * mi.price), MAX(ts_rank(mi.tsv, q.q)) as relevance
+		SELECT o.id, o.customer_name, array_agg(mi.name), SUM(oi.line_total), MAX(ts_rank(mi.tsv, q.q)) as relevance
 		FROM menu_items mi
 		CROSS JOIN q
 		JOIN order_item oi ON mi.id = oi.menu_item_id
@@ -152,75 +334,76 @@ func (m *reportRepositoryPostgres) TextSearchOrders(query string, minPrice float
 	rows, err := m.pq.Query(dbQuery, queryArgs...)
 	if err != nil {
 		m.logger.Error(err.Error())
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var results []models.ReportOrderSearchItem
 	for rows.Next() {
 		var resItem models.ReportOrderSearchItem
 		err = rows.Scan(&resItem.Id, &resItem.CustomerName, pq.Array(&resItem.Items), &resItem.Total, &resItem.Relevance)
 		if err != nil {
 			m.logger.Error(err.Error())
-			return nil, err
+			return err
+		}
+		if err := fn(resItem); err != nil {
+			return err
 		}
-		results = append(results, resItem)
 	}
-	return results, nil
+	return rows.Err()
 }
 
-func (m *reportRepositoryPostgres) OrderedItemsByDays(month int) ([]map[string]int, error) {
-	rows, err := m.pq.Query(`
-		SELECT EXTRACT(DAY FROM created_at) AS day, COUNT(id) AS num
-		FROM orders
-		WHERE EXTRACT(MONTH FROM created_at)=$1
-		GROUP BY day`, month)
-	if err != nil {
-		m.logger.Error(err.Error())
-		return nil, err
-	}
-	defer rows.Close()
+// orderedItemsLabels are the to_char formats labelling each ordered items period
+var orderedItemsLabels = map[string]string{
+	models.OrderedItemsPeriodHour:  "YYYY-MM-DD HH24:00",
+	models.OrderedItemsPeriodDay:   "YYYY-MM-DD",
+	models.OrderedItemsPeriodWeek:  "YYYY-MM-DD", // the Monday starting the week
+	models.OrderedItemsPeriodMonth: "YYYY-MM",
+	models.OrderedItemsPeriodYear:  "YYYY",
+}
 
-	results := make([]map[string]int, utils.GetDaysInMonth(month))
-	for i := range results {
-		results[i] = map[string]int{strconv.Itoa(i + 1): 0}
+// EachOrderedItemsPeriod counts the items ordered (or the orders placed) in
+// every period from filter.From to filter.To, including periods without any,
+// and calls fn with each period's label and count in time order as its row is read
+func (m *reportRepositoryPostgres) EachOrderedItemsPeriod(filter models.OrderedItemsFilter, fn func(period string, count int) error) error {
+	count := "SUM(oi.quantity)"
+	if filter.Count == models.OrderedItemsCountOrders {
+		count = "COUNT(DISTINCT o.id)"
 	}
-	for rows.Next() {
-		var day, cnt int
-		err = rows.Scan(&day, &cnt)
-		if err != nil {
-			m.logger.Error(err.Error())
-			return nil, err
-		}
-		results[day-1][strconv.Itoa(day)] = cnt
-	}
-	return results, nil
-}
 
-func (m *reportRepositoryPostgres) OrderedItemsByMonths(year int) ([]map[string]int, error) {
 	rows, err := m.pq.Query(`
-		SELECT EXTRACT(MONTH FROM created_at) AS month, COUNT(id) AS num
-		FROM orders
-		WHERE EXTRACT(YEAR FROM created_at)=$1
-		GROUP BY month`, year)
+		SELECT to_char(p.period, $4), COALESCE(c.num, 0)
+		FROM generate_series(
+			date_trunc($1, $2::date::timestamp),
+			$3::date + interval '1 day' - interval '1 second',
+			('1 ' || $1)::interval
+		) AS p (period)
+		LEFT JOIN (
+			SELECT date_trunc($1, o.created_at) AS period, `+count+` AS num
+			FROM orders o
+			LEFT JOIN order_item oi ON oi.order_id = o.id
+			WHERE o.created_at >= $2::date AND o.created_at < $3::date + 1
+				AND o.order_status <> 'cancelled'
+			GROUP BY 1
+		) c ON c.period = p.period
+		ORDER BY p.period`,
+		filter.Period, filter.From, filter.To, orderedItemsLabels[filter.Period])
 	if err != nil {
 		m.logger.Error(err.Error())
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	results := make([]map[string]int, 12)
-	for i := range results {
-		results[i] = map[string]int{utils.GetMonthName(i + 1): 0}
-	}
 	for rows.Next() {
-		var mon, cnt int
-		err = rows.Scan(&mon, &cnt)
+		var label string
+		var num int
+		err = rows.Scan(&label, &num)
 		if err != nil {
 			m.logger.Error(err.Error())
-			return nil, err
+			return err
+		}
+		if err := fn(label, num); err != nil {
+			return err
 		}
-		results[mon-1][utils.GetMonthName(mon)] = cnt
 	}
-	return results, nil
+	return rows.Err()
 }
@@ -0,0 +1,296 @@
+package postgre
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type shiftRepositoryPostgres struct {
+	pq     *sql.DB
+	logger *slog.Logger
+}
+
+func NewShiftRepositoryPostgres(db *sql.DB, logger *slog.Logger) *shiftRepositoryPostgres {
+	return &shiftRepositoryPostgres{
+		pq:     db,
+		logger: logger,
+	}
+}
+
+const shiftColumns = "id, opened_at, opened_by, closed_at, COALESCE(closed_by, ''), forced"
+
+func scanShift(row interface{ Scan(...any) error }) (models.Shift, error) {
+	var shift models.Shift
+	var closedAt sql.NullTime
+	err := row.Scan(&shift.ID, &shift.OpenedAt, &shift.OpenedBy, &closedAt, &shift.ClosedBy, &shift.Forced)
+	if closedAt.Valid {
+		shift.ClosedAt = &closedAt.Time
+	}
+	return shift, err
+}
+
+// Open starts a shift; the one-open-shift index rejects it while another is open
+func (m *shiftRepositoryPostgres) Open(shift models.Shift) (models.Shift, error) {
+	opened, err := scanShift(m.pq.QueryRow(
+		"INSERT INTO shifts (opened_by) VALUES ($1) RETURNING "+shiftColumns, shift.OpenedBy))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.Shift{}, models.ErrShiftAlreadyOpen
+			}
+		}
+		m.logger.Error("Failed to open shift", "error", err)
+		return models.Shift{}, err
+	}
+
+	return opened, nil
+}
+
+func (m *shiftRepositoryPostgres) RetrieveAll() ([]models.Shift, error) {
+	rows, err := m.pq.Query("SELECT " + shiftColumns + " FROM shifts ORDER BY opened_at DESC")
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	shifts := []models.Shift{}
+	for rows.Next() {
+		shift, err := scanShift(rows)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, shift)
+	}
+
+	return shifts, rows.Err()
+}
+
+func (m *shiftRepositoryPostgres) RetrieveByID(id int) (models.Shift, error) {
+	shift, err := scanShift(m.pq.QueryRow("SELECT "+shiftColumns+" FROM shifts WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Shift{}, models.ErrNoRecord
+		}
+		return models.Shift{}, err
+	}
+
+	return shift, nil
+}
+
+func (m *shiftRepositoryPostgres) RetrieveCurrent() (models.Shift, error) {
+	shift, err := scanShift(m.pq.QueryRow("SELECT " + shiftColumns + " FROM shifts WHERE closed_at IS NULL"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Shift{}, models.ErrNoRecord
+		}
+		return models.Shift{}, err
+	}
+
+	return shift, nil
+}
+
+// Close closes the shift and stores its Z-report in one transaction. Unless
+// forced, it fails while any order is open or in progress.
+func (m *shiftRepositoryPostgres) Close(id int, close models.ShiftClose) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var openedAt time.Time
+	var closed bool
+	err = tx.QueryRow("SELECT opened_at, closed_at IS NOT NULL FROM shifts WHERE id = $1 FOR UPDATE", id).
+		Scan(&openedAt, &closed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+	if closed {
+		return models.ErrShiftClosed
+	}
+
+	var report models.ZReport
+	err = tx.QueryRow("SELECT COUNT(*) FROM orders WHERE order_status IN ('open', 'in progress')").Scan(&report.OrdersOpen)
+	if err != nil {
+		return err
+	}
+	if report.OrdersOpen > 0 && !close.Force {
+		return fmt.Errorf("%w: %d orders", models.ErrShiftHasOpenOrders, report.OrdersOpen)
+	}
+
+	var closedAt time.Time
+	err = tx.QueryRow("UPDATE shifts SET closed_at = now(), closed_by = $1, forced = $2 WHERE id = $3 RETURNING closed_at",
+		close.ClosedBy, report.OrdersOpen > 0, id).
+		Scan(&closedAt)
+	if err != nil {
+		m.logger.Error("Failed to close shift", "error", err)
+		return err
+	}
+
+	err = snapshotShift(tx, openedAt, closedAt, &report)
+	if err != nil {
+		m.logger.Error("Failed to build Z-report", "shift_id", id, "error", err)
+		return err
+	}
+
+	itemsJSON, err := json.Marshal(report.ItemsSold)
+	if err != nil {
+		return err
+	}
+	inventoryJSON, err := json.Marshal(report.InventoryConsumed)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO z_reports (shift_id, orders_taken, orders_closed, orders_cancelled, orders_open,
+			revenue, net_revenue, tax, cogs, cancelled_value, items_sold, inventory_consumed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		id, report.OrdersTaken, report.OrdersClosed, report.OrdersCancelled, report.OrdersOpen,
+		report.Revenue, report.NetRevenue, report.Tax, report.COGS, report.CancelledValue, itemsJSON, inventoryJSON)
+	if err != nil {
+		m.logger.Error("Failed to store Z-report", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// snapshotShift fills report with the orders and stock movements from openedAt
+// to closedAt. Orders closed or cancelled during the shift count by the time
+// of the status change, not the time they were taken.
+func snapshotShift(tx *sql.Tx, openedAt, closedAt time.Time, report *models.ZReport) error {
+	err := tx.QueryRow("SELECT COUNT(*) FROM orders WHERE created_at >= $1 AND created_at <= $2", openedAt, closedAt).
+		Scan(&report.OrdersTaken)
+	if err != nil {
+		return err
+	}
+
+	statusTotals := `
+		WITH changed AS (
+			SELECT DISTINCT order_id FROM order_status_history
+			WHERE new_status = $1 AND updated_at >= $2 AND updated_at <= $3
+		)
+		SELECT
+			(SELECT COUNT(*) FROM changed),
+			COALESCE(SUM(` + lineGross + `), 0),
+			COALESCE(SUM(` + lineNet + `), 0),
+			COALESCE(SUM(oi.tax), 0),
+			COALESCE(ROUND(SUM(oi.quantity * oi.unit_cost), 2), 0)
+		FROM changed c
+		JOIN order_item oi ON oi.order_id = c.order_id`
+	err = tx.QueryRow(statusTotals, "closed", openedAt, closedAt).
+		Scan(&report.OrdersClosed, &report.Revenue, &report.NetRevenue, &report.Tax, &report.COGS)
+	if err != nil {
+		return err
+	}
+	var cancelledNet, cancelledTax, cancelledCost float64
+	err = tx.QueryRow(statusTotals, "cancelled", openedAt, closedAt).
+		Scan(&report.OrdersCancelled, &report.CancelledValue, &cancelledNet, &cancelledTax, &cancelledCost)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT oi.item_name, SUM(oi.quantity), SUM(`+lineGross+`)
+		FROM order_item oi
+		WHERE oi.order_id IN (
+			SELECT order_id FROM order_status_history
+			WHERE new_status = 'closed' AND updated_at >= $1 AND updated_at <= $2
+		)
+		GROUP BY oi.item_name
+		ORDER BY SUM(oi.quantity) DESC, oi.item_name`, openedAt, closedAt)
+	if err != nil {
+		return err
+	}
+	report.ItemsSold = []models.ZReportItem{}
+	for rows.Next() {
+		var item models.ZReportItem
+		if err := rows.Scan(&item.Name, &item.Quantity, &item.Revenue); err != nil {
+			rows.Close()
+			return err
+		}
+		report.ItemsSold = append(report.ItemsSold, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(`
+		SELECT i.id, i.name, i.unit,
+			COALESCE(SUM(t.old_quantity - t.new_quantity) FILTER (WHERE t.reason IN ('order', 'order_update', 'order_cancel')), 0),
+			COALESCE(SUM(t.old_quantity - t.new_quantity) FILTER (WHERE t.reason IN ('spoilage', 'spill')), 0)
+		FROM inventory_transactions t
+		JOIN inventory i ON i.id = t.inventory_id
+		WHERE t.transaction_date >= $1 AND t.transaction_date <= $2
+			AND t.reason IN ('order', 'order_update', 'order_cancel', 'spoilage', 'spill')
+		GROUP BY i.id
+		ORDER BY i.id`, openedAt, closedAt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	report.InventoryConsumed = []models.ZReportInventory{}
+	for rows.Next() {
+		var item models.ZReportInventory
+		if err := rows.Scan(&item.InventoryID, &item.Name, &item.Unit, &item.Consumed, &item.Wasted); err != nil {
+			return err
+		}
+		report.InventoryConsumed = append(report.InventoryConsumed, item)
+	}
+
+	return rows.Err()
+}
+
+// ZReport returns the Z-report stored when the shift closed
+func (m *shiftRepositoryPostgres) ZReport(id int) (models.ZReport, error) {
+	shift, err := m.RetrieveByID(id)
+	if err != nil {
+		return models.ZReport{}, err
+	}
+	if shift.ClosedAt == nil {
+		return models.ZReport{}, models.ErrShiftNotClosed
+	}
+
+	report := models.ZReport{Shift: shift}
+	var itemsJSON, inventoryJSON []byte
+	err = m.pq.QueryRow(`
+		SELECT orders_taken, orders_closed, orders_cancelled, orders_open,
+			revenue, net_revenue, tax, cogs, cancelled_value, items_sold, inventory_consumed
+		FROM z_reports
+		WHERE shift_id = $1`, id).
+		Scan(&report.OrdersTaken, &report.OrdersClosed, &report.OrdersCancelled, &report.OrdersOpen,
+			&report.Revenue, &report.NetRevenue, &report.Tax, &report.COGS, &report.CancelledValue, &itemsJSON, &inventoryJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ZReport{}, models.ErrNoRecord
+		}
+		m.logger.Error("Failed to retrieve Z-report", "error", err)
+		return models.ZReport{}, err
+	}
+
+	if err := json.Unmarshal(itemsJSON, &report.ItemsSold); err != nil {
+		return models.ZReport{}, err
+	}
+	if err := json.Unmarshal(inventoryJSON, &report.InventoryConsumed); err != nil {
+		return models.ZReport{}, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,597 @@
+package postgre
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type supplierRepositoryPostgres struct {
+	pq     *sql.DB
+	logger *slog.Logger
+}
+
+func NewSupplierRepositoryPostgres(db *sql.DB, logger *slog.Logger) *supplierRepositoryPostgres {
+	return &supplierRepositoryPostgres{
+		pq:     db,
+		logger: logger,
+	}
+}
+
+func (m *supplierRepositoryPostgres) InsertSupplier(supplier models.Supplier) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var supplierID int
+	err = tx.QueryRow(`
+		INSERT INTO suppliers (name, contact_name, email, phone, lead_time_days)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, supplier.Name, supplier.ContactName, supplier.Email, supplier.Phone, supplier.LeadTimeDays).
+		Scan(&supplierID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateSupplier
+			}
+		}
+		return err
+	}
+
+	err = insertSupplierItems(tx, supplierID, supplier.Items)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertSupplierItems(tx *sql.Tx, supplierID int, items []models.SupplierItem) error {
+	for _, item := range items {
+		_, err := tx.Exec("INSERT INTO supplier_items (supplier_id, inventory_id, pack_size, pack_price) VALUES ($1, $2, $3, $4)",
+			supplierID, item.InventoryID, item.PackSize, item.PackPrice)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok {
+				switch pqErr.Code {
+				case "23503":
+					return models.ErrForeignKeyConstraintMenuInventory
+				}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *supplierRepositoryPostgres) RetrieveSuppliers() ([]models.Supplier, error) {
+	return m.retrieveSuppliers("")
+}
+
+func (m *supplierRepositoryPostgres) RetrieveSupplierByID(id int) (models.Supplier, error) {
+	suppliers, err := m.retrieveSuppliers("WHERE s.id = $1", id)
+	if err != nil {
+		return models.Supplier{}, err
+	}
+	if len(suppliers) == 0 {
+		return models.Supplier{}, models.ErrNoRecord
+	}
+
+	return suppliers[0], nil
+}
+
+func (m *supplierRepositoryPostgres) retrieveSuppliers(where string, args ...any) ([]models.Supplier, error) {
+	rows, err := m.pq.Query(`
+		SELECT s.id, s.name, COALESCE(s.contact_name, ''), COALESCE(s.email, ''), COALESCE(s.phone, ''), s.lead_time_days,
+		       si.inventory_id, i.name, si.pack_size, si.pack_price
+		FROM suppliers s
+		LEFT JOIN supplier_items si ON si.supplier_id = s.id
+		LEFT JOIN inventory i ON i.id = si.inventory_id
+		`+where+`
+		ORDER BY s.name, s.id, i.name
+	`, args...)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := []models.Supplier{}
+	supplierIndex := make(map[int]int)
+	for rows.Next() {
+		var supplier models.Supplier
+		var inventoryID, packSize sql.NullInt32
+		var inventoryName sql.NullString
+		var packPrice sql.NullFloat64
+
+		err := rows.Scan(&supplier.ID, &supplier.Name, &supplier.ContactName, &supplier.Email, &supplier.Phone, &supplier.LeadTimeDays,
+			&inventoryID, &inventoryName, &packSize, &packPrice)
+		if err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+
+		idx, ok := supplierIndex[supplier.ID]
+		if !ok {
+			idx = len(suppliers)
+			supplierIndex[supplier.ID] = idx
+			supplier.Items = []models.SupplierItem{}
+			suppliers = append(suppliers, supplier)
+		}
+
+		if inventoryID.Valid {
+			suppliers[idx].Items = append(suppliers[idx].Items, models.SupplierItem{
+				InventoryID: int(inventoryID.Int32),
+				Name:        inventoryName.String,
+				PackSize:    int(packSize.Int32),
+				PackPrice:   packPrice.Float64,
+			})
+		}
+	}
+
+	return suppliers, rows.Err()
+}
+
+// UpdateSupplier replaces the supplier's details and the items it sells.
+// Existing purchase orders keep the pack sizes and prices they were written with.
+func (m *supplierRepositoryPostgres) UpdateSupplier(id int, supplier models.Supplier) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE suppliers
+		SET name = $1, contact_name = $2, email = $3, phone = $4, lead_time_days = $5
+		WHERE id = $6
+	`, supplier.Name, supplier.ContactName, supplier.Email, supplier.Phone, supplier.LeadTimeDays, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return models.ErrDuplicateSupplier
+			}
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	_, err = tx.Exec("DELETE FROM supplier_items WHERE supplier_id = $1", id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	err = insertSupplierItems(tx, id, supplier.Items)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *supplierRepositoryPostgres) DeleteSupplier(id int) error {
+	result, err := m.pq.Exec("DELETE FROM suppliers WHERE id = $1", id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23503":
+				return models.ErrSupplierHasPurchaseOrders
+			}
+		}
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.ErrNoRecord
+	}
+
+	return err
+}
+
+// InsertPurchaseOrder stores a new draft purchase order and returns its id
+func (m *supplierRepositoryPostgres) InsertPurchaseOrder(order models.PurchaseOrder) (int, error) {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	err = tx.QueryRow("INSERT INTO purchase_orders (supplier_id, note) VALUES ($1, NULLIF($2, '')) RETURNING id",
+		order.SupplierID, order.Note).
+		Scan(&orderID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23503":
+				return 0, models.ErrForeignKeyConstraintSupplier
+			}
+		}
+		return 0, err
+	}
+
+	err = insertPurchaseOrderItems(tx, orderID, order.SupplierID, order.Items)
+	if err != nil {
+		return 0, err
+	}
+
+	return orderID, tx.Commit()
+}
+
+// insertPurchaseOrderItems writes the lines with the supplier's current pack size and price
+func insertPurchaseOrderItems(tx *sql.Tx, orderID, supplierID int, items []models.PurchaseOrderItem) error {
+	for _, item := range items {
+		var inventoryID int
+		err := tx.QueryRow(`
+			INSERT INTO purchase_order_items (purchase_order_id, inventory_id, packs_ordered, pack_size, pack_price)
+			SELECT $1, si.inventory_id, $2, si.pack_size, si.pack_price
+			FROM supplier_items si
+			WHERE si.supplier_id = $3 AND si.inventory_id = $4
+			RETURNING inventory_id
+		`, orderID, item.PacksOrdered, supplierID, item.InventoryID).
+			Scan(&inventoryID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w: inventory item %d", models.ErrItemNotSupplied, item.InventoryID)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *supplierRepositoryPostgres) RetrievePurchaseOrders(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, error) {
+	var queryArgs []any
+	var conditions []string
+	if filter.Status != "" {
+		queryArgs = append(queryArgs, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("po.status = $%v", len(queryArgs)))
+	}
+	if filter.SupplierID != 0 {
+		queryArgs = append(queryArgs, filter.SupplierID)
+		conditions = append(conditions, fmt.Sprintf("po.supplier_id = $%v", len(queryArgs)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return m.retrievePurchaseOrders(where, queryArgs...)
+}
+
+func (m *supplierRepositoryPostgres) RetrievePurchaseOrderByID(id int) (models.PurchaseOrder, error) {
+	orders, err := m.retrievePurchaseOrders("WHERE po.id = $1", id)
+	if err != nil {
+		return models.PurchaseOrder{}, err
+	}
+	if len(orders) == 0 {
+		return models.PurchaseOrder{}, models.ErrNoRecord
+	}
+
+	return orders[0], nil
+}
+
+func (m *supplierRepositoryPostgres) retrievePurchaseOrders(where string, args ...any) ([]models.PurchaseOrder, error) {
+	rows, err := m.pq.Query(`
+		SELECT po.id, po.supplier_id, s.name, po.status, COALESCE(po.note, ''), po.created_at, po.sent_at, po.received_at,
+		       poi.inventory_id, i.name, poi.packs_ordered, poi.packs_received, poi.pack_size, poi.pack_price
+		FROM purchase_orders po
+		JOIN suppliers s ON s.id = po.supplier_id
+		LEFT JOIN purchase_order_items poi ON poi.purchase_order_id = po.id
+		LEFT JOIN inventory i ON i.id = poi.inventory_id
+		`+where+`
+		ORDER BY po.created_at DESC, po.id DESC, i.name
+	`, args...)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.PurchaseOrder{}
+	orderIndex := make(map[int]int)
+	for rows.Next() {
+		var order models.PurchaseOrder
+		var sentAt, receivedAt sql.NullTime
+		var inventoryID, packsOrdered, packsReceived, packSize sql.NullInt32
+		var inventoryName sql.NullString
+		var packPrice sql.NullFloat64
+
+		err := rows.Scan(&order.ID, &order.SupplierID, &order.SupplierName, &order.Status, &order.Note,
+			&order.CreatedAt, &sentAt, &receivedAt,
+			&inventoryID, &inventoryName, &packsOrdered, &packsReceived, &packSize, &packPrice)
+		if err != nil {
+			m.logger.Error("Failed to scan row", "error", err)
+			return nil, err
+		}
+
+		idx, ok := orderIndex[order.ID]
+		if !ok {
+			if sentAt.Valid {
+				order.SentAt = &sentAt.Time
+			}
+			if receivedAt.Valid {
+				order.ReceivedAt = &receivedAt.Time
+			}
+			idx = len(orders)
+			orderIndex[order.ID] = idx
+			order.Items = []models.PurchaseOrderItem{}
+			orders = append(orders, order)
+		}
+
+		if inventoryID.Valid {
+			item := models.PurchaseOrderItem{
+				InventoryID:   int(inventoryID.Int32),
+				Name:          inventoryName.String,
+				PacksOrdered:  int(packsOrdered.Int32),
+				PacksReceived: int(packsReceived.Int32),
+				PackSize:      int(packSize.Int32),
+				PackPrice:     packPrice.Float64,
+			}
+			orders[idx].Items = append(orders[idx].Items, item)
+			orders[idx].Total += float64(item.PacksOrdered) * item.PackPrice
+		}
+	}
+
+	return orders, rows.Err()
+}
+
+// lockPurchaseOrder returns the status of a purchase order and locks it until tx ends
+func lockPurchaseOrder(tx *sql.Tx, id int) (string, error) {
+	var status string
+	err := tx.QueryRow("SELECT status FROM purchase_orders WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", models.ErrNoRecord
+	}
+
+	return status, err
+}
+
+// UpdatePurchaseOrder replaces the supplier, note and lines of a draft purchase order
+func (m *supplierRepositoryPostgres) UpdatePurchaseOrder(id int, order models.PurchaseOrder) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockPurchaseOrder(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != models.PurchaseOrderStatusDraft {
+		return &models.PurchaseOrderStatusError{Status: status, Operation: "edit"}
+	}
+
+	_, err = tx.Exec("UPDATE purchase_orders SET supplier_id = $1, note = NULLIF($2, '') WHERE id = $3",
+		order.SupplierID, order.Note, id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23503":
+				return models.ErrForeignKeyConstraintSupplier
+			}
+		}
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM purchase_order_items WHERE purchase_order_id = $1", id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	err = insertPurchaseOrderItems(tx, id, order.SupplierID, order.Items)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeletePurchaseOrder removes a draft purchase order
+func (m *supplierRepositoryPostgres) DeletePurchaseOrder(id int) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockPurchaseOrder(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != models.PurchaseOrderStatusDraft {
+		return &models.PurchaseOrderStatusError{Status: status, Operation: "delete"}
+	}
+
+	_, err = tx.Exec("DELETE FROM purchase_orders WHERE id = $1", id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SendPurchaseOrder marks a draft purchase order as sent to the supplier
+func (m *supplierRepositoryPostgres) SendPurchaseOrder(id int) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockPurchaseOrder(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != models.PurchaseOrderStatusDraft {
+		return &models.PurchaseOrderStatusError{Status: status, Operation: "send"}
+	}
+
+	_, err = tx.Exec("UPDATE purchase_orders SET status = $1, sent_at = now() WHERE id = $2",
+		models.PurchaseOrderStatusSent, id)
+	if err != nil {
+		m.logger.Error("Failed to execute query", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReceivePurchaseOrder books delivered packs against a sent purchase order and
+// raises the inventory by packs * pack size. The stock changes are recorded as
+// deliveries referencing the purchase order, like any other adjustment.
+func (m *supplierRepositoryPostgres) ReceivePurchaseOrder(id int, receipt models.PurchaseOrderReceipt) error {
+	tx, err := m.pq.Begin()
+	if err != nil {
+		m.logger.Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockPurchaseOrder(tx, id)
+	if err != nil {
+		return err
+	}
+	if status != models.PurchaseOrderStatusSent && status != models.PurchaseOrderStatusPartiallyReceived {
+		return &models.PurchaseOrderStatusError{Status: status, Operation: "receive"}
+	}
+
+	rows, err := tx.Query(`
+		SELECT inventory_id, packs_ordered - packs_received, pack_size, pack_price
+		FROM purchase_order_items
+		WHERE purchase_order_id = $1
+	`, id)
+	if err != nil {
+		m.logger.Error("Failed to retrieve purchase order items", "error", err)
+		return err
+	}
+
+	outstanding := make(map[int]int)
+	packSizes := make(map[int]int)
+	packPrices := make(map[int]float64)
+	for rows.Next() {
+		var inventoryID, packs, packSize int
+		var packPrice float64
+		if err := rows.Scan(&inventoryID, &packs, &packSize, &packPrice); err != nil {
+			rows.Close()
+			return err
+		}
+		outstanding[inventoryID] = packs
+		packSizes[inventoryID] = packSize
+		packPrices[inventoryID] = packPrice
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	received := make(map[int]int)
+	if len(receipt.Items) == 0 {
+		for inventoryID, packs := range outstanding {
+			if packs > 0 {
+				received[inventoryID] = packs
+			}
+		}
+	}
+	for _, item := range receipt.Items {
+		packs, ok := outstanding[item.InventoryID]
+		if !ok {
+			return fmt.Errorf("%w: inventory item %d", models.ErrItemNotOnPurchaseOrder, item.InventoryID)
+		}
+		if item.Packs > packs {
+			return fmt.Errorf("%w: inventory item %d has %d packs outstanding", models.ErrOverReceipt, item.InventoryID, packs)
+		}
+		received[item.InventoryID] = item.Packs
+	}
+
+	err = setInventoryReason(tx, models.InventoryReasonDelivery, fmt.Sprintf("purchase_order:%d", id))
+	if err != nil {
+		return err
+	}
+
+	// lock inventory rows in id order, like order inserts do
+	inventoryIDs := make([]int, 0, len(received))
+	for inventoryID := range received {
+		inventoryIDs = append(inventoryIDs, inventoryID)
+	}
+	slices.Sort(inventoryIDs)
+
+	for _, inventoryID := range inventoryIDs {
+		packs := received[inventoryID]
+		_, err = tx.Exec("UPDATE purchase_order_items SET packs_received = packs_received + $1 WHERE purchase_order_id = $2 AND inventory_id = $3",
+			packs, id, inventoryID)
+		if err != nil {
+			m.logger.Error("Failed to update purchase order item", "error", err)
+			return err
+		}
+
+		// the unit cost becomes the average of the stock on hand and the
+		// delivery, weighted by quantity
+		_, err = tx.Exec(`
+			UPDATE inventory
+			SET unit_cost = CASE WHEN quantity + $1 > 0
+			                     THEN (quantity * unit_cost + $1 * $2::decimal) / (quantity + $1)
+			                     ELSE unit_cost END,
+			    quantity = quantity + $1
+			WHERE id = $3`,
+			packs*packSizes[inventoryID], packPrices[inventoryID]/float64(packSizes[inventoryID]), inventoryID)
+		if err != nil {
+			m.logger.Error("Failed to raise inventory", "inventory_id", inventoryID, "error", err)
+			return err
+		}
+		outstanding[inventoryID] -= packs
+	}
+
+	status = models.PurchaseOrderStatusReceived
+	for _, packs := range outstanding {
+		if packs > 0 {
+			status = models.PurchaseOrderStatusPartiallyReceived
+			break
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE purchase_orders
+		SET status = $1, received_at = CASE WHEN $1 = 'received' THEN now() END
+		WHERE id = $2
+	`, status, id)
+	if err != nil {
+		m.logger.Error("Failed to update purchase order status", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
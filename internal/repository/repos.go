@@ -2,40 +2,134 @@ package repository
 
 import "frappuccino/internal/models"
 
+type CustomerRepository interface {
+	Insert(customer models.Customer) (int, error)
+	RetrieveAll(search string) ([]models.Customer, error)
+	RetrieveByID(id int) (models.Customer, error)
+	Update(id int, customer models.Customer) error
+	Delete(id int) error
+}
+
 type InventoryRepository interface {
-	Insert(name, unit string, quantity int, categories []string) error
+	Insert(inventory models.Inventory) error
 	RetrieveByID(id int) (models.Inventory, error)
 	RetrieveAll() ([]models.Inventory, error)
-	Update(id int, name, unit string, quantity int, categories []string) error
+	Update(id int, inventory models.Inventory) error
+	RetrieveUnits(ids []int) (map[int]models.InventoryUnit, error)
+	Adjust(adjustments []models.InventoryAdjustment, reference string) ([]models.AdjustedInventory, error)
 	Delete(id int) error
 	GetLeftOvers(sortBy string, page, pageSize int) ([]models.InventoryLeftOverItem, int, error)
+	Transactions(filter models.InventoryTransactionFilter) ([]models.InventoryTransaction, error)
+	LowStock() ([]models.LowStockItem, error)
+}
+
+type LoyaltyRepository interface {
+	InsertRule(rule models.LoyaltyRule) (int, error)
+	RetrieveRules() ([]models.LoyaltyRule, error)
+	UpdateRule(id int, rule models.LoyaltyRule) error
+	DeleteRule(id int) error
+	InsertReward(reward models.LoyaltyReward) (int, error)
+	RetrieveRewards() ([]models.LoyaltyReward, error)
+	UpdateReward(id int, reward models.LoyaltyReward) error
+	DeleteReward(id int) error
+	Balance(customerID int) (map[string]int, error)
+	Ledger(customerID int) ([]models.LoyaltyEntry, error)
 }
 
 type MenuRepository interface {
 	InsertMenuItem(item models.MenuItem) error
-	RetrieveAll() ([]models.MenuItem, error)
+	RetrieveAll(category string) ([]models.MenuItem, error)
 	RetrieveByID(id int) (models.MenuItem, error)
+	RetrieveAvailability(threshold int) ([]models.MenuAvailability, error)
+	RetrievePriceHistory(menuID int) ([]models.PriceHistory, error)
+	InsertScheduledPriceChange(change models.ScheduledPriceChange) (int, error)
+	RetrieveScheduledPriceChanges(menuID int) ([]models.ScheduledPriceChange, error)
+	RetrieveDueScheduledPriceChanges() ([]models.ScheduledPriceChange, error)
+	DeleteScheduledPriceChange(menuID, id int) error
+	ApplyScheduledPriceChange(id int, update func(change models.ScheduledPriceChange) (models.MenuItem, error)) error
 	UpdateMenuItem(menuID int, menuItem models.MenuItem) error
 	Delete(id int) error
+	InsertCategory(category models.MenuCategory) error
+	RetrieveCategories() ([]models.MenuCategory, error)
+	UpdateCategory(id int, category models.MenuCategory) error
+	DeleteCategory(id int) error
+	InsertTaxClass(taxClass models.TaxClass) error
+	RetrieveTaxClasses() ([]models.TaxClass, error)
+	UpdateTaxClass(id int, taxClass models.TaxClass) error
+	DeleteTaxClass(id int) error
+	InsertModifierGroup(group models.ModifierGroup) error
+	RetrieveModifierGroups() ([]models.ModifierGroup, error)
+	RetrieveModifierGroupByID(id int) (models.ModifierGroup, error)
+	UpdateModifierGroup(id int, group models.ModifierGroup) error
+	DeleteModifierGroup(id int) error
+	RetrieveMenuItemModifierGroups(menuID int) ([]models.ModifierGroup, error)
+	SetMenuItemModifierGroups(menuID int, groupIDs []int) error
 }
 
 type OrderRepository interface {
 	Insert(order models.Order) (int, error)
-	RetrieveAll() ([]models.Order, error)
+	RetrieveAll(filter models.OrderFilter, sortColumn string, descending bool) ([]models.Order, int, error)
 	RetrieveByID(id int) (models.Order, error)
 	Update(orderID int, order models.Order) error
 	Delete(id int) error
-	Close(id int) error
+	RetrieveStatus(id int) (string, error)
+	UpdateStatus(id int, from, to string) error
+	Close(id int, from string, overrideBy string) error
+	Cancel(id int, from, reason string) ([]models.InventoryRestock, error)
+	StatusHistory(filter models.OrderStatusHistoryFilter) ([]models.OrderStatusHistory, error)
 	NumberOfOrderedItems(startDate string, endDate string) (map[string]int, error)
 	GetBatchTotalOrderPrice(orderID int) (float64, error)
+	PriceLines(items []models.OrderItem) ([]models.PricedLine, error)
 	GetBatchInventoryUpdates(orderIDs []int) ([]models.BatchInventoryUpdate, error)
 }
 
+type PromotionRepository interface {
+	Insert(promotion models.Promotion) (int, error)
+	RetrieveAll() ([]models.Promotion, error)
+	RetrieveForPricing(code string) ([]models.Promotion, error)
+	RetrieveByID(id int) (models.Promotion, error)
+	Update(id int, promotion models.Promotion) error
+	Delete(id int) error
+}
+
+type PaymentRepository interface {
+	Insert(payment models.Payment) (models.Payment, error)
+	RetrieveByOrder(orderID int) (models.OrderPayments, error)
+	RetrieveByID(orderID, id int) (models.Payment, error)
+	Void(orderID, id int, refund func(models.Payment) error) (models.Payment, error)
+}
+
 type ReportRepository interface {
-	GetTotalSales() (models.ReportTotalSales, error)
-	GetPopularMenuItems() ([]models.ReportPopularItem, error)
-	TextSearchMenu(query string, minPrice float64, maxPrice float64) ([]models.ReportMenuSearchItem, error)
-	TextSearchOrders(query string, minPrice float64, maxPrice float64) ([]models.ReportOrderSearchItem, error)
-	OrderedItemsByDays(month int) ([]map[string]int, error)
-	OrderedItemsByMonths(year int) ([]map[string]int, error)
+	GetTotalSales(filter models.ReportFilter) (models.ReportTotalSales, error)
+	EachSalesGroup(filter models.ReportFilter, fn func(models.ReportSalesGroup) error) error
+	GetTaxSummary(filter models.ReportFilter) ([]models.ReportTaxClass, error)
+	MenuMargins() ([]models.ReportMenuMargin, error)
+	GetPopularMenuItems(filter models.ReportFilter) ([]models.ReportPopularItem, error)
+	EachMenuMatch(query string, minPrice float64, maxPrice float64, fn func(models.ReportMenuSearchItem) error) error
+	EachOrderMatch(query string, minPrice float64, maxPrice float64, fn func(models.ReportOrderSearchItem) error) error
+	EachOrderedItemsPeriod(filter models.OrderedItemsFilter, fn func(period string, count int) error) error
+}
+
+type ShiftRepository interface {
+	Open(shift models.Shift) (models.Shift, error)
+	RetrieveAll() ([]models.Shift, error)
+	RetrieveByID(id int) (models.Shift, error)
+	RetrieveCurrent() (models.Shift, error)
+	Close(id int, close models.ShiftClose) error
+	ZReport(id int) (models.ZReport, error)
+}
+
+type SupplierRepository interface {
+	InsertSupplier(supplier models.Supplier) error
+	RetrieveSuppliers() ([]models.Supplier, error)
+	RetrieveSupplierByID(id int) (models.Supplier, error)
+	UpdateSupplier(id int, supplier models.Supplier) error
+	DeleteSupplier(id int) error
+	InsertPurchaseOrder(order models.PurchaseOrder) (int, error)
+	RetrievePurchaseOrders(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, error)
+	RetrievePurchaseOrderByID(id int) (models.PurchaseOrder, error)
+	UpdatePurchaseOrder(id int, order models.PurchaseOrder) error
+	DeletePurchaseOrder(id int) error
+	SendPurchaseOrder(id int) error
+	ReceivePurchaseOrder(id int, receipt models.PurchaseOrderReceipt) error
 }
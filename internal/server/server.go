@@ -1,35 +1,64 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"frappuccino/internal/handlers"
+	"frappuccino/internal/notifier"
+	"frappuccino/internal/payment"
 	"frappuccino/internal/service"
 )
 
 type server struct {
-	port   string
-	db     *sql.DB
-	logger *slog.Logger
+	port             string
+	db               *sql.DB
+	logger           *slog.Logger
+	lowStockNotifier notifier.Notifier
+	paymentProvider  payment.PaymentProvider
+	location         *time.Location
 }
 
-func NewServer(port string, db *sql.DB, logger *slog.Logger) *server {
+func NewServer(port string, db *sql.DB, logger *slog.Logger, lowStockNotifier notifier.Notifier, paymentProvider payment.PaymentProvider, location *time.Location) *server {
 	return &server{
-		port:   port,
-		db:     db,
-		logger: logger,
+		port:             port,
+		db:               db,
+		logger:           logger,
+		lowStockNotifier: lowStockNotifier,
+		paymentProvider:  paymentProvider,
+		location:         location,
 	}
 }
 
+// RunServer serves until the process is interrupted or terminated, then stops
+// the background jobs and lets requests in flight finish
 func (s *server) RunServer() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	menuSvc := service.NewMenuService(s.db, s.logger)
+	go menuSvc.RunPriceScheduler(ctx, time.Minute)
+
+	lowStockChecker := service.NewLowStockChecker(s.db, s.logger, s.lowStockNotifier)
+	go lowStockChecker.Run(ctx)
+
 	app := handlers.NewApplication(s.logger,
 		service.NewInventoryService(s.db, s.logger),
-		service.NewMenuService(s.db, s.logger),
-		service.NewOrderService(s.db, s.logger),
+		menuSvc,
+		service.NewOrderService(s.db, s.logger, lowStockChecker, s.location),
 		service.NewReportService(s.db, s.logger),
+		service.NewSupplierService(s.db, s.logger),
+		service.NewShiftService(s.db, s.logger),
+		service.NewCustomerService(s.db, s.logger),
+		service.NewLoyaltyService(s.db, s.logger),
+		service.NewPromotionService(s.db, s.logger),
+		service.NewPaymentService(s.db, s.logger, s.paymentProvider),
 	)
 
 	srv := &http.Server{
@@ -40,7 +69,22 @@ func (s *server) RunServer() {
 
 	s.logger.Info("starting server", "addr", srv.Addr)
 
-	err := srv.ListenAndServe()
-	s.logger.Error(err.Error())
-	os.Exit(1)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		s.logger.Error(err.Error())
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		s.logger.Error("Failed to shut down server", "error", err)
+	}
 }
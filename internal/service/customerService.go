@@ -0,0 +1,87 @@
+package service
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/repository"
+	"frappuccino/internal/repository/postgre"
+)
+
+type customerService struct {
+	customerRepo repository.CustomerRepository
+}
+
+func NewCustomerService(db *sql.DB, logger *slog.Logger) *customerService {
+	return &customerService{
+		postgre.NewCustomerRepositoryPostgres(db, logger),
+	}
+}
+
+func (s *customerService) Insert(customer models.Customer) (models.Customer, map[string]string, error) {
+	customer = normalizeCustomer(customer)
+	validator := models.NewCustomerValidator(customer)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.Customer{}, errMap, models.ErrMissingFields
+	}
+
+	id, err := s.customerRepo.Insert(customer)
+	if err != nil {
+		return models.Customer{}, nil, err
+	}
+
+	created, err := s.customerRepo.RetrieveByID(id)
+	return created, nil, err
+}
+
+func (s *customerService) RetrieveAll(search string) ([]models.Customer, error) {
+	return s.customerRepo.RetrieveAll(strings.TrimSpace(search))
+}
+
+func (s *customerService) RetrieveByID(id string) (models.Customer, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Customer{}, models.ErrInvalidID
+	}
+
+	return s.customerRepo.RetrieveByID(idInt)
+}
+
+func (s *customerService) Update(id string, customer models.Customer) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	customer = normalizeCustomer(customer)
+	validator := models.NewCustomerValidator(customer)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.customerRepo.Update(idInt, customer)
+}
+
+func (s *customerService) Delete(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.customerRepo.Delete(idInt)
+}
+
+// normalizeCustomer trims the contact details, so that the unique email and
+// phone constraints are not dodged by case or whitespace
+func normalizeCustomer(customer models.Customer) models.Customer {
+	customer.Name = strings.TrimSpace(customer.Name)
+	customer.Email = strings.ToLower(strings.TrimSpace(customer.Email))
+	customer.Phone = strings.TrimSpace(customer.Phone)
+	if customer.DefaultPreferences == nil {
+		customer.DefaultPreferences = models.Jsonb{}
+	}
+	return customer
+}
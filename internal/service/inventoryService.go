@@ -2,12 +2,15 @@ package service
 
 import (
 	"database/sql"
+	"errors"
 	"log/slog"
 	"strconv"
+	"strings"
 
 	"frappuccino/internal/models"
 	"frappuccino/internal/repository"
 	"frappuccino/internal/repository/postgre"
+	"frappuccino/internal/utils"
 )
 
 type inventoryService struct {
@@ -27,7 +30,7 @@ func (s *inventoryService) Insert(inventory models.Inventory) (map[string]string
 		return m, models.ErrMissingFields
 	}
 
-	err := s.inventoryRepo.Insert(inventory.Name, inventory.Unit, inventory.Quantity, inventory.Categories)
+	err := s.inventoryRepo.Insert(inventory)
 
 	return nil, err
 }
@@ -55,16 +58,99 @@ func (s *inventoryService) Update(inventory models.Inventory, id string) (map[st
 		return nil, models.ErrInvalidID
 	}
 
-	validator := models.NewInventoryValidator(inventory)
+	validator := models.NewInventoryMetadataValidator(inventory)
 	m := validator.Validate()
 	if len(m) > 0 {
 		return m, models.ErrMissingFields
 	}
 
-	err = s.inventoryRepo.Update(idInt, inventory.Name, inventory.Unit, inventory.Quantity, inventory.Categories)
+	err = s.inventoryRepo.Update(idInt, inventory)
 	return nil, err
 }
 
+func (s *inventoryService) Adjust(id string, adjustment models.InventoryAdjustment) (models.AdjustedInventory, map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.AdjustedInventory{}, nil, models.ErrInvalidID
+	}
+	adjustment.InventoryID = idInt
+	adjustment.Reason = strings.ToLower(adjustment.Reason)
+
+	adjustments := []models.InventoryAdjustment{adjustment}
+	units, err := s.adjustmentUnits(adjustments)
+	if err != nil {
+		return models.AdjustedInventory{}, nil, err
+	}
+
+	validator := models.NewInventoryAdjustmentValidator(adjustments...).WithUnits(units)
+	if m := validator.Validate(); m != nil {
+		return models.AdjustedInventory{}, m, models.ErrMissingFields
+	}
+	normalizeAdjustments(adjustments, units)
+
+	adjusted, err := s.inventoryRepo.Adjust(adjustments, "")
+	if err != nil {
+		return models.AdjustedInventory{}, nil, err
+	}
+
+	return adjusted[0], nil, nil
+}
+
+// BatchAdjust applies a whole delivery note or count sheet; either every item
+// is adjusted or none is
+func (s *inventoryService) BatchAdjust(batch models.BatchInventoryAdjustment) ([]models.AdjustedInventory, map[string]string, error) {
+	for i := range batch.Items {
+		if batch.Items[i].Reason == "" {
+			batch.Items[i].Reason = batch.Reason
+		}
+		if batch.Items[i].Note == "" {
+			batch.Items[i].Note = batch.Note
+		}
+		batch.Items[i].Reason = strings.ToLower(batch.Items[i].Reason)
+	}
+
+	units, err := s.adjustmentUnits(batch.Items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	validator := models.NewInventoryAdjustmentValidator(batch.Items...).WithUnits(units)
+	if m := validator.Validate(); m != nil {
+		return nil, m, models.ErrMissingFields
+	}
+	normalizeAdjustments(batch.Items, units)
+
+	adjusted, err := s.inventoryRepo.Adjust(batch.Items, "")
+	if errors.Is(err, models.ErrNoRecord) {
+		return nil, nil, models.ErrInventoryItemNotFound
+	}
+
+	return adjusted, nil, err
+}
+
+// adjustmentUnits looks up the stock units of the adjusted items
+func (s *inventoryService) adjustmentUnits(adjustments []models.InventoryAdjustment) (map[int]models.InventoryUnit, error) {
+	ids := make([]int, len(adjustments))
+	for i, adjustment := range adjustments {
+		ids[i] = adjustment.InventoryID
+	}
+
+	return s.inventoryRepo.RetrieveUnits(ids)
+}
+
+// normalizeAdjustments converts validated deltas to the items' own units
+func normalizeAdjustments(adjustments []models.InventoryAdjustment, units map[int]models.InventoryUnit) {
+	for i, adjustment := range adjustments {
+		unit, ok := units[adjustment.InventoryID]
+		if !ok {
+			continue // unknown item, rejected when the adjustment is applied
+		}
+		delta, _ := models.NormalizeQuantity(adjustment.Delta, adjustment.Unit, unit)
+		adjustments[i].Delta = float64(delta)
+		adjustments[i].Unit = unit.Unit
+	}
+}
+
 func (s *inventoryService) Delete(id string) error {
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
@@ -107,3 +193,39 @@ func (s *inventoryService) GetLeftOvers(sortBy string, page, pageSize int) (mode
 		Data:        data,
 	}, nil
 }
+
+// Transactions returns the stock movements of one inventory item
+func (s *inventoryService) Transactions(id string) ([]models.InventoryTransaction, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	if _, err := s.inventoryRepo.RetrieveByID(idInt); err != nil {
+		return nil, err
+	}
+
+	return s.inventoryRepo.Transactions(models.InventoryTransactionFilter{InventoryID: idInt})
+}
+
+// TransactionsByPeriod returns the stock movements of all inventory items,
+// optionally limited to a date range and a reason
+func (s *inventoryService) TransactionsByPeriod(from, to, reason string) ([]models.InventoryTransaction, error) {
+	filter := models.InventoryTransactionFilter{
+		From:   utils.ConvertDateFormat(from),
+		To:     utils.ConvertDateFormat(to),
+		Reason: strings.ToLower(reason),
+	}
+	if (from != "" && filter.From == "") || (to != "" && filter.To == "") {
+		return nil, models.ErrInvalidDate
+	}
+	if filter.Reason != "" && !models.IsInventoryReason(filter.Reason) {
+		return nil, models.ErrInvalidInventoryReason
+	}
+
+	return s.inventoryRepo.Transactions(filter)
+}
+
+func (s *inventoryService) LowStock() ([]models.LowStockItem, error) {
+	return s.inventoryRepo.LowStock()
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/notifier"
+	"frappuccino/internal/repository"
+	"frappuccino/internal/repository/postgre"
+)
+
+// lowStockChecker looks for inventory below its minimum quantity whenever it is
+// triggered and sends an alert for the items that have dropped below since the
+// last check. An item is alerted on again only after it was restocked.
+type lowStockChecker struct {
+	inventoryRepo repository.InventoryRepository
+	notifier      notifier.Notifier
+	logger        *slog.Logger
+	trigger       chan struct{}
+	alerted       map[int]bool
+}
+
+func NewLowStockChecker(db *sql.DB, logger *slog.Logger, n notifier.Notifier) *lowStockChecker {
+	return &lowStockChecker{
+		inventoryRepo: postgre.NewInventoryRepositoryWithPostgres(db, logger),
+		notifier:      n,
+		logger:        logger,
+		trigger:       make(chan struct{}, 1),
+		alerted:       make(map[int]bool),
+	}
+}
+
+// Trigger asks for a check without waiting for it; triggers that arrive while
+// a check is already pending are merged into it
+func (c *lowStockChecker) Trigger() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks the stock on every trigger until ctx is done
+func (c *lowStockChecker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.trigger:
+			c.check(ctx)
+		}
+	}
+}
+
+func (c *lowStockChecker) check(ctx context.Context) {
+	items, err := c.inventoryRepo.LowStock()
+	if err != nil {
+		c.logger.Error("Failed to check low stock", "error", err)
+		return
+	}
+
+	low := make(map[int]bool, len(items))
+	var newlyLow []models.LowStockItem
+	for _, item := range items {
+		low[item.InventoryID] = true
+		if !c.alerted[item.InventoryID] {
+			newlyLow = append(newlyLow, item)
+		}
+	}
+
+	if len(newlyLow) > 0 {
+		err = c.notifier.Notify(ctx, notifier.LowStockAlert{CreatedAt: time.Now(), Items: newlyLow})
+		if err != nil {
+			// keep the items unalerted so the next check tries again
+			c.logger.Error("Failed to send low stock alert", "error", err)
+			for _, item := range newlyLow {
+				delete(low, item.InventoryID)
+			}
+		}
+	}
+	c.alerted = low
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/repository"
+	"frappuccino/internal/repository/postgre"
+)
+
+type loyaltyService struct {
+	loyaltyRepo  repository.LoyaltyRepository
+	customerRepo repository.CustomerRepository
+}
+
+func NewLoyaltyService(db *sql.DB, logger *slog.Logger) *loyaltyService {
+	return &loyaltyService{
+		postgre.NewLoyaltyRepositoryPostgres(db, logger),
+		postgre.NewCustomerRepositoryPostgres(db, logger),
+	}
+}
+
+func (s *loyaltyService) InsertRule(rule models.LoyaltyRule) (models.LoyaltyRule, map[string]string, error) {
+	validator := models.NewLoyaltyRuleValidator(rule)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.LoyaltyRule{}, errMap, models.ErrMissingFields
+	}
+
+	id, err := s.loyaltyRepo.InsertRule(rule)
+	if err != nil {
+		return models.LoyaltyRule{}, nil, err
+	}
+
+	rule.ID = id
+	return rule, nil, nil
+}
+
+func (s *loyaltyService) RetrieveRules() ([]models.LoyaltyRule, error) {
+	return s.loyaltyRepo.RetrieveRules()
+}
+
+func (s *loyaltyService) UpdateRule(id string, rule models.LoyaltyRule) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	validator := models.NewLoyaltyRuleValidator(rule)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.loyaltyRepo.UpdateRule(idInt, rule)
+}
+
+func (s *loyaltyService) DeleteRule(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.loyaltyRepo.DeleteRule(idInt)
+}
+
+func (s *loyaltyService) InsertReward(reward models.LoyaltyReward) (models.LoyaltyReward, map[string]string, error) {
+	if reward.DiscountPercent == 0 {
+		reward.DiscountPercent = 100
+	}
+	validator := models.NewLoyaltyRewardValidator(reward)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.LoyaltyReward{}, errMap, models.ErrMissingFields
+	}
+
+	id, err := s.loyaltyRepo.InsertReward(reward)
+	if err != nil {
+		return models.LoyaltyReward{}, nil, err
+	}
+
+	reward.ID = id
+	return reward, nil, nil
+}
+
+func (s *loyaltyService) RetrieveRewards() ([]models.LoyaltyReward, error) {
+	return s.loyaltyRepo.RetrieveRewards()
+}
+
+func (s *loyaltyService) UpdateReward(id string, reward models.LoyaltyReward) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	if reward.DiscountPercent == 0 {
+		reward.DiscountPercent = 100
+	}
+	validator := models.NewLoyaltyRewardValidator(reward)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.loyaltyRepo.UpdateReward(idInt, reward)
+}
+
+func (s *loyaltyService) DeleteReward(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.loyaltyRepo.DeleteReward(idInt)
+}
+
+// CustomerLoyalty returns the customer's balances, the rewards they can redeem
+// now and their ledger
+func (s *loyaltyService) CustomerLoyalty(customerID string) (models.CustomerLoyalty, error) {
+	idInt, err := strconv.Atoi(customerID)
+	if err != nil {
+		return models.CustomerLoyalty{}, models.ErrInvalidID
+	}
+
+	if _, err := s.customerRepo.RetrieveByID(idInt); err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+
+	balance, err := s.loyaltyRepo.Balance(idInt)
+	if err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+
+	rewards, err := s.loyaltyRepo.RetrieveRewards()
+	if err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+	redeemable := []models.LoyaltyReward{}
+	for _, reward := range rewards {
+		if balance[reward.Unit] >= reward.Cost {
+			redeemable = append(redeemable, reward)
+		}
+	}
+
+	ledger, err := s.loyaltyRepo.Ledger(idInt)
+	if err != nil {
+		return models.CustomerLoyalty{}, err
+	}
+
+	return models.CustomerLoyalty{
+		CustomerID: idInt,
+		Points:     balance[models.LoyaltyUnitPoints],
+		Stamps:     balance[models.LoyaltyUnitStamps],
+		Redeemable: redeemable,
+		Ledger:     ledger,
+	}, nil
+}
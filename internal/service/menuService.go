@@ -1,9 +1,13 @@
 package service
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
 	"strconv"
+	"time"
 
 	"frappuccino/internal/models"
 	"frappuccino/internal/repository"
@@ -11,31 +15,143 @@ import (
 )
 
 type menuService struct {
-	menuRepo repository.MenuRepository
+	menuRepo      repository.MenuRepository
+	inventoryRepo repository.InventoryRepository
+	logger        *slog.Logger
 }
 
 func NewMenuService(db *sql.DB, logger *slog.Logger) *menuService {
 	return &menuService{
 		postgre.NewMenuRepositoryPostgres(db, logger),
+		postgre.NewInventoryRepositoryWithPostgres(db, logger),
+		logger,
 	}
 }
 
 func (s *menuService) InsertMenu(menu models.MenuItem) (map[string]string, error) {
-	validator := models.NewMenuItemValidator(menu)
+	units, err := s.recipeUnits(menu.Inventory)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := models.NewMenuItemValidator(menu).WithUnits(units)
 	if errMap := validator.Validate(); errMap != nil {
 		return errMap, models.ErrMissingFields
 	}
+	normalizeRecipe(menu.Inventory, units)
 
-	err := s.menuRepo.InsertMenuItem(menu)
+	err = s.menuRepo.InsertMenuItem(menu)
 	return nil, err
 }
 
-func (s *menuService) RetrieveAll() ([]models.MenuItem, error) {
-	menuItems, err := s.menuRepo.RetrieveAll()
+// recipeUnits looks up the stock units of the ingredients of a recipe
+func (s *menuService) recipeUnits(recipe []models.MenuItemInventory) (map[int]models.InventoryUnit, error) {
+	ids := make([]int, len(recipe))
+	for i, inv := range recipe {
+		ids[i] = inv.InventoryID
+	}
+
+	return s.inventoryRepo.RetrieveUnits(ids)
+}
+
+// modifierUnits looks up the stock units of the ingredients the group's modifiers change
+func (s *menuService) modifierUnits(group models.ModifierGroup) (map[int]models.InventoryUnit, error) {
+	var recipe []models.MenuItemInventory
+	for _, modifier := range group.Modifiers {
+		for _, inv := range modifier.Inventory {
+			recipe = append(recipe, models.MenuItemInventory{InventoryID: inv.InventoryID})
+		}
+	}
+
+	return s.recipeUnits(recipe)
+}
+
+// normalizeModifiers converts validated modifier quantities to the ingredients'
+// own units, the same way normalizeRecipe does for recipes
+func normalizeModifiers(group *models.ModifierGroup, units map[int]models.InventoryUnit) {
+	for _, modifier := range group.Modifiers {
+		for i, inv := range modifier.Inventory {
+			unit, ok := units[inv.InventoryID]
+			if !ok {
+				continue // unknown ingredient, rejected when the modifier is written
+			}
+			quantity, _ := models.NormalizeQuantity(inv.Quantity, inv.Unit, unit)
+			modifier.Inventory[i].Quantity = float64(quantity)
+			modifier.Inventory[i].Unit = unit.Unit
+		}
+	}
+}
+
+// normalizeRecipe converts validated recipe quantities to the ingredients' own units
+func normalizeRecipe(recipe []models.MenuItemInventory, units map[int]models.InventoryUnit) {
+	for i, inv := range recipe {
+		unit, ok := units[inv.InventoryID]
+		if !ok {
+			continue // unknown ingredient, rejected when the recipe is written
+		}
+		quantity, _ := models.NormalizeQuantity(inv.Quantity, inv.Unit, unit)
+		recipe[i].Quantity = float64(quantity)
+		recipe[i].Unit = unit.Unit
+	}
+}
+
+func (s *menuService) RetrieveAll(category string) ([]models.MenuItem, error) {
+	menuItems, err := s.menuRepo.RetrieveAll(category)
 
 	return menuItems, err
 }
 
+// RetrieveGrouped returns the menu split into its categories in display order.
+// Empty categories are left out and uncategorized items come last.
+func (s *menuService) RetrieveGrouped() ([]models.MenuCategoryGroup, error) {
+	categories, err := s.menuRepo.RetrieveCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	menuItems, err := s.menuRepo.RetrieveAll("")
+	if err != nil {
+		return nil, err
+	}
+
+	itemsByCategory := make(map[int][]models.MenuItem)
+	for _, item := range menuItems {
+		itemsByCategory[item.CategoryID] = append(itemsByCategory[item.CategoryID], item)
+	}
+
+	groups := []models.MenuCategoryGroup{}
+	for _, category := range categories {
+		if items, ok := itemsByCategory[category.ID]; ok {
+			groups = append(groups, models.MenuCategoryGroup{MenuCategory: category, Items: items})
+		}
+	}
+	if items, ok := itemsByCategory[0]; ok {
+		groups = append(groups, models.MenuCategoryGroup{
+			MenuCategory: models.MenuCategory{Name: "Uncategorized"},
+			Items:        items,
+		})
+	}
+
+	return groups, nil
+}
+
+// defaultAvailabilityThreshold is the number of servings at or below which an
+// item counts as about to sell out when no threshold is given
+const defaultAvailabilityThreshold = 5
+
+func (s *menuService) RetrieveAvailability(threshold string) ([]models.MenuAvailability, error) {
+	thresholdInt := defaultAvailabilityThreshold
+	if threshold != "" {
+		var err error
+		thresholdInt, err = strconv.Atoi(threshold)
+		if err != nil || thresholdInt < 0 {
+			return nil, models.ErrInvalidThreshold
+		}
+	}
+
+	return s.menuRepo.RetrieveAvailability(thresholdInt)
+}
+
 func (s *menuService) RetrieveByID(id string) (models.MenuItem, error) {
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
@@ -53,15 +169,31 @@ func (s *menuService) Update(id string, menuItem models.MenuItem) (map[string]st
 		return nil, models.ErrInvalidID
 	}
 
-	validator := models.NewMenuItemValidator(menuItem)
-	if errMap := validator.Validate(); errMap != nil {
-		return errMap, models.ErrMissingFields
+	if errMap, err := s.prepareUpdate(&menuItem); err != nil {
+		return errMap, err
 	}
 
 	err = s.menuRepo.UpdateMenuItem(idInt, menuItem)
 	return nil, err
 }
 
+// prepareUpdate validates a menu item before it replaces the stored one and
+// converts its recipe to the ingredients' own units
+func (s *menuService) prepareUpdate(menuItem *models.MenuItem) (map[string]string, error) {
+	units, err := s.recipeUnits(menuItem.Inventory)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := models.NewMenuItemValidator(*menuItem).WithUnits(units)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+	normalizeRecipe(menuItem.Inventory, units)
+
+	return nil, nil
+}
+
 func (s *menuService) Delete(id string) error {
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
@@ -71,3 +203,286 @@ func (s *menuService) Delete(id string) error {
 	err = s.menuRepo.Delete(idInt)
 	return err
 }
+
+func (s *menuService) InsertCategory(category models.MenuCategory) (map[string]string, error) {
+	validator := models.NewMenuCategoryValidator(category)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.menuRepo.InsertCategory(category)
+}
+
+func (s *menuService) RetrieveCategories() ([]models.MenuCategory, error) {
+	return s.menuRepo.RetrieveCategories()
+}
+
+func (s *menuService) UpdateCategory(id string, category models.MenuCategory) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	validator := models.NewMenuCategoryValidator(category)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.menuRepo.UpdateCategory(idInt, category)
+}
+
+func (s *menuService) DeleteCategory(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.menuRepo.DeleteCategory(idInt)
+}
+
+func (s *menuService) InsertTaxClass(taxClass models.TaxClass) (map[string]string, error) {
+	validator := models.NewTaxClassValidator(taxClass)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.menuRepo.InsertTaxClass(taxClass)
+}
+
+func (s *menuService) RetrieveTaxClasses() ([]models.TaxClass, error) {
+	return s.menuRepo.RetrieveTaxClasses()
+}
+
+func (s *menuService) UpdateTaxClass(id string, taxClass models.TaxClass) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	validator := models.NewTaxClassValidator(taxClass)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.menuRepo.UpdateTaxClass(idInt, taxClass)
+}
+
+func (s *menuService) DeleteTaxClass(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.menuRepo.DeleteTaxClass(idInt)
+}
+
+func (s *menuService) InsertModifierGroup(group models.ModifierGroup) (map[string]string, error) {
+	if group.MaxSelect == 0 {
+		group.MaxSelect = 1
+	}
+
+	units, err := s.modifierUnits(group)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := models.NewModifierGroupValidator(group).WithUnits(units)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+	normalizeModifiers(&group, units)
+
+	return nil, s.menuRepo.InsertModifierGroup(group)
+}
+
+func (s *menuService) RetrieveModifierGroups() ([]models.ModifierGroup, error) {
+	return s.menuRepo.RetrieveModifierGroups()
+}
+
+func (s *menuService) RetrieveModifierGroupByID(id string) (models.ModifierGroup, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ModifierGroup{}, models.ErrInvalidID
+	}
+
+	return s.menuRepo.RetrieveModifierGroupByID(idInt)
+}
+
+func (s *menuService) UpdateModifierGroup(id string, group models.ModifierGroup) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	if group.MaxSelect == 0 {
+		group.MaxSelect = 1
+	}
+
+	units, err := s.modifierUnits(group)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := models.NewModifierGroupValidator(group).WithUnits(units)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+	normalizeModifiers(&group, units)
+
+	return nil, s.menuRepo.UpdateModifierGroup(idInt, group)
+}
+
+func (s *menuService) DeleteModifierGroup(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.menuRepo.DeleteModifierGroup(idInt)
+}
+
+func (s *menuService) RetrieveMenuItemModifiers(menuID string) ([]models.ModifierGroup, error) {
+	idInt, err := strconv.Atoi(menuID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	// an unknown menu item is a 404 rather than an empty list
+	if _, err := s.menuRepo.RetrieveByID(idInt); err != nil {
+		return nil, err
+	}
+
+	return s.menuRepo.RetrieveMenuItemModifierGroups(idInt)
+}
+
+func (s *menuService) SetMenuItemModifiers(menuID string, groupIDs []int) error {
+	idInt, err := strconv.Atoi(menuID)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.menuRepo.SetMenuItemModifierGroups(idInt, groupIDs)
+}
+
+func (s *menuService) RetrievePriceHistory(menuID string) ([]models.PriceHistory, error) {
+	idInt, err := strconv.Atoi(menuID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	if _, err := s.menuRepo.RetrieveByID(idInt); err != nil {
+		return nil, err
+	}
+
+	return s.menuRepo.RetrievePriceHistory(idInt)
+}
+
+func (s *menuService) SchedulePriceChange(menuID string, change models.ScheduledPriceChange) (models.ScheduledPriceChange, map[string]string, error) {
+	idInt, err := strconv.Atoi(menuID)
+	if err != nil {
+		return models.ScheduledPriceChange{}, nil, models.ErrInvalidID
+	}
+
+	validator := models.NewScheduledPriceChangeValidator(change)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.ScheduledPriceChange{}, errMap, models.ErrMissingFields
+	}
+
+	change.MenuItemID = idInt
+	change.ID, err = s.menuRepo.InsertScheduledPriceChange(change)
+	if err != nil {
+		return models.ScheduledPriceChange{}, nil, err
+	}
+
+	return change, nil, nil
+}
+
+func (s *menuService) RetrieveScheduledPriceChanges(menuID string) ([]models.ScheduledPriceChange, error) {
+	idInt, err := strconv.Atoi(menuID)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	if _, err := s.menuRepo.RetrieveByID(idInt); err != nil {
+		return nil, err
+	}
+
+	return s.menuRepo.RetrieveScheduledPriceChanges(idInt)
+}
+
+func (s *menuService) DeleteScheduledPriceChange(menuID, id string) error {
+	menuIDInt, err := strconv.Atoi(menuID)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.menuRepo.DeleteScheduledPriceChange(menuIDInt, idInt)
+}
+
+// ApplyDuePriceChanges applies every pending price change whose effective date
+// has passed, as an update of the menu item with the new price. A change that
+// fails is logged and retried on the next run without holding up the others.
+// It returns the number applied.
+func (s *menuService) ApplyDuePriceChanges() (int, error) {
+	changes, err := s.menuRepo.RetrieveDueScheduledPriceChanges()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, change := range changes {
+		err := s.menuRepo.ApplyScheduledPriceChange(change.ID, s.priceChangeUpdate)
+		if err != nil {
+			if !errors.Is(err, models.ErrNoRecord) { // removed or applied meanwhile
+				s.logger.Error("Failed to apply scheduled price change", "id", change.ID, "menu_item_id", change.MenuItemID, "error", err)
+			}
+			continue
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// priceChangeUpdate is the menu item as it is stored with the change's new price
+func (s *menuService) priceChangeUpdate(change models.ScheduledPriceChange) (models.MenuItem, error) {
+	menuItem, err := s.menuRepo.RetrieveByID(change.MenuItemID)
+	if err != nil {
+		return models.MenuItem{}, err
+	}
+
+	menuItem.Price = change.NewPrice
+	if errMap, err := s.prepareUpdate(&menuItem); err != nil {
+		if errMap != nil {
+			return models.MenuItem{}, fmt.Errorf("%w: %v", err, errMap)
+		}
+		return models.MenuItem{}, err
+	}
+
+	return menuItem, nil
+}
+
+// RunPriceScheduler applies due price changes every interval until ctx is done
+func (s *menuService) RunPriceScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		applied, err := s.ApplyDuePriceChanges()
+		if err != nil {
+			s.logger.Error("Failed to apply scheduled price changes", "error", err)
+		} else if applied > 0 {
+			s.logger.Info("applied scheduled price changes", "count", applied)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
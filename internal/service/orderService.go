@@ -3,8 +3,12 @@ package service
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"frappuccino/internal/models"
 	"frappuccino/internal/repository"
@@ -12,30 +16,198 @@ import (
 	"frappuccino/internal/utils"
 )
 
+// stockChecker is told when orders have taken stock from the inventory
+type stockChecker interface {
+	Trigger()
+}
+
 type orderService struct {
-	orderRepo repository.OrderRepository
+	orderRepo     repository.OrderRepository
+	customerRepo  repository.CustomerRepository
+	promotionRepo repository.PromotionRepository
+	stockChecker  stockChecker
+	location      *time.Location // the shop's, for the daily windows of promotions
 }
 
-func NewOrderService(db *sql.DB, logger *slog.Logger) *orderService {
+func NewOrderService(db *sql.DB, logger *slog.Logger, checker stockChecker, location *time.Location) *orderService {
 	return &orderService{
 		postgre.NewOrderRepositoryPostgres(db, logger),
+		postgre.NewCustomerRepositoryPostgres(db, logger),
+		postgre.NewPromotionRepositoryPostgres(db, logger),
+		checker,
+		location,
+	}
+}
+
+// price sets the order's discounts from the promotions in force at the time
+// the order was placed and the order's promo code
+func (s *orderService) price(order *models.Order, at time.Time) error {
+	order.PromoCode = strings.ToUpper(strings.TrimSpace(order.PromoCode))
+
+	lines, err := s.orderRepo.PriceLines(order.Items)
+	if err != nil {
+		return err
+	}
+	promotions, err := s.promotionRepo.RetrieveForPricing(order.PromoCode)
+	if err != nil {
+		return err
+	}
+
+	pricing, err := priceOrder(lines, promotions, order.PromoCode, at.In(s.location))
+	if err != nil {
+		return err
+	}
+
+	for i := range order.Items {
+		order.Items[i].Discount = pricing.LineDiscounts[i]
+		order.Items[i].OrderDiscount = pricing.OrderDiscounts[i]
+	}
+	order.Discount = pricing.OrderDiscount
+	order.Promotions = pricing.Applied
+	return nil
+}
+
+// keepUnitPrices marks the lines of order that match a placed line, by menu
+// item and modifiers, to keep that line's unit price. Each placed line is
+// matched at most once.
+func keepUnitPrices(order *models.Order, placed []models.OrderItem) {
+	used := make([]bool, len(placed))
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.KeepPrice, item.UnitPrice = false, 0
+		for j, line := range placed {
+			if !used[j] && line.MenuID == item.MenuID && sameModifiers(line.Modifiers, item.Modifiers) {
+				used[j] = true
+				item.KeepPrice, item.UnitPrice = true, line.UnitPrice
+				break
+			}
+		}
 	}
 }
 
+// sameModifiers reports whether a and b hold the same modifier ids in any order
+func sameModifiers(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// applyCustomer fills in what the order leaves out from its customer: the name
+// when none is given and, for a new order, the customer's default preferences
+// under the ones given for this order. An edit keeps the preferences as sent,
+// so a default can be dropped from a single order.
+func (s *orderService) applyCustomer(order *models.Order, newOrder bool) error {
+	if order.CustomerID == nil {
+		return nil
+	}
+
+	customer, err := s.customerRepo.RetrieveByID(*order.CustomerID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return models.ErrForeignKeyConstraintCustomer
+		}
+		return err
+	}
+
+	if order.CustomerName == "" {
+		order.CustomerName = customer.Name
+	}
+	if !newOrder {
+		return nil
+	}
+	prefs := models.Jsonb{}
+	for key, value := range customer.DefaultPreferences {
+		prefs[key] = value
+	}
+	for key, value := range order.CustomerPreferences {
+		prefs[key] = value
+	}
+	order.CustomerPreferences = prefs
+
+	return nil
+}
+
 func (s *orderService) Insert(order models.Order) (map[string]string, error) {
+	if err := s.applyCustomer(&order, true); err != nil {
+		return nil, err
+	}
+
 	validator := models.NewOrderValidator(order)
 	if errMap := validator.Validate(); errMap != nil {
 		return errMap, models.ErrMissingFields
 	}
 
+	if err := s.price(&order, time.Now()); err != nil {
+		return nil, err
+	}
+
 	_, err := s.orderRepo.Insert(order)
-	return nil, err
+	if err != nil {
+		return nil, err
+	}
+
+	s.stockChecker.Trigger()
+	return nil, nil
 }
 
-func (s *orderService) RetrieveAll() ([]models.Order, error) {
-	orders, err := s.orderRepo.RetrieveAll()
+// maxOrdersPageSize caps the page size of the order list, since every order
+// comes with its lines
+const maxOrdersPageSize = 100
+
+func (s *orderService) RetrieveAll(filter models.OrderFilter) (models.OrdersResponse, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	filter.PageSize = min(filter.PageSize, maxOrdersPageSize)
+
+	from, to := utils.ConvertDateFormat(filter.From), utils.ConvertDateFormat(filter.To)
+	if (filter.From != "" && from == "") || (filter.To != "" && to == "") {
+		return models.OrdersResponse{}, models.ErrInvalidDate
+	}
+	filter.From, filter.To = from, to
+
+	filter.Status = strings.ToLower(filter.Status)
+	if filter.Status != "" && !models.IsOrderStatus(filter.Status) {
+		return models.OrdersResponse{}, models.ErrInvalidOrderStatus
+	}
 
-	return orders, err
+	if filter.Sort == "" {
+		filter.Sort = "-created_at"
+	}
+	descending := strings.HasPrefix(filter.Sort, "-")
+	var sortColumn string
+	switch strings.TrimPrefix(filter.Sort, "-") {
+	case "created_at":
+		sortColumn = "o.created_at"
+	case "id":
+		sortColumn = "o.id"
+	case "customer_name":
+		sortColumn = "o.customer_name"
+	case "status":
+		sortColumn = "o.order_status"
+	default:
+		return models.OrdersResponse{}, models.ErrInvalidSortOption
+	}
+
+	orders, totalPages, err := s.orderRepo.RetrieveAll(filter, sortColumn, descending)
+	if err != nil {
+		return models.OrdersResponse{}, err
+	}
+
+	return models.OrdersResponse{
+		CurrentPage: filter.Page,
+		PageSize:    filter.PageSize,
+		TotalPages:  totalPages,
+		HasNextPage: filter.Page < totalPages,
+		Data:        orders,
+	}, nil
 }
 
 func (s *orderService) RetrieveByID(id string) (models.Order, error) {
@@ -55,12 +227,34 @@ func (s *orderService) Update(id string, order models.Order) (map[string]string,
 		return nil, models.ErrInvalidID
 	}
 
+	if err := s.applyCustomer(&order, false); err != nil {
+		return nil, err
+	}
+
 	validator := models.NewOrderValidator(order)
 	if errMap := validator.Validate(); errMap != nil {
 		return errMap, models.ErrMissingFields
 	}
 
-	return nil, s.orderRepo.Update(idInt, order)
+	// an edit is priced as the order was placed: under the promotions in
+	// force then, and with the lines it leaves in place at their stored
+	// unit price. Only lines it adds or changes take today's menu price.
+	placed, err := s.orderRepo.RetrieveByID(idInt)
+	if err != nil {
+		return nil, err
+	}
+	keepUnitPrices(&order, placed.Items)
+	if err := s.price(&order, placed.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	err = s.orderRepo.Update(idInt, order)
+	if err != nil {
+		return nil, err
+	}
+
+	s.stockChecker.Trigger()
+	return nil, nil
 }
 
 func (s *orderService) Delete(id string) error {
@@ -72,13 +266,131 @@ func (s *orderService) Delete(id string) error {
 	return s.orderRepo.Delete(idInt)
 }
 
-func (s *orderService) Close(id string) error {
+// Start marks an open order as being made at the bar
+func (s *orderService) Start(id string) error {
+	return s.transition(id, models.OrderStatusInProgress)
+}
+
+// Close completes an open or in progress order and credits the customer's
+// loyalty balance
+func (s *orderService) Close(id string, close models.OrderClose) (map[string]string, error) {
+	close.Manager = strings.TrimSpace(close.Manager)
+	validator := models.NewOrderCloseValidator(close)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	idInt, from, err := s.checkTransition(id, models.OrderStatusClosed)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrideBy string
+	if close.ManagerOverride {
+		overrideBy = close.Manager
+	}
+	return nil, s.orderRepo.Close(idInt, from, overrideBy)
+}
+
+// Cancel voids an order that has not been completed yet and returns the
+// ingredients it consumed to the inventory
+func (s *orderService) Cancel(id string, reason string) (models.OrderCancellation, error) {
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
-		return models.ErrInvalidID
+		return models.OrderCancellation{}, models.ErrInvalidID
 	}
 
-	return s.orderRepo.Close(idInt)
+	return s.cancel(idInt, reason)
+}
+
+func (s *orderService) cancel(id int, reason string) (models.OrderCancellation, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return models.OrderCancellation{}, models.ErrMissingCancelReason
+	}
+
+	from, err := s.orderRepo.RetrieveStatus(id)
+	if err != nil {
+		return models.OrderCancellation{}, err
+	}
+
+	if !models.CanTransitionOrderStatus(from, models.OrderStatusCancelled) {
+		return models.OrderCancellation{}, &models.OrderStatusTransitionError{From: from, To: models.OrderStatusCancelled}
+	}
+
+	restocked, err := s.orderRepo.Cancel(id, from, reason)
+	if err != nil {
+		return models.OrderCancellation{}, err
+	}
+
+	return models.OrderCancellation{
+		OrderID:   id,
+		Reason:    reason,
+		Restocked: restocked,
+	}, nil
+}
+
+// Reopen puts an in progress or closed order back into the open queue
+func (s *orderService) Reopen(id string) error {
+	return s.transition(id, models.OrderStatusOpen)
+}
+
+func (s *orderService) transition(id string, to string) error {
+	idInt, from, err := s.checkTransition(id, to)
+	if err != nil {
+		return err
+	}
+
+	return s.orderRepo.UpdateStatus(idInt, from, to)
+}
+
+// checkTransition returns the order's id and current status if it may move to status to
+func (s *orderService) checkTransition(id string, to string) (int, string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, "", models.ErrInvalidID
+	}
+
+	from, err := s.orderRepo.RetrieveStatus(idInt)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if !models.CanTransitionOrderStatus(from, to) {
+		return 0, "", &models.OrderStatusTransitionError{From: from, To: to}
+	}
+
+	return idInt, from, nil
+}
+
+func (s *orderService) History(id string) ([]models.OrderStatusHistory, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	// make sure the order exists so an unknown id is a 404 rather than an empty list
+	if _, err := s.orderRepo.RetrieveStatus(idInt); err != nil {
+		return nil, err
+	}
+
+	return s.orderRepo.StatusHistory(models.OrderStatusHistoryFilter{OrderID: idInt})
+}
+
+func (s *orderService) StatusHistory(from, to, status string) ([]models.OrderStatusHistory, error) {
+	filter := models.OrderStatusHistoryFilter{
+		From:   utils.ConvertDateFormat(from),
+		To:     utils.ConvertDateFormat(to),
+		Status: strings.ToLower(status),
+	}
+	if (from != "" && filter.From == "") || (to != "" && filter.To == "") {
+		return nil, models.ErrInvalidDate
+	}
+	if filter.Status != "" && !models.IsOrderStatus(filter.Status) {
+		return nil, models.ErrInvalidOrderStatus
+	}
+
+	return s.orderRepo.StatusHistory(filter)
 }
 
 func (s *orderService) NumberOfOrderedItems(startDate string, endDate string) (map[string]int, error) {
@@ -94,7 +406,18 @@ func (s *orderService) BatchOrderProcess(orders []models.Order) (models.BatchOrd
 
 	for _, order := range orders {
 		var processedOrder models.BatchProcessedOrder
+		err := s.applyCustomer(&order, true)
 		processedOrder.CustomerName = order.CustomerName
+		if err != nil {
+			processedOrder.Status = "rejected"
+			processedOrder.Reason = "customer does not exist"
+			if !errors.Is(err, models.ErrForeignKeyConstraintCustomer) {
+				processedOrder.Reason = "internal server error"
+			}
+			batchOrderResponse.ProcessedOrders = append(batchOrderResponse.ProcessedOrders, processedOrder)
+			continue
+		}
+
 		validator := models.NewOrderValidator(order)
 		if errMap := validator.Validate(); errMap != nil {
 			processedOrder.Status = "rejected"
@@ -103,15 +426,33 @@ func (s *orderService) BatchOrderProcess(orders []models.Order) (models.BatchOrd
 			continue
 		}
 
-		orderID, err := s.orderRepo.Insert(order)
+		var orderID int
+		err = s.price(&order, time.Now())
+		if err == nil {
+			orderID, err = s.orderRepo.Insert(order)
+		}
 		processedOrder.ID = orderID
 		if err != nil {
 			processedOrder.Status = "rejected"
 			switch {
 			case errors.Is(err, models.ErrForeignKeyConstraintOrderMenu):
 				processedOrder.Reason = "menu item does not exist"
+			case errors.Is(err, models.ErrForeignKeyConstraintCustomer):
+				processedOrder.Reason = "customer does not exist"
 			case errors.Is(err, models.ErrNegativeQuantity):
 				processedOrder.Reason = "insufficient inventory"
+			case errors.Is(err, models.ErrInvalidModifier),
+				errors.Is(err, models.ErrModifierLimitExceeded),
+				errors.Is(err, models.ErrMissingRequiredModifier),
+				errors.Is(err, models.ErrForeignKeyConstraintReward),
+				errors.Is(err, models.ErrRewardNotApplicable),
+				errors.Is(err, models.ErrRewardNeedsCustomer),
+				errors.Is(err, models.ErrInsufficientLoyalty),
+				errors.Is(err, models.ErrInvalidPromoCode),
+				errors.Is(err, models.ErrPromoCodeNotActive),
+				errors.Is(err, models.ErrPromoCodeNotApplicable),
+				errors.Is(err, models.ErrPromoCodeLimitReached):
+				processedOrder.Reason = err.Error()
 			default:
 				processedOrder.Reason = "internal server error"
 			}
@@ -135,6 +476,9 @@ func (s *orderService) BatchOrderProcess(orders []models.Order) (models.BatchOrd
 		}
 	}
 	batchOrderResponse.Summary.Rejected = batchOrderResponse.Summary.TotalOrders - batchOrderResponse.Summary.Accepted
+	if batchOrderResponse.Summary.Accepted > 0 {
+		s.stockChecker.Trigger()
+	}
 	inventoryUpdates, err := s.orderRepo.GetBatchInventoryUpdates(orderIDs)
 	if err != nil {
 		slog.Error("Failed to get inventory updates", "error", err)
@@ -142,3 +486,63 @@ func (s *orderService) BatchOrderProcess(orders []models.Order) (models.BatchOrd
 	batchOrderResponse.Summary.InventoryUpdates = append(batchOrderResponse.Summary.InventoryUpdates, inventoryUpdates...)
 	return batchOrderResponse, err
 }
+
+// BatchCancel cancels every order of the request with the same reason. Each
+// order is cancelled in its own transaction, so one rejected order does not
+// keep the others from being cancelled and restocked.
+func (s *orderService) BatchCancel(request models.BatchCancelRequest) (models.BatchCancelResponse, error) {
+	if strings.TrimSpace(request.Reason) == "" {
+		return models.BatchCancelResponse{}, models.ErrMissingCancelReason
+	}
+
+	var response models.BatchCancelResponse
+	response.Summary.TotalOrders = len(request.OrderIDs)
+
+	inventoryUpdates := make(map[int]*models.InventoryRestock)
+	var inventoryOrder []int
+	for _, orderID := range request.OrderIDs {
+		processedOrder := models.BatchCancelledOrder{ID: orderID}
+
+		cancellation, err := s.cancel(orderID, request.Reason)
+		if err != nil {
+			processedOrder.Status = "rejected"
+			var transitionErr *models.OrderStatusTransitionError
+			switch {
+			case errors.Is(err, models.ErrNoRecord):
+				processedOrder.Reason = "order does not exist"
+			case errors.As(err, &transitionErr):
+				processedOrder.Reason = fmt.Sprintf("order is %s", transitionErr.From)
+			case errors.Is(err, models.ErrOrderHasPayments):
+				processedOrder.Reason = err.Error()
+			default:
+				processedOrder.Reason = "internal server error"
+			}
+			response.ProcessedOrders = append(response.ProcessedOrders, processedOrder)
+			continue
+		}
+
+		processedOrder.Status = models.OrderStatusCancelled
+		processedOrder.Restocked = cancellation.Restocked
+		response.Summary.Cancelled++
+		response.ProcessedOrders = append(response.ProcessedOrders, processedOrder)
+
+		for _, restock := range cancellation.Restocked {
+			update, ok := inventoryUpdates[restock.InventoryID]
+			if !ok {
+				update = &models.InventoryRestock{InventoryID: restock.InventoryID, Name: restock.Name}
+				inventoryUpdates[restock.InventoryID] = update
+				inventoryOrder = append(inventoryOrder, restock.InventoryID)
+			}
+			update.Quantity += restock.Quantity
+			update.Remaining = restock.Remaining
+		}
+	}
+
+	response.Summary.Rejected = response.Summary.TotalOrders - response.Summary.Cancelled
+	response.Summary.InventoryUpdates = []models.InventoryRestock{}
+	for _, inventoryID := range inventoryOrder {
+		response.Summary.InventoryUpdates = append(response.Summary.InventoryUpdates, *inventoryUpdates[inventoryID])
+	}
+
+	return response, nil
+}
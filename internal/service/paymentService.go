@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/payment"
+	"frappuccino/internal/repository"
+	"frappuccino/internal/repository/postgre"
+)
+
+type paymentService struct {
+	paymentRepo repository.PaymentRepository
+	provider    payment.PaymentProvider
+	logger      *slog.Logger
+}
+
+func NewPaymentService(db *sql.DB, logger *slog.Logger, provider payment.PaymentProvider) *paymentService {
+	return &paymentService{
+		postgre.NewPaymentRepositoryPostgres(db, logger),
+		provider,
+		logger,
+	}
+}
+
+// Insert pays toward an order. Without an amount it pays what is due. Cash
+// gives change for what was tendered beyond the amount and tip; a card is
+// charged the amount and tip through the payment provider, and refunded if
+// the payment cannot be stored.
+func (s *paymentService) Insert(ctx context.Context, orderID string, p models.Payment) (models.Payment, map[string]string, error) {
+	orderIDInt, err := strconv.Atoi(orderID)
+	if err != nil {
+		return models.Payment{}, nil, models.ErrInvalidID
+	}
+
+	p.OrderID = orderIDInt
+	p.Tender = strings.ToLower(strings.TrimSpace(p.Tender))
+	p.Reference = strings.TrimSpace(p.Reference)
+	p.Amount, p.Tip, p.Tendered = roundMoney(p.Amount), roundMoney(p.Tip), roundMoney(p.Tendered)
+	validator := models.NewPaymentValidator(p)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.Payment{}, errMap, models.ErrMissingFields
+	}
+
+	if p.Amount == 0 {
+		bill, err := s.RetrieveByOrder(orderID)
+		if err != nil {
+			return models.Payment{}, nil, err
+		}
+		if bill.Due <= 0 {
+			return models.Payment{}, nil, models.ErrOrderAlreadyPaid
+		}
+		p.Amount = bill.Due
+	}
+
+	switch p.Tender {
+	case models.PaymentTenderCash:
+		if p.Tendered == 0 {
+			p.Tendered = roundMoney(p.Amount + p.Tip)
+		}
+		p.ChangeDue = roundMoney(p.Tendered - p.Amount - p.Tip)
+		if p.ChangeDue < 0 {
+			return models.Payment{}, nil, models.ErrInsufficientTender
+		}
+
+	case models.PaymentTenderCard:
+		authorization, err := s.provider.Charge(ctx, p.Card, roundMoney(p.Amount+p.Tip))
+		if err != nil {
+			return models.Payment{}, nil, err
+		}
+		p.Reference = authorization
+	}
+
+	stored, err := s.paymentRepo.Insert(p)
+	if err != nil {
+		if p.Tender == models.PaymentTenderCard {
+			if refundErr := s.provider.Refund(ctx, p.Reference, roundMoney(p.Amount+p.Tip)); refundErr != nil {
+				s.logger.Error("Failed to refund card payment", "authorization", p.Reference, "error", refundErr)
+			}
+		}
+		return models.Payment{}, nil, err
+	}
+
+	return stored, nil, nil
+}
+
+// RetrieveByOrder returns the order's bill: its payments and what is paid,
+// tipped and still due
+func (s *paymentService) RetrieveByOrder(orderID string) (models.OrderPayments, error) {
+	orderIDInt, err := strconv.Atoi(orderID)
+	if err != nil {
+		return models.OrderPayments{}, models.ErrInvalidID
+	}
+
+	bill, err := s.paymentRepo.RetrieveByOrder(orderIDInt)
+	if err != nil {
+		return models.OrderPayments{}, err
+	}
+
+	for _, p := range bill.Payments {
+		if p.VoidedAt != nil {
+			continue
+		}
+		bill.Paid = roundMoney(bill.Paid + p.Amount)
+		bill.Tips = roundMoney(bill.Tips + p.Tip)
+	}
+	bill.Due = roundMoney(bill.GrossTotal - bill.Paid)
+
+	return bill, nil
+}
+
+// Void takes back a payment of an order that is not closed yet, refunding a
+// card payment through the payment provider before the void is committed
+func (s *paymentService) Void(ctx context.Context, orderID, id string) (models.Payment, error) {
+	orderIDInt, err := strconv.Atoi(orderID)
+	if err != nil {
+		return models.Payment{}, models.ErrInvalidID
+	}
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Payment{}, models.ErrInvalidID
+	}
+
+	return s.paymentRepo.Void(orderIDInt, idInt, func(p models.Payment) error {
+		if p.Tender != models.PaymentTenderCard {
+			return nil
+		}
+		return s.provider.Refund(ctx, p.Reference, roundMoney(p.Amount+p.Tip))
+	})
+}
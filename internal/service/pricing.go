@@ -0,0 +1,224 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+// priceOrder works out the discounts on an order's lines for an order placed
+// at time at. Promotions without a code do not stack: each line gets the
+// largest line promotion that covers it and the order the largest order-wide
+// one. The promotion with the given code, if any, applies on top of them.
+// No line is ever discounted below zero.
+//
+// priceOrder only works on its arguments, so any set of lines and promotions
+// can be priced without a database.
+func priceOrder(lines []models.PricedLine, promotions []models.Promotion, code string, at time.Time) (models.OrderPricing, error) {
+	pricing := models.OrderPricing{
+		LineDiscounts:  make([]float64, len(lines)),
+		OrderDiscounts: make([]float64, len(lines)),
+		Applied:        []models.AppliedPromotion{},
+	}
+	remaining := make([]float64, len(lines))
+	for i, line := range lines {
+		remaining[i] = roundMoney(float64(line.Quantity) * line.UnitPrice)
+	}
+
+	var coded *models.Promotion
+	var automatic []models.Promotion
+	for i, promotion := range promotions {
+		switch {
+		case code != "" && strings.EqualFold(promotion.Code, code):
+			coded = &promotions[i]
+		case promotion.Code == "" && promotion.ActiveAt(at):
+			automatic = append(automatic, promotion)
+		}
+	}
+	if code != "" {
+		if coded == nil {
+			return models.OrderPricing{}, models.ErrInvalidPromoCode
+		}
+		if !coded.ActiveAt(at) {
+			return models.OrderPricing{}, models.ErrPromoCodeNotActive
+		}
+	}
+
+	apply := func(promotion models.Promotion, amount float64) {
+		for i, applied := range pricing.Applied {
+			if applied.PromotionID == promotion.ID {
+				pricing.Applied[i].Amount = roundMoney(applied.Amount + amount)
+				return
+			}
+		}
+		pricing.Applied = append(pricing.Applied, models.AppliedPromotion{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			Code:        promotion.Code,
+			Amount:      amount,
+		})
+	}
+	discountLine := func(i int, amount float64) {
+		pricing.LineDiscounts[i] = roundMoney(pricing.LineDiscounts[i] + amount)
+		remaining[i] = roundMoney(remaining[i] - amount)
+	}
+
+	// the largest automatic line promotion of each line
+	best := make([]float64, len(lines))
+	bestPromotion := make([]int, len(lines))
+	for j, promotion := range automatic {
+		if !promotion.OnLines() {
+			continue
+		}
+		for i, amount := range lineDiscounts(promotion, lines, remaining) {
+			if amount > best[i] {
+				best[i], bestPromotion[i] = amount, j
+			}
+		}
+	}
+	for i, amount := range best {
+		if amount > 0 {
+			discountLine(i, amount)
+			apply(automatic[bestPromotion[i]], amount)
+		}
+	}
+
+	if coded != nil {
+		var amount float64
+		if coded.OnLines() {
+			for i, lineAmount := range lineDiscounts(*coded, lines, remaining) {
+				discountLine(i, lineAmount)
+				amount = roundMoney(amount + lineAmount)
+			}
+		} else {
+			amount = spreadOrderDiscount(orderDiscount(*coded, remaining), remaining, pricing.OrderDiscounts)
+		}
+		if amount <= 0 {
+			return models.OrderPricing{}, models.ErrPromoCodeNotApplicable
+		}
+		apply(*coded, amount)
+	}
+
+	// the largest automatic order-wide promotion
+	var bestOrder *models.Promotion
+	var bestAmount float64
+	for j, promotion := range automatic {
+		if promotion.OnLines() {
+			continue
+		}
+		if amount := orderDiscount(promotion, remaining); amount > bestAmount {
+			bestOrder, bestAmount = &automatic[j], amount
+		}
+	}
+	if bestOrder != nil {
+		apply(*bestOrder, spreadOrderDiscount(bestAmount, remaining, pricing.OrderDiscounts))
+	}
+
+	for _, share := range pricing.OrderDiscounts {
+		pricing.OrderDiscount = roundMoney(pricing.OrderDiscount + share)
+	}
+	return pricing, nil
+}
+
+// lineDiscounts returns what a line promotion takes off each line, given what
+// is left to pay on each line
+func lineDiscounts(promotion models.Promotion, lines []models.PricedLine, remaining []float64) []float64 {
+	amounts := make([]float64, len(lines))
+	switch promotion.Kind {
+	case models.PromotionKindPercent:
+		for i, line := range lines {
+			if promotion.Matches(line.MenuID, line.CategoryID) {
+				amounts[i] = roundMoney(remaining[i] * promotion.Value / 100)
+			}
+		}
+
+	case models.PromotionKindFixed:
+		for i, line := range lines {
+			if promotion.Matches(line.MenuID, line.CategoryID) {
+				amounts[i] = min(roundMoney(promotion.Value*float64(line.Quantity)), remaining[i])
+			}
+		}
+
+	case models.PromotionKindBuyXGetY:
+		// of the matching units, the cheapest ones are the free ones
+		var matching []int
+		units := 0
+		for i, line := range lines {
+			if promotion.Matches(line.MenuID, line.CategoryID) {
+				matching = append(matching, i)
+				units += line.Quantity
+			}
+		}
+		slices.SortStableFunc(matching, func(a, b int) int {
+			return cmp.Compare(lines[a].UnitPrice, lines[b].UnitPrice)
+		})
+
+		free := units / (promotion.BuyQuantity + promotion.GetQuantity) * promotion.GetQuantity
+		for _, i := range matching {
+			if free == 0 {
+				break
+			}
+			discounted := min(free, lines[i].Quantity)
+			free -= discounted
+			amounts[i] = min(roundMoney(float64(discounted)*lines[i].UnitPrice*promotion.Value/100), remaining[i])
+		}
+	}
+
+	return amounts
+}
+
+// orderDiscount returns what an order-wide promotion takes off the order,
+// given what is left to pay on each line
+func orderDiscount(promotion models.Promotion, remaining []float64) float64 {
+	var total float64
+	for _, amount := range remaining {
+		total += amount
+	}
+
+	switch promotion.Kind {
+	case models.PromotionKindPercent:
+		return roundMoney(total * promotion.Value / 100)
+	case models.PromotionKindFixed:
+		return roundMoney(min(promotion.Value, total))
+	}
+	return 0
+}
+
+// spreadOrderDiscount shares an order-wide discount out over the lines in
+// proportion to what is left to pay on each, adding each line's share to
+// shares. The last line takes the rounding difference. It returns the amount
+// shared out.
+func spreadOrderDiscount(amount float64, remaining []float64, shares []float64) float64 {
+	var total float64
+	last := -1
+	for i, left := range remaining {
+		total += left
+		if left > 0 {
+			last = i
+		}
+	}
+	if amount <= 0 || last < 0 {
+		return 0
+	}
+
+	var spread float64
+	for i, left := range remaining {
+		if left <= 0 {
+			continue
+		}
+		share := roundMoney(amount * left / total)
+		if i == last {
+			share = roundMoney(amount - spread)
+		}
+		share = max(0, min(share, left))
+
+		shares[i] = roundMoney(shares[i] + share)
+		remaining[i] = roundMoney(left - share)
+		spread = roundMoney(spread + share)
+	}
+
+	return spread
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+func TestPriceOrder(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	timePtr := func(t time.Time) *time.Time { return &t }
+	at := time.Date(2025, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	// two lines worth 6.00 and 4.50
+	lines := []models.PricedLine{
+		{MenuID: 1, CategoryID: 10, Quantity: 2, UnitPrice: 3},
+		{MenuID: 2, CategoryID: 20, Quantity: 1, UnitPrice: 4.5},
+	}
+
+	tests := []struct {
+		name       string
+		lines      []models.PricedLine
+		promotions []models.Promotion
+		code       string
+		at         time.Time
+		wantLine   []float64
+		wantOrder  []float64
+		wantIDs    []int // promotions applied, in the order applied
+		wantErr    error
+	}{
+		{
+			name:       "percent off a line",
+			lines:      lines,
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(1)}},
+			wantLine:   []float64{0.6, 0},
+			wantOrder:  []float64{0, 0},
+			wantIDs:    []int{1},
+		},
+		{
+			name:       "fixed off every unit of a category",
+			lines:      lines,
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindFixed, Value: 0.5, CategoryID: intPtr(20)}},
+			wantLine:   []float64{0, 0.5},
+			wantOrder:  []float64{0, 0},
+			wantIDs:    []int{1},
+		},
+		{
+			name:       "fixed off a line stops at the line total",
+			lines:      lines,
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindFixed, Value: 5, MenuID: intPtr(1)}},
+			wantLine:   []float64{6, 0},
+			wantOrder:  []float64{0, 0},
+			wantIDs:    []int{1},
+		},
+		{
+			name:       "percent off the order",
+			lines:      lines,
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindPercent, Value: 10}},
+			wantLine:   []float64{0, 0},
+			wantOrder:  []float64{0.6, 0.45},
+			wantIDs:    []int{1},
+		},
+		{
+			name:       "fixed off the order",
+			lines:      lines,
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindFixed, Value: 2}},
+			wantLine:   []float64{0, 0},
+			wantOrder:  []float64{1.14, 0.86},
+			wantIDs:    []int{1},
+		},
+		{
+			name:  "automatic promotions do not stack",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(1)},
+				{ID: 2, Kind: models.PromotionKindFixed, Value: 0.5, MenuID: intPtr(1)},
+				{ID: 3, Kind: models.PromotionKindPercent, Value: 10},
+				{ID: 4, Kind: models.PromotionKindFixed, Value: 2},
+			},
+			wantLine:  []float64{1, 0},
+			wantOrder: []float64{1.05, 0.95},
+			wantIDs:   []int{2, 4},
+		},
+		{
+			name:  "line promo code applies on top",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(1)},
+				{ID: 2, Kind: models.PromotionKindPercent, Value: 50, MenuID: intPtr(2), Code: "HALF"},
+			},
+			code:      "half",
+			wantLine:  []float64{0.6, 2.25},
+			wantOrder: []float64{0, 0},
+			wantIDs:   []int{1, 2},
+		},
+		{
+			name:  "order promo code applies before the automatic order promotion",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10},
+				{ID: 2, Kind: models.PromotionKindFixed, Value: 1, Code: "ONE"},
+			},
+			code:      "ONE",
+			wantLine:  []float64{0, 0},
+			wantOrder: []float64{1.11, 0.84},
+			wantIDs:   []int{2, 1},
+		},
+		{
+			name:       "promotions with a code need the code",
+			lines:      lines,
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindPercent, Value: 10, Code: "TEN"}},
+			wantLine:   []float64{0, 0},
+			wantOrder:  []float64{0, 0},
+			wantIDs:    []int{},
+		},
+		{
+			name:    "unknown code",
+			lines:   lines,
+			code:    "NOPE",
+			wantErr: models.ErrInvalidPromoCode,
+		},
+		{
+			name:  "expired code",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, Code: "OLD", ExpiresAt: timePtr(at.Add(-time.Hour))},
+			},
+			code:    "OLD",
+			wantErr: models.ErrPromoCodeNotActive,
+		},
+		{
+			name:  "code not started yet",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, Code: "SOON", StartsAt: timePtr(at.Add(time.Hour))},
+			},
+			code:    "SOON",
+			wantErr: models.ErrPromoCodeNotActive,
+		},
+		{
+			name:  "code matching no line",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(99), Code: "OTHER"},
+			},
+			code:    "OTHER",
+			wantErr: models.ErrPromoCodeNotApplicable,
+		},
+		{
+			name:  "daily window across midnight, before midnight",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(1), DailyFrom: "22:00", DailyTo: "02:00"},
+			},
+			at:        time.Date(2025, 3, 14, 23, 30, 0, 0, time.UTC),
+			wantLine:  []float64{0.6, 0},
+			wantOrder: []float64{0, 0},
+			wantIDs:   []int{1},
+		},
+		{
+			name:  "daily window across midnight, after midnight",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(1), DailyFrom: "22:00", DailyTo: "02:00"},
+			},
+			at:        time.Date(2025, 3, 15, 1, 59, 0, 0, time.UTC),
+			wantLine:  []float64{0.6, 0},
+			wantOrder: []float64{0, 0},
+			wantIDs:   []int{1},
+		},
+		{
+			name:  "daily window across midnight, at its end",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(1), DailyFrom: "22:00", DailyTo: "02:00"},
+			},
+			at:        time.Date(2025, 3, 15, 2, 0, 0, 0, time.UTC),
+			wantLine:  []float64{0, 0},
+			wantOrder: []float64{0, 0},
+			wantIDs:   []int{},
+		},
+		{
+			name:  "daily window across midnight, outside it",
+			lines: lines,
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindPercent, Value: 10, MenuID: intPtr(1), DailyFrom: "22:00", DailyTo: "02:00"},
+			},
+			wantLine:  []float64{0, 0},
+			wantOrder: []float64{0, 0},
+			wantIDs:   []int{},
+		},
+		{
+			name: "buy one get one frees the cheapest units",
+			lines: []models.PricedLine{
+				{MenuID: 1, CategoryID: 4, Quantity: 2, UnitPrice: 3},
+				{MenuID: 2, CategoryID: 4, Quantity: 1, UnitPrice: 2},
+				{MenuID: 3, CategoryID: 4, Quantity: 1, UnitPrice: 4},
+			},
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindBuyXGetY, Value: 100, CategoryID: intPtr(4), BuyQuantity: 1, GetQuantity: 1},
+			},
+			wantLine:  []float64{3, 2, 0},
+			wantOrder: []float64{0, 0, 0},
+			wantIDs:   []int{1},
+		},
+		{
+			name: "buy two get one half off",
+			lines: []models.PricedLine{
+				{MenuID: 1, CategoryID: 4, Quantity: 5, UnitPrice: 3},
+				{MenuID: 2, CategoryID: 4, Quantity: 1, UnitPrice: 2},
+				{MenuID: 3, CategoryID: 5, Quantity: 3, UnitPrice: 1},
+			},
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindBuyXGetY, Value: 50, CategoryID: intPtr(4), BuyQuantity: 2, GetQuantity: 1},
+			},
+			wantLine:  []float64{1.5, 1, 0},
+			wantOrder: []float64{0, 0, 0},
+			wantIDs:   []int{1},
+		},
+		{
+			name: "order discount spread leaves no rounding remainder",
+			lines: []models.PricedLine{
+				{MenuID: 1, Quantity: 1, UnitPrice: 1},
+				{MenuID: 2, Quantity: 1, UnitPrice: 1},
+				{MenuID: 3, Quantity: 1, UnitPrice: 1},
+			},
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindFixed, Value: 1}},
+			wantLine:   []float64{0, 0, 0},
+			wantOrder:  []float64{0.33, 0.33, 0.34},
+			wantIDs:    []int{1},
+		},
+		{
+			name: "order discount skips fully discounted lines",
+			lines: []models.PricedLine{
+				{MenuID: 1, Quantity: 1, UnitPrice: 1},
+				{MenuID: 2, Quantity: 1, UnitPrice: 2},
+			},
+			promotions: []models.Promotion{
+				{ID: 1, Kind: models.PromotionKindFixed, Value: 5, MenuID: intPtr(1)},
+				{ID: 2, Kind: models.PromotionKindFixed, Value: 5},
+			},
+			wantLine:  []float64{1, 0},
+			wantOrder: []float64{0, 2},
+			wantIDs:   []int{1, 2},
+		},
+		{
+			name: "order discount on tiny lines",
+			lines: []models.PricedLine{
+				{MenuID: 1, Quantity: 1, UnitPrice: 0.01},
+				{MenuID: 2, Quantity: 1, UnitPrice: 0.01},
+				{MenuID: 3, Quantity: 1, UnitPrice: 0.01},
+			},
+			promotions: []models.Promotion{{ID: 1, Kind: models.PromotionKindFixed, Value: 0.02}},
+			wantLine:   []float64{0, 0, 0},
+			wantOrder:  []float64{0.01, 0.01, 0},
+			wantIDs:    []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.at.IsZero() {
+				tt.at = at
+			}
+			pricing, err := priceOrder(tt.lines, tt.promotions, tt.code, tt.at)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equalMoney(pricing.LineDiscounts, tt.wantLine) {
+				t.Errorf("line discounts = %v, want %v", pricing.LineDiscounts, tt.wantLine)
+			}
+			if !equalMoney(pricing.OrderDiscounts, tt.wantOrder) {
+				t.Errorf("order discounts = %v, want %v", pricing.OrderDiscounts, tt.wantOrder)
+			}
+
+			ids := []int{}
+			var applied float64
+			for _, promotion := range pricing.Applied {
+				ids = append(ids, promotion.PromotionID)
+				applied += promotion.Amount
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("applied promotions = %v, want %v", ids, tt.wantIDs)
+			}
+
+			// the shares add up to the order discount, and no line goes below zero
+			var shares, discounts float64
+			for i, line := range tt.lines {
+				shares += pricing.OrderDiscounts[i]
+				discounts += pricing.LineDiscounts[i] + pricing.OrderDiscounts[i]
+				left := roundMoney(float64(line.Quantity)*line.UnitPrice - pricing.LineDiscounts[i] - pricing.OrderDiscounts[i])
+				if pricing.LineDiscounts[i] < 0 || pricing.OrderDiscounts[i] < 0 || left < 0 {
+					t.Errorf("line %d: discounts %v and %v leave %v", i, pricing.LineDiscounts[i], pricing.OrderDiscounts[i], left)
+				}
+			}
+			if roundMoney(shares) != pricing.OrderDiscount {
+				t.Errorf("order discount shares add up to %v, want %v", roundMoney(shares), pricing.OrderDiscount)
+			}
+			if roundMoney(applied) != roundMoney(discounts) {
+				t.Errorf("applied promotions add up to %v, want %v", roundMoney(applied), roundMoney(discounts))
+			}
+		})
+	}
+}
+
+func equalMoney(got, want []float64) bool {
+	return slices.EqualFunc(got, want, func(a, b float64) bool {
+		return math.Abs(a-b) < 0.001
+	})
+}
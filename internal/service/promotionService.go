@@ -0,0 +1,88 @@
+package service
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/repository"
+	"frappuccino/internal/repository/postgre"
+)
+
+type promotionService struct {
+	promotionRepo repository.PromotionRepository
+}
+
+func NewPromotionService(db *sql.DB, logger *slog.Logger) *promotionService {
+	return &promotionService{
+		postgre.NewPromotionRepositoryPostgres(db, logger),
+	}
+}
+
+// normalizePromotion makes codes case-insensitive and fills in the defaults
+func normalizePromotion(promotion models.Promotion) models.Promotion {
+	promotion.Name = strings.TrimSpace(promotion.Name)
+	promotion.Code = strings.ToUpper(strings.TrimSpace(promotion.Code))
+	if promotion.Kind == models.PromotionKindBuyXGetY && promotion.Value == 0 {
+		promotion.Value = 100
+	}
+	if promotion.Kind != models.PromotionKindBuyXGetY {
+		promotion.BuyQuantity, promotion.GetQuantity = 0, 0
+	}
+	return promotion
+}
+
+func (s *promotionService) Insert(promotion models.Promotion) (models.Promotion, map[string]string, error) {
+	promotion = normalizePromotion(promotion)
+	validator := models.NewPromotionValidator(promotion)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.Promotion{}, errMap, models.ErrMissingFields
+	}
+
+	id, err := s.promotionRepo.Insert(promotion)
+	if err != nil {
+		return models.Promotion{}, nil, err
+	}
+
+	created, err := s.promotionRepo.RetrieveByID(id)
+	return created, nil, err
+}
+
+func (s *promotionService) RetrieveAll() ([]models.Promotion, error) {
+	return s.promotionRepo.RetrieveAll()
+}
+
+func (s *promotionService) RetrieveByID(id string) (models.Promotion, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Promotion{}, models.ErrInvalidID
+	}
+
+	return s.promotionRepo.RetrieveByID(idInt)
+}
+
+func (s *promotionService) Update(id string, promotion models.Promotion) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	promotion = normalizePromotion(promotion)
+	validator := models.NewPromotionValidator(promotion)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.promotionRepo.Update(idInt, promotion)
+}
+
+func (s *promotionService) Delete(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.promotionRepo.Delete(idInt)
+}
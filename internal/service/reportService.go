@@ -2,9 +2,12 @@ package service
 
 import (
 	"database/sql"
+	"fmt"
 	"log/slog"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"frappuccino/internal/models"
 	"frappuccino/internal/repository"
@@ -20,85 +23,407 @@ func NewReportService(db *sql.DB, logger *slog.Logger) *reportService {
 	return &reportService{postgre.NewReportRepositoryPostgres(db, logger)}
 }
 
-func (s *reportService) GetTotalSales() (models.ReportTotalSales, error) {
-	report, err := s.reportRepo.GetTotalSales()
+// defaultPopularItemsLimit is how many items popular-items ranks without a limit
+const defaultPopularItemsLimit = 5
 
-	return report, err
+// parseReportQuery validates the report query parameters. It returns the filter
+// they select and whether the report is to be compared with the previous period.
+func parseReportQuery(query models.ReportQuery, defaultLimit int) (models.ReportFilter, bool, error) {
+	filter := models.ReportFilter{
+		From:     utils.ConvertDateFormat(query.From),
+		To:       utils.ConvertDateFormat(query.To),
+		Limit:    defaultLimit,
+		GroupBy:  strings.ToLower(query.GroupBy),
+		Category: query.Category,
+	}
+	if (query.From != "" && filter.From == "") || (query.To != "" && filter.To == "") {
+		return models.ReportFilter{}, false, models.ErrInvalidDate
+	}
+	if filter.From != "" && filter.To != "" && filter.From > filter.To {
+		return models.ReportFilter{}, false, models.ErrInvalidDateRange
+	}
+	if filter.GroupBy != "" && !models.IsReportGroupBy(filter.GroupBy) {
+		return models.ReportFilter{}, false, models.ErrInvalidGroupBy
+	}
+	if query.Limit != "" {
+		limit, err := strconv.Atoi(query.Limit)
+		if err != nil || limit < 1 {
+			return models.ReportFilter{}, false, models.ErrInvalidLimit
+		}
+		filter.Limit = limit
+	}
+
+	switch query.CompareTo {
+	case "":
+		return filter, false, nil
+	case models.ReportCompareToPreviousPeriod:
+		if filter.From == "" || filter.To == "" {
+			return models.ReportFilter{}, false, models.ErrComparisonNeedsRange
+		}
+		return filter, true, nil
+	default:
+		return models.ReportFilter{}, false, models.ErrInvalidCompareTo
+	}
 }
 
-func (s *reportService) GetPopularMenuItems() ([]models.ReportPopularItem, error) {
-	popularItems, err := s.reportRepo.GetPopularMenuItems()
+// previousPeriod returns the filter for the days just before filter's range,
+// as many as the range covers
+func previousPeriod(filter models.ReportFilter) models.ReportFilter {
+	from, _ := time.Parse(time.DateOnly, filter.From)
+	to, _ := time.Parse(time.DateOnly, filter.To)
+	days := int(to.Sub(from).Hours()/24) + 1
 
-	return popularItems, err
+	previous := filter
+	previous.From = from.AddDate(0, 0, -days).Format(time.DateOnly)
+	previous.To = from.AddDate(0, 0, -1).Format(time.DateOnly)
+	return previous
 }
 
-func (s *reportService) TextSearch(query string, filter string, minPriceArg string, maxPriceArg string) (models.ReportSearch, error) {
-	if filter != "all" && filter != "menu" && filter != "orders" {
-		return models.ReportSearch{}, models.ErrInvalidFilterOption
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func (s *reportService) GetTotalSales(query models.ReportQuery) (models.ReportTotalSales, error) {
+	filter, compare, err := parseReportQuery(query, 0)
+	if err != nil {
+		return models.ReportTotalSales{}, err
 	}
-	minPrice, maxPrice, err := utils.ValidatePrices(minPriceArg, maxPriceArg)
+
+	report, err := s.totalSales(filter)
 	if err != nil {
-		return models.ReportSearch{}, err
+		return models.ReportTotalSales{}, err
 	}
 
-	var results models.ReportSearch
-	results.TotalMatches = 0
-	if filter == "menu" || filter == "all" {
-		results.MenuResults, err = s.reportRepo.TextSearchMenu(query, minPrice, maxPrice)
+	if filter.GroupBy != "" {
+		report.GroupBy = filter.GroupBy
+		err = s.salesGroups(filter)(func(group models.ReportSalesGroup) error {
+			report.Groups = append(report.Groups, group)
+			return nil
+		})
 		if err != nil {
-			return models.ReportSearch{}, err
+			return models.ReportTotalSales{}, err
 		}
-		results.TotalMatches += len(results.MenuResults)
 	}
-	if filter == "orders" || filter == "all" {
-		results.OrdersResults, err = s.reportRepo.TextSearchOrders(query, minPrice, maxPrice)
+
+	if compare {
+		previous, err := s.totalSales(previousPeriod(filter))
 		if err != nil {
-			return models.ReportSearch{}, err
+			return models.ReportTotalSales{}, err
+		}
+		report.Previous = &previous
+		report.Change = &models.ReportSalesChange{
+			OrdersCompleted: report.OrdersCompleted - previous.OrdersCompleted,
+			TotalSales:      roundMoney(report.TotalSales - previous.TotalSales),
+			GrossProfit:     roundMoney(report.GrossProfit - previous.GrossProfit),
+		}
+		if previous.TotalSales != 0 {
+			percent := roundMoney((report.TotalSales - previous.TotalSales) / previous.TotalSales * 100)
+			report.Change.TotalSalesPercent = &percent
+		}
+	}
+
+	return report, nil
+}
+
+// SalesGroups validates a grouped total-sales query and returns its grouping
+// and its groups, read from the database only as the source is consumed
+func (s *reportService) SalesGroups(query models.ReportQuery) (string, RowSource[models.ReportSalesGroup], error) {
+	filter, _, err := parseReportQuery(query, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if filter.GroupBy == "" {
+		return "", nil, models.ErrInvalidGroupBy
+	}
+
+	return filter.GroupBy, s.salesGroups(filter), nil
+}
+
+func (s *reportService) salesGroups(filter models.ReportFilter) RowSource[models.ReportSalesGroup] {
+	return func(yield func(models.ReportSalesGroup) error) error {
+		return s.reportRepo.EachSalesGroup(filter, func(group models.ReportSalesGroup) error {
+			group.GrossProfit = roundMoney(group.NetSales - group.COGS)
+			return yield(group)
+		})
+	}
+}
+
+// totalSales returns the ungrouped sales totals the filter covers
+func (s *reportService) totalSales(filter models.ReportFilter) (models.ReportTotalSales, error) {
+	report, err := s.reportRepo.GetTotalSales(filter)
+	if err != nil {
+		return models.ReportTotalSales{}, err
+	}
+	report.From, report.To = filter.From, filter.To
+	report.GrossProfit = roundMoney(report.NetSales - report.COGS)
+
+	return report, nil
+}
+
+// GetTaxSummary reports the tax per tax class of the closed orders placed from
+// query.From to query.To
+func (s *reportService) GetTaxSummary(query models.ReportQuery) (models.ReportTax, error) {
+	filter, _, err := parseReportQuery(models.ReportQuery{From: query.From, To: query.To}, 0)
+	if err != nil {
+		return models.ReportTax{}, err
+	}
+
+	classes, err := s.reportRepo.GetTaxSummary(filter)
+	if err != nil {
+		return models.ReportTax{}, err
+	}
+
+	report := models.ReportTax{From: filter.From, To: filter.To, Classes: classes}
+	for _, class := range classes {
+		report.Net = roundMoney(report.Net + class.Net)
+		report.Tax = roundMoney(report.Tax + class.Tax)
+		report.Gross = roundMoney(report.Gross + class.Gross)
+	}
+
+	return report, nil
+}
+
+func (s *reportService) MenuMargins() ([]models.ReportMenuMargin, error) {
+	margins, err := s.reportRepo.MenuMargins()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, margin := range margins {
+		margins[i].Margin = roundMoney(margin.Price - margin.Cost)
+		if margin.Price > 0 {
+			margins[i].MarginPercent = math.Round(margins[i].Margin/margin.Price*10000) / 100
+		}
+	}
+	return margins, nil
+}
+
+func (s *reportService) GetPopularMenuItems(query models.ReportQuery) ([]models.ReportPopularItem, error) {
+	filter, compare, err := parseReportQuery(query, defaultPopularItemsLimit)
+	if err != nil {
+		return nil, err
+	}
+	// time groups differ from one period to the next, so only ungrouped or
+	// per-category rankings can be compared
+	if compare && filter.GroupBy != "" && filter.GroupBy != models.ReportGroupByCategory {
+		return nil, fmt.Errorf("%w: popular items grouped by %s cannot be compared", models.ErrInvalidCompareTo, filter.GroupBy)
+	}
+
+	popularItems, err := s.reportRepo.GetPopularMenuItems(filter)
+	if err != nil || !compare {
+		return popularItems, err
+	}
+
+	// rank the whole previous period so items outside its top still compare
+	previousFilter := previousPeriod(filter)
+	previousFilter.Limit = 0
+	previousItems, err := s.reportRepo.GetPopularMenuItems(previousFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	type groupItem struct {
+		group  string
+		menuID int
+	}
+	previous := make(map[groupItem]models.ReportPopularItem, len(previousItems))
+	for _, item := range previousItems {
+		previous[groupItem{item.Group, item.MenuID}] = item
+	}
+
+	for i, item := range popularItems {
+		change := item.TotalItemsSold
+		if previousItem, ok := previous[groupItem{item.Group, item.MenuID}]; ok {
+			popularItems[i].PreviousRank = &previousItem.Rank
+			popularItems[i].PreviousItemsSold = &previousItem.TotalItemsSold
+			change -= previousItem.TotalItemsSold
 		}
-		results.TotalMatches += len(results.OrdersResults)
+		popularItems[i].ItemsSoldChange = &change
 	}
+
+	return popularItems, nil
+}
+
+func (s *reportService) TextSearch(query string, filter string, minPriceArg string, maxPriceArg string) (models.ReportSearch, error) {
+	menuMatches, orderMatches, err := s.SearchMatches(query, filter, minPriceArg, maxPriceArg)
+	if err != nil {
+		return models.ReportSearch{}, err
+	}
+
+	var results models.ReportSearch
+	err = menuMatches(func(item models.ReportMenuSearchItem) error {
+		results.MenuResults = append(results.MenuResults, item)
+		return nil
+	})
+	if err != nil {
+		return models.ReportSearch{}, err
+	}
+	err = orderMatches(func(order models.ReportOrderSearchItem) error {
+		results.OrdersResults = append(results.OrdersResults, order)
+		return nil
+	})
+	if err != nil {
+		return models.ReportSearch{}, err
+	}
+	results.TotalMatches = len(results.MenuResults) + len(results.OrdersResults)
 	return results, nil
 }
 
-func (s *reportService) OrderedItemsByPeriod(period, month, year string) (models.ReportOrderedItems, error) {
-	period = strings.ToLower(period)
-	month = strings.ToLower(month)
-	year = strings.ToLower(year)
-	if period != "day" && period != "month" {
-		return models.ReportOrderedItems{}, models.ErrInvalidPeriod
+// SearchMatches validates a search and returns the matching menu items and
+// orders, read from the database only as the sources are consumed. A source
+// the filter leaves out yields nothing.
+func (s *reportService) SearchMatches(query string, filter string, minPriceArg string, maxPriceArg string) (RowSource[models.ReportMenuSearchItem], RowSource[models.ReportOrderSearchItem], error) {
+	if filter != "all" && filter != "menu" && filter != "orders" {
+		return nil, nil, models.ErrInvalidFilterOption
+	}
+	minPrice, maxPrice, err := utils.ValidatePrices(minPriceArg, maxPriceArg)
+	if err != nil {
+		return nil, nil, err
 	}
-	if period == "day" {
-		if year != "" {
-			return models.ReportOrderedItems{}, models.ErrInvalidOrderedItemsFormat
+
+	menuMatches := func(yield func(models.ReportMenuSearchItem) error) error {
+		if filter == "orders" {
+			return nil
 		}
-		monthNum := utils.GetMonthNumber(month)
-		if monthNum == -1 {
-			return models.ReportOrderedItems{}, models.ErrInvalidOrderedItemsFormat
+		return s.reportRepo.EachMenuMatch(query, minPrice, maxPrice, yield)
+	}
+	orderMatches := func(yield func(models.ReportOrderSearchItem) error) error {
+		if filter == "menu" {
+			return nil
 		}
-		data, err := s.reportRepo.OrderedItemsByDays(monthNum)
-		if err != nil {
-			return models.ReportOrderedItems{}, err
-		}
-		return models.ReportOrderedItems{
-			Period:       period,
-			Month:        month,
-			OrderedItems: data,
-		}, nil
-	} // period = month
-	if month != "" {
-		return models.ReportOrderedItems{}, models.ErrInvalidOrderedItemsFormat
-	}
-	yearNum, err := strconv.Atoi(year)
-	if err != nil || yearNum <= 0 {
-		return models.ReportOrderedItems{}, models.ErrInvalidOrderedItemsFormat
-	}
-	data, err := s.reportRepo.OrderedItemsByMonths(yearNum)
+		return s.reportRepo.EachOrderMatch(query, minPrice, maxPrice, yield)
+	}
+	return menuMatches, orderMatches, nil
+}
+
+// maxOrderedItemsPeriods caps the periods one ordered items report lists, such
+// as a month by hour
+const maxOrderedItemsPeriods = 1000
+
+// orderedItemsPeriodCount returns how many periods the filter's date range
+// covers, counting the partial periods at either end
+func orderedItemsPeriodCount(filter models.OrderedItemsFilter) int {
+	from, errFrom := time.Parse(time.DateOnly, filter.From)
+	to, errTo := time.Parse(time.DateOnly, filter.To)
+	if errFrom != nil || errTo != nil {
+		return 0
+	}
+	days := int(to.Sub(from).Hours()/24) + 1
+
+	switch filter.Period {
+	case models.OrderedItemsPeriodHour:
+		return days * 24
+	case models.OrderedItemsPeriodWeek:
+		sinceMonday := (int(from.Weekday()) + 6) % 7
+		return (sinceMonday + days + 6) / 7
+	case models.OrderedItemsPeriodMonth:
+		return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	case models.OrderedItemsPeriodYear:
+		return to.Year() - from.Year() + 1
+	}
+	return days
+}
+
+// OrderedItemsByPeriod counts ordered items or orders per period over a date
+// range. Instead of from and to, a month and/or year may be given: a month of
+// the current year, a month of the given year, or a whole year.
+func (s *reportService) OrderedItemsByPeriod(query models.OrderedItemsQuery) (models.ReportOrderedItems, error) {
+	report, periods, err := s.OrderedItemsPeriods(query)
 	if err != nil {
 		return models.ReportOrderedItems{}, err
 	}
-	return models.ReportOrderedItems{
-		Period:       period,
-		Year:         year,
-		OrderedItems: data,
-	}, nil
+
+	report.OrderedItems = []map[string]int{}
+	err = periods(func(entry map[string]int) error {
+		report.OrderedItems = append(report.OrderedItems, entry)
+		return nil
+	})
+	if err != nil {
+		return models.ReportOrderedItems{}, err
+	}
+	return report, nil
+}
+
+// OrderedItemsPeriods validates an ordered items query like
+// OrderedItemsByPeriod and returns the report without its entries, and the
+// entries, read from the database only as the source is consumed
+func (s *reportService) OrderedItemsPeriods(query models.OrderedItemsQuery) (models.ReportOrderedItems, RowSource[map[string]int], error) {
+	filter := models.OrderedItemsFilter{
+		Period: strings.ToLower(query.Period),
+		Count:  strings.ToLower(query.Count),
+	}
+	month := strings.ToLower(query.Month)
+	if !models.IsOrderedItemsPeriod(filter.Period) {
+		return models.ReportOrderedItems{}, nil, models.ErrInvalidPeriod
+	}
+	if filter.Count == "" {
+		filter.Count = models.OrderedItemsCountItems
+	}
+	if filter.Count != models.OrderedItemsCountItems && filter.Count != models.OrderedItemsCountOrders {
+		return models.ReportOrderedItems{}, nil, models.ErrInvalidCountOption
+	}
+
+	switch {
+	case month != "" || query.Year != "":
+		if query.From != "" || query.To != "" {
+			return models.ReportOrderedItems{}, nil, models.ErrInvalidOrderedItemsFormat
+		}
+
+		yearNum := time.Now().Year()
+		if query.Year != "" {
+			var err error
+			yearNum, err = strconv.Atoi(query.Year)
+			if err != nil || yearNum <= 0 {
+				return models.ReportOrderedItems{}, nil, models.ErrInvalidOrderedItemsFormat
+			}
+		}
+
+		if month == "" {
+			filter.From = fmt.Sprintf("%04d-01-01", yearNum)
+			filter.To = fmt.Sprintf("%04d-12-31", yearNum)
+			break
+		}
+		monthNum := utils.GetMonthNumber(month)
+		if monthNum == -1 {
+			return models.ReportOrderedItems{}, nil, models.ErrInvalidOrderedItemsFormat
+		}
+		filter.From = fmt.Sprintf("%04d-%02d-01", yearNum, monthNum)
+		filter.To = fmt.Sprintf("%04d-%02d-%02d", yearNum, monthNum, utils.GetDaysInMonth(yearNum, monthNum))
+
+	default:
+		if query.From == "" {
+			return models.ReportOrderedItems{}, nil, models.ErrInvalidOrderedItemsFormat
+		}
+		filter.From = utils.ConvertDateFormat(query.From)
+		filter.To = time.Now().Format(time.DateOnly)
+		if query.To != "" {
+			filter.To = utils.ConvertDateFormat(query.To)
+		}
+		if filter.From == "" || filter.To == "" {
+			return models.ReportOrderedItems{}, nil, models.ErrInvalidDate
+		}
+		if filter.From > filter.To {
+			return models.ReportOrderedItems{}, nil, models.ErrInvalidDateRange
+		}
+	}
+
+	if periods := orderedItemsPeriodCount(filter); periods > maxOrderedItemsPeriods {
+		return models.ReportOrderedItems{}, nil, fmt.Errorf("%w: %d %ss, at most %d",
+			models.ErrTooManyPeriods, periods, filter.Period, maxOrderedItemsPeriods)
+	}
+
+	report := models.ReportOrderedItems{
+		Period: filter.Period,
+		Month:  month,
+		Year:   query.Year,
+		From:   filter.From,
+		To:     filter.To,
+		Count:  filter.Count,
+	}
+	periods := func(yield func(map[string]int) error) error {
+		return s.reportRepo.EachOrderedItemsPeriod(filter, func(period string, count int) error {
+			return yield(map[string]int{period: count})
+		})
+	}
+	return report, periods, nil
 }
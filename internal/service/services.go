@@ -1,38 +1,141 @@
 package service
 
-import "frappuccino/internal/models"
+import (
+	"context"
+
+	"frappuccino/internal/models"
+)
+
+type CustomerService interface {
+	Insert(customer models.Customer) (models.Customer, map[string]string, error)
+	RetrieveAll(search string) ([]models.Customer, error)
+	RetrieveByID(id string) (models.Customer, error)
+	Update(id string, customer models.Customer) (map[string]string, error)
+	Delete(id string) error
+}
 
 type InventoryService interface {
 	Insert(inventory models.Inventory) (map[string]string, error)
 	RetrieveByID(id string) (models.Inventory, error)
 	RetrieveAll() ([]models.Inventory, error)
 	Update(inventory models.Inventory, id string) (map[string]string, error)
+	Adjust(id string, adjustment models.InventoryAdjustment) (models.AdjustedInventory, map[string]string, error)
+	BatchAdjust(batch models.BatchInventoryAdjustment) ([]models.AdjustedInventory, map[string]string, error)
 	Delete(id string) error
 	GetLeftOvers(sortBy string, page, pageSize int) (models.InventoryLeftOversResponse, error)
+	Transactions(id string) ([]models.InventoryTransaction, error)
+	TransactionsByPeriod(from, to, reason string) ([]models.InventoryTransaction, error)
+	LowStock() ([]models.LowStockItem, error)
+}
+
+type LoyaltyService interface {
+	InsertRule(rule models.LoyaltyRule) (models.LoyaltyRule, map[string]string, error)
+	RetrieveRules() ([]models.LoyaltyRule, error)
+	UpdateRule(id string, rule models.LoyaltyRule) (map[string]string, error)
+	DeleteRule(id string) error
+	InsertReward(reward models.LoyaltyReward) (models.LoyaltyReward, map[string]string, error)
+	RetrieveRewards() ([]models.LoyaltyReward, error)
+	UpdateReward(id string, reward models.LoyaltyReward) (map[string]string, error)
+	DeleteReward(id string) error
+	CustomerLoyalty(customerID string) (models.CustomerLoyalty, error)
 }
 
 type MenuService interface {
 	InsertMenu(menuItem models.MenuItem) (map[string]string, error)
-	RetrieveAll() ([]models.MenuItem, error)
+	RetrieveAll(category string) ([]models.MenuItem, error)
+	RetrieveGrouped() ([]models.MenuCategoryGroup, error)
 	RetrieveByID(id string) (models.MenuItem, error)
+	RetrieveAvailability(threshold string) ([]models.MenuAvailability, error)
+	RetrievePriceHistory(menuID string) ([]models.PriceHistory, error)
+	SchedulePriceChange(menuID string, change models.ScheduledPriceChange) (models.ScheduledPriceChange, map[string]string, error)
+	RetrieveScheduledPriceChanges(menuID string) ([]models.ScheduledPriceChange, error)
+	DeleteScheduledPriceChange(menuID, id string) error
 	Update(id string, menuItem models.MenuItem) (map[string]string, error)
 	Delete(id string) error
+	InsertCategory(category models.MenuCategory) (map[string]string, error)
+	RetrieveCategories() ([]models.MenuCategory, error)
+	UpdateCategory(id string, category models.MenuCategory) (map[string]string, error)
+	DeleteCategory(id string) error
+	InsertTaxClass(taxClass models.TaxClass) (map[string]string, error)
+	RetrieveTaxClasses() ([]models.TaxClass, error)
+	UpdateTaxClass(id string, taxClass models.TaxClass) (map[string]string, error)
+	DeleteTaxClass(id string) error
+	InsertModifierGroup(group models.ModifierGroup) (map[string]string, error)
+	RetrieveModifierGroups() ([]models.ModifierGroup, error)
+	RetrieveModifierGroupByID(id string) (models.ModifierGroup, error)
+	UpdateModifierGroup(id string, group models.ModifierGroup) (map[string]string, error)
+	DeleteModifierGroup(id string) error
+	RetrieveMenuItemModifiers(menuID string) ([]models.ModifierGroup, error)
+	SetMenuItemModifiers(menuID string, groupIDs []int) error
 }
 
 type OrderService interface {
 	Insert(order models.Order) (map[string]string, error)
-	RetrieveAll() ([]models.Order, error)
+	RetrieveAll(filter models.OrderFilter) (models.OrdersResponse, error)
 	RetrieveByID(id string) (models.Order, error)
 	Update(id string, order models.Order) (map[string]string, error)
 	Delete(id string) error
-	Close(id string) error
+	Start(id string) error
+	Close(id string, close models.OrderClose) (map[string]string, error)
+	Cancel(id string, reason string) (models.OrderCancellation, error)
+	BatchCancel(request models.BatchCancelRequest) (models.BatchCancelResponse, error)
+	Reopen(id string) error
+	History(id string) ([]models.OrderStatusHistory, error)
+	StatusHistory(from, to, status string) ([]models.OrderStatusHistory, error)
 	NumberOfOrderedItems(startDate string, endDate string) (map[string]int, error)
 	BatchOrderProcess(orders []models.Order) (models.BatchOrderResponse, error)
 }
 
+type PromotionService interface {
+	Insert(promotion models.Promotion) (models.Promotion, map[string]string, error)
+	RetrieveAll() ([]models.Promotion, error)
+	RetrieveByID(id string) (models.Promotion, error)
+	Update(id string, promotion models.Promotion) (map[string]string, error)
+	Delete(id string) error
+}
+
+type PaymentService interface {
+	Insert(ctx context.Context, orderID string, payment models.Payment) (models.Payment, map[string]string, error)
+	RetrieveByOrder(orderID string) (models.OrderPayments, error)
+	Void(ctx context.Context, orderID, id string) (models.Payment, error)
+}
+
+// RowSource reads report rows one at a time, calling yield with each, and
+// stops at the first error. Nothing is read until it is called.
+type RowSource[T any] func(yield func(T) error) error
+
 type ReportService interface {
-	GetTotalSales() (models.ReportTotalSales, error)
-	GetPopularMenuItems() ([]models.ReportPopularItem, error)
+	GetTotalSales(query models.ReportQuery) (models.ReportTotalSales, error)
+	SalesGroups(query models.ReportQuery) (string, RowSource[models.ReportSalesGroup], error)
+	GetTaxSummary(query models.ReportQuery) (models.ReportTax, error)
+	MenuMargins() ([]models.ReportMenuMargin, error)
+	GetPopularMenuItems(query models.ReportQuery) ([]models.ReportPopularItem, error)
 	TextSearch(query string, filter string, minPriceArg string, maxPriceArg string) (models.ReportSearch, error)
-	OrderedItemsByPeriod(period, month, year string) (models.ReportOrderedItems, error)
+	SearchMatches(query string, filter string, minPriceArg string, maxPriceArg string) (RowSource[models.ReportMenuSearchItem], RowSource[models.ReportOrderSearchItem], error)
+	OrderedItemsByPeriod(query models.OrderedItemsQuery) (models.ReportOrderedItems, error)
+	OrderedItemsPeriods(query models.OrderedItemsQuery) (models.ReportOrderedItems, RowSource[map[string]int], error)
+}
+
+type ShiftService interface {
+	Open(shift models.Shift) (models.Shift, map[string]string, error)
+	RetrieveAll() ([]models.Shift, error)
+	RetrieveByID(id string) (models.Shift, error)
+	RetrieveCurrent() (models.Shift, error)
+	Close(id string, close models.ShiftClose) (models.ZReport, map[string]string, error)
+	ZReport(id string) (models.ZReport, error)
+}
+
+type SupplierService interface {
+	InsertSupplier(supplier models.Supplier) (map[string]string, error)
+	RetrieveSuppliers() ([]models.Supplier, error)
+	RetrieveSupplierByID(id string) (models.Supplier, error)
+	UpdateSupplier(id string, supplier models.Supplier) (map[string]string, error)
+	DeleteSupplier(id string) error
+	InsertPurchaseOrder(order models.PurchaseOrder) (models.PurchaseOrder, map[string]string, error)
+	RetrievePurchaseOrders(status, supplierID string) ([]models.PurchaseOrder, error)
+	RetrievePurchaseOrderByID(id string) (models.PurchaseOrder, error)
+	UpdatePurchaseOrder(id string, order models.PurchaseOrder) (map[string]string, error)
+	DeletePurchaseOrder(id string) error
+	SendPurchaseOrder(id string) (models.PurchaseOrder, error)
+	ReceivePurchaseOrder(id string, receipt models.PurchaseOrderReceipt) (models.PurchaseOrder, map[string]string, error)
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/repository"
+	"frappuccino/internal/repository/postgre"
+)
+
+type shiftService struct {
+	shiftRepo repository.ShiftRepository
+}
+
+func NewShiftService(db *sql.DB, logger *slog.Logger) *shiftService {
+	return &shiftService{
+		postgre.NewShiftRepositoryPostgres(db, logger),
+	}
+}
+
+func (s *shiftService) Open(shift models.Shift) (models.Shift, map[string]string, error) {
+	validator := models.NewShiftValidator(shift)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.Shift{}, errMap, models.ErrMissingFields
+	}
+
+	opened, err := s.shiftRepo.Open(shift)
+	return opened, nil, err
+}
+
+func (s *shiftService) RetrieveAll() ([]models.Shift, error) {
+	return s.shiftRepo.RetrieveAll()
+}
+
+func (s *shiftService) RetrieveByID(id string) (models.Shift, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Shift{}, models.ErrInvalidID
+	}
+
+	return s.shiftRepo.RetrieveByID(idInt)
+}
+
+func (s *shiftService) RetrieveCurrent() (models.Shift, error) {
+	return s.shiftRepo.RetrieveCurrent()
+}
+
+// Close closes the shift and returns its Z-report
+func (s *shiftService) Close(id string, close models.ShiftClose) (models.ZReport, map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ZReport{}, nil, models.ErrInvalidID
+	}
+
+	validator := models.NewShiftCloseValidator(close)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.ZReport{}, errMap, models.ErrMissingFields
+	}
+
+	err = s.shiftRepo.Close(idInt, close)
+	if err != nil {
+		return models.ZReport{}, nil, err
+	}
+
+	report, err := s.ZReport(id)
+	return report, nil, err
+}
+
+func (s *shiftService) ZReport(id string) (models.ZReport, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ZReport{}, models.ErrInvalidID
+	}
+
+	report, err := s.shiftRepo.ZReport(idInt)
+	if err != nil {
+		return models.ZReport{}, err
+	}
+	report.GrossProfit = roundMoney(report.NetRevenue - report.COGS)
+
+	return report, nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/repository"
+	"frappuccino/internal/repository/postgre"
+)
+
+type supplierService struct {
+	supplierRepo repository.SupplierRepository
+}
+
+func NewSupplierService(db *sql.DB, logger *slog.Logger) *supplierService {
+	return &supplierService{
+		postgre.NewSupplierRepositoryPostgres(db, logger),
+	}
+}
+
+func (s *supplierService) InsertSupplier(supplier models.Supplier) (map[string]string, error) {
+	validator := models.NewSupplierValidator(supplier)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.supplierRepo.InsertSupplier(supplier)
+}
+
+func (s *supplierService) RetrieveSuppliers() ([]models.Supplier, error) {
+	return s.supplierRepo.RetrieveSuppliers()
+}
+
+func (s *supplierService) RetrieveSupplierByID(id string) (models.Supplier, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Supplier{}, models.ErrInvalidID
+	}
+
+	return s.supplierRepo.RetrieveSupplierByID(idInt)
+}
+
+func (s *supplierService) UpdateSupplier(id string, supplier models.Supplier) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	validator := models.NewSupplierValidator(supplier)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.supplierRepo.UpdateSupplier(idInt, supplier)
+}
+
+func (s *supplierService) DeleteSupplier(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.supplierRepo.DeleteSupplier(idInt)
+}
+
+func (s *supplierService) InsertPurchaseOrder(order models.PurchaseOrder) (models.PurchaseOrder, map[string]string, error) {
+	validator := models.NewPurchaseOrderValidator(order)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.PurchaseOrder{}, errMap, models.ErrMissingFields
+	}
+
+	orderID, err := s.supplierRepo.InsertPurchaseOrder(order)
+	if err != nil {
+		return models.PurchaseOrder{}, nil, err
+	}
+
+	order, err = s.supplierRepo.RetrievePurchaseOrderByID(orderID)
+	return order, nil, err
+}
+
+func (s *supplierService) RetrievePurchaseOrders(status, supplierID string) ([]models.PurchaseOrder, error) {
+	filter := models.PurchaseOrderFilter{Status: strings.ToLower(status)}
+	if filter.Status != "" && !models.IsPurchaseOrderStatus(filter.Status) {
+		return nil, models.ErrInvalidPurchaseOrderStatus
+	}
+	if supplierID != "" {
+		var err error
+		filter.SupplierID, err = strconv.Atoi(supplierID)
+		if err != nil {
+			return nil, models.ErrInvalidID
+		}
+	}
+
+	return s.supplierRepo.RetrievePurchaseOrders(filter)
+}
+
+func (s *supplierService) RetrievePurchaseOrderByID(id string) (models.PurchaseOrder, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.PurchaseOrder{}, models.ErrInvalidID
+	}
+
+	return s.supplierRepo.RetrievePurchaseOrderByID(idInt)
+}
+
+func (s *supplierService) UpdatePurchaseOrder(id string, order models.PurchaseOrder) (map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, models.ErrInvalidID
+	}
+
+	validator := models.NewPurchaseOrderValidator(order)
+	if errMap := validator.Validate(); errMap != nil {
+		return errMap, models.ErrMissingFields
+	}
+
+	return nil, s.supplierRepo.UpdatePurchaseOrder(idInt, order)
+}
+
+func (s *supplierService) DeletePurchaseOrder(id string) error {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.ErrInvalidID
+	}
+
+	return s.supplierRepo.DeletePurchaseOrder(idInt)
+}
+
+func (s *supplierService) SendPurchaseOrder(id string) (models.PurchaseOrder, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.PurchaseOrder{}, models.ErrInvalidID
+	}
+
+	err = s.supplierRepo.SendPurchaseOrder(idInt)
+	if err != nil {
+		return models.PurchaseOrder{}, err
+	}
+
+	return s.supplierRepo.RetrievePurchaseOrderByID(idInt)
+}
+
+// ReceivePurchaseOrder books a delivery against a purchase order and returns
+// the purchase order with the packs received so far
+func (s *supplierService) ReceivePurchaseOrder(id string, receipt models.PurchaseOrderReceipt) (models.PurchaseOrder, map[string]string, error) {
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		return models.PurchaseOrder{}, nil, models.ErrInvalidID
+	}
+
+	validator := models.NewPurchaseOrderReceiptValidator(receipt)
+	if errMap := validator.Validate(); errMap != nil {
+		return models.PurchaseOrder{}, errMap, models.ErrMissingFields
+	}
+
+	err = s.supplierRepo.ReceivePurchaseOrder(idInt, receipt)
+	if err != nil {
+		return models.PurchaseOrder{}, nil, err
+	}
+
+	order, err := s.supplierRepo.RetrievePurchaseOrderByID(idInt)
+	return order, nil, err
+}
@@ -0,0 +1,46 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func writeCSV(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Columns()); err != nil {
+		return err
+	}
+
+	record := make([]string, 0, len(table.Columns()))
+	err := table.Rows(func(row []any) error {
+		record = record[:0]
+		for _, cell := range row {
+			record = append(record, formatCell(cell))
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatCell renders a cell as text, numbers without trailing zeros
+func formatCell(cell any) string {
+	switch value := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
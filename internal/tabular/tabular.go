@@ -0,0 +1,122 @@
+// Package tabular writes report rows as CSV or XLSX. Rows are encoded as they
+// are produced, so the file itself is never held in memory; a table built
+// with New over rows read from the database never holds the report either.
+package tabular
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"frappuccino/internal/models"
+)
+
+// export formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:  "text/csv",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Table is a report in rows. Cells are strings, ints, float64s or nil for an
+// empty cell.
+type Table interface {
+	Columns() []string
+	// Rows calls write for each row in order and stops at the first error
+	Rows(write func(row []any) error) error
+}
+
+type funcTable struct {
+	columns []string
+	rows    func(write func(row []any) error) error
+}
+
+// New returns a table whose rows come from calling rows
+func New(columns []string, rows func(write func(row []any) error) error) Table {
+	return &funcTable{columns: columns, rows: rows}
+}
+
+func (t *funcTable) Columns() []string {
+	return t.columns
+}
+
+func (t *funcTable) Rows(write func(row []any) error) error {
+	return t.rows(write)
+}
+
+type sliceTable[T any] struct {
+	columns []string
+	items   []T
+	row     func(T) []any
+}
+
+// FromSlice returns a table with one row per item, built by row when the
+// table is written
+func FromSlice[T any](columns []string, items []T, row func(T) []any) Table {
+	return &sliceTable[T]{columns: columns, items: items, row: row}
+}
+
+func (t *sliceTable[T]) Columns() []string {
+	return t.columns
+}
+
+func (t *sliceTable[T]) Rows(write func(row []any) error) error {
+	for _, item := range t.items {
+		if err := write(t.row(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Negotiate picks the response format: the format query parameter if given,
+// otherwise CSV or XLSX when the Accept header asks for it, otherwise JSON
+func Negotiate(r *http.Request) (string, error) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		switch format {
+		case FormatJSON, FormatCSV, FormatXLSX:
+			return format, nil
+		}
+		return "", models.ErrInvalidExportFormat
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		for format, contentType := range contentTypes {
+			if mediaType == contentType {
+				return format, nil
+			}
+		}
+	}
+	return FormatJSON, nil
+}
+
+// Write sends table as an attachment named name in the given format, which
+// must be CSV or XLSX. Once the first row is written the status is sent, so
+// later errors can only cut the file short.
+func Write(w http.ResponseWriter, format, name string, table Table) error {
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return models.ErrInvalidExportFormat
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": fmt.Sprintf("%s.%s", name, format),
+	}))
+	w.WriteHeader(http.StatusOK)
+
+	if format == FormatCSV {
+		return writeCSV(w, table)
+	}
+	return writeXLSX(w, table)
+}
@@ -37,25 +37,124 @@ func MapErrorToResponse(err error, validationMap any) (int, any) {
 	// Inventory errors
 	case errors.Is(err, models.ErrDuplicateInventory),
 		errors.Is(err, models.ErrNegativeQuantity),
-		errors.Is(err, models.ErrInvalidEnumTypeInventory):
+		errors.Is(err, models.ErrInvalidEnumTypeInventory),
+		errors.Is(err, models.ErrInvalidInventoryReason),
+		errors.Is(err, models.ErrUnknownUnit),
+		errors.Is(err, models.ErrIncompatibleUnit),
+		errors.Is(err, models.ErrFractionalQuantity):
 		return http.StatusBadRequest, Response{"error": err.Error()}
 
+	case errors.Is(err, models.ErrInventoryUnitChange):
+		return http.StatusConflict, Response{"error": err.Error()}
+
+	case errors.Is(err, models.ErrInventoryItemNotFound):
+		return http.StatusNotFound, Response{"error": err.Error()}
+
 	// Menu errors
 	case errors.Is(err, models.ErrDuplicateMenuItem),
 		errors.Is(err, models.ErrNegativePrice),
-		errors.Is(err, models.ErrForeignKeyConstraintMenuInventory):
+		errors.Is(err, models.ErrForeignKeyConstraintMenuInventory),
+		errors.Is(err, models.ErrForeignKeyConstraintMenuCategory),
+		errors.Is(err, models.ErrDuplicateMenuCategory),
+		errors.Is(err, models.ErrDuplicateModifierGroup),
+		errors.Is(err, models.ErrForeignKeyConstraintModifierGroup),
+		errors.Is(err, models.ErrModifierNotInGroup),
+		errors.Is(err, models.ErrInvalidThreshold),
+		errors.Is(err, models.ErrDuplicateTaxClass),
+		errors.Is(err, models.ErrForeignKeyConstraintTaxClass):
+		return http.StatusBadRequest, Response{"error": err.Error()}
+
+	case errors.Is(err, models.ErrTaxClassInUse):
+		return http.StatusConflict, Response{"error": err.Error()}
+
+	// Supplier errors
+	case errors.Is(err, models.ErrDuplicateSupplier),
+		errors.Is(err, models.ErrForeignKeyConstraintSupplier),
+		errors.Is(err, models.ErrItemNotSupplied),
+		errors.Is(err, models.ErrItemNotOnPurchaseOrder),
+		errors.Is(err, models.ErrOverReceipt),
+		errors.Is(err, models.ErrInvalidPurchaseOrderStatus):
 		return http.StatusBadRequest, Response{"error": err.Error()}
 
+	case errors.Is(err, models.ErrSupplierHasPurchaseOrders),
+		errors.As(err, new(*models.PurchaseOrderStatusError)):
+		return http.StatusConflict, Response{"error": err.Error()}
+
+	// Shift errors
+	case errors.Is(err, models.ErrShiftAlreadyOpen),
+		errors.Is(err, models.ErrShiftClosed),
+		errors.Is(err, models.ErrShiftNotClosed),
+		errors.Is(err, models.ErrShiftHasOpenOrders):
+		return http.StatusConflict, Response{"error": err.Error()}
+
+	// Customer errors
+	case errors.Is(err, models.ErrDuplicateCustomer):
+		return http.StatusBadRequest, Response{"error": err.Error()}
+
+	// Loyalty errors
+	case errors.Is(err, models.ErrForeignKeyConstraintReward),
+		errors.Is(err, models.ErrRewardNotApplicable),
+		errors.Is(err, models.ErrRewardNeedsCustomer):
+		return http.StatusBadRequest, Response{"error": err.Error()}
+
+	case errors.Is(err, models.ErrInsufficientLoyalty):
+		return http.StatusConflict, Response{"error": err.Error()}
+
+	// Promotion errors
+	case errors.Is(err, models.ErrDuplicatePromoCode),
+		errors.Is(err, models.ErrInvalidPromoCode),
+		errors.Is(err, models.ErrPromoCodeNotActive),
+		errors.Is(err, models.ErrPromoCodeNotApplicable):
+		return http.StatusBadRequest, Response{"error": err.Error()}
+
+	case errors.Is(err, models.ErrPromoCodeLimitReached):
+		return http.StatusConflict, Response{"error": err.Error()}
+
+	// Payment errors
+	case errors.Is(err, models.ErrOverpayment),
+		errors.Is(err, models.ErrInsufficientTender):
+		return http.StatusBadRequest, Response{"error": err.Error()}
+
+	case errors.Is(err, models.ErrPaymentDeclined):
+		return http.StatusPaymentRequired, Response{"error": err.Error()}
+
+	case errors.Is(err, models.ErrOrderAlreadyPaid),
+		errors.Is(err, models.ErrOrderNotPayable),
+		errors.Is(err, models.ErrPaymentVoided),
+		errors.Is(err, models.ErrOrderHasPayments),
+		errors.Is(err, models.ErrPaymentsOnRecord),
+		errors.Is(err, models.ErrOrderNotPaid):
+		return http.StatusConflict, Response{"error": err.Error()}
+
 	// Order errors
 	case errors.Is(err, models.ErrDuplicateOrder),
 		errors.Is(err, models.ErrInvalidFilterOption),
-		errors.Is(err, models.ErrForeignKeyConstraintOrderMenu):
+		errors.Is(err, models.ErrInvalidOrderStatus),
+		errors.Is(err, models.ErrInvalidDate),
+		errors.Is(err, models.ErrMissingCancelReason),
+		errors.Is(err, models.ErrInvalidSortOption),
+		errors.Is(err, models.ErrForeignKeyConstraintOrderMenu),
+		errors.Is(err, models.ErrForeignKeyConstraintCustomer),
+		errors.Is(err, models.ErrInvalidModifier),
+		errors.Is(err, models.ErrModifierLimitExceeded),
+		errors.Is(err, models.ErrMissingRequiredModifier):
 		return http.StatusBadRequest, Response{"error": err.Error()}
 
+	case errors.As(err, new(*models.OrderStatusTransitionError)):
+		return http.StatusConflict, Response{"error": err.Error()}
+
 	// Report errors
 	case errors.Is(err, models.ErrInvalidPrice),
 		errors.Is(err, models.ErrInvalidPeriod),
-		errors.Is(err, models.ErrInvalidOrderedItemsFormat):
+		errors.Is(err, models.ErrInvalidOrderedItemsFormat),
+		errors.Is(err, models.ErrTooManyPeriods),
+		errors.Is(err, models.ErrInvalidCountOption),
+		errors.Is(err, models.ErrInvalidExportFormat),
+		errors.Is(err, models.ErrInvalidGroupBy),
+		errors.Is(err, models.ErrInvalidLimit),
+		errors.Is(err, models.ErrInvalidCompareTo),
+		errors.Is(err, models.ErrComparisonNeedsRange),
+		errors.Is(err, models.ErrInvalidDateRange):
 		return http.StatusBadRequest, Response{"error": err.Error()}
 
 	// Default catch-all
@@ -136,10 +235,12 @@ func GetMonthName(month int) string {
 	return months[month-1]
 }
 
-func GetDaysInMonth(month int) int {
-	days := []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+// GetDaysInMonth returns the number of days in the month of the given year,
+// counting leap years
+func GetDaysInMonth(year, month int) int {
 	if month < 1 || month > 12 {
 		return -1
 	}
-	return days[month-1]
+	// day 0 of the next month is the last day of this one
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
 }